@@ -0,0 +1,33 @@
+package dns
+
+import "net"
+
+// NewAddrRR returns an A record for owner when ip is an IPv4 address, also when it is
+// in the 16 octet form net.ParseIP returns, and an AAAA record otherwise. The address
+// is stored in its 4 or 16 octet form, so records made from either form of the same
+// IPv4 address are identical. NewAddrRR returns nil when ip is not a valid address.
+func NewAddrRR(owner string, ttl uint32, ip net.IP) RR {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &A{Hdr: RR_Header{Name: owner, Rrtype: TypeA, Class: ClassINET, Ttl: ttl}, A: ip4}
+	}
+	if len(ip) != net.IPv6len {
+		return nil
+	}
+	return &AAAA{Hdr: RR_Header{Name: owner, Rrtype: TypeAAAA, Class: ClassINET, Ttl: ttl}, AAAA: ip}
+}
+
+// AddrFromRR returns the address in rr, in its 4 octet form for an A record and in
+// its 16 octet form for an AAAA record. It returns nil for other records and for
+// records without a valid address.
+func AddrFromRR(rr RR) net.IP {
+	switch x := rr.(type) {
+	case *A:
+		return x.A.To4()
+	case *AAAA:
+		if len(x.AAAA) != net.IPv6len {
+			return nil
+		}
+		return x.AAAA
+	}
+	return nil
+}
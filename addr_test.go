@@ -0,0 +1,56 @@
+package dns
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestNewAddrRR(t *testing.T) {
+	var wires [][]byte
+	for _, ip := range []net.IP{net.IPv4(192, 0, 2, 1).To4(), net.ParseIP("192.0.2.1")} {
+		rr := NewAddrRR("www.example.org.", 3600, ip)
+		a, ok := rr.(*A)
+		if !ok {
+			t.Fatalf("expected an A record for %d octet address, got %T", len(ip), rr)
+		}
+		if len(a.A) != net.IPv4len {
+			t.Errorf("expected a 4 octet address, got %d octets", len(a.A))
+		}
+		buf := make([]byte, rr.len())
+		off, err := PackRR(rr, buf, 0, nil, false)
+		if err != nil {
+			t.Fatalf("failed to pack: %v", err)
+		}
+		wires = append(wires, buf[:off])
+		if addr := AddrFromRR(rr); !addr.Equal(ip) || len(addr) != net.IPv4len {
+			t.Errorf("expected address %s in 4 octets, got %v", ip, []byte(addr))
+		}
+	}
+	if !bytes.Equal(wires[0], wires[1]) {
+		t.Errorf("expected identical wire format, got %v and %v", wires[0], wires[1])
+	}
+
+	rr := NewAddrRR("www.example.org.", 3600, net.ParseIP("2001:db8::1"))
+	if _, ok := rr.(*AAAA); !ok {
+		t.Fatalf("expected an AAAA record, got %T", rr)
+	}
+	if addr := AddrFromRR(rr); !addr.Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("expected address 2001:db8::1, got %s", addr)
+	}
+
+	if rr := NewAddrRR("www.example.org.", 3600, net.IP{1, 2, 3}); rr != nil {
+		t.Errorf("expected nil for a bad address, got %s", rr)
+	}
+	if addr := AddrFromRR(newRR(t, "www.example.org. IN TXT \"192.0.2.1\"")); addr != nil {
+		t.Errorf("expected no address for a TXT record, got %s", addr)
+	}
+}
+
+func TestPackAIPv6(t *testing.T) {
+	rr := &A{Hdr: RR_Header{Name: "www.example.org.", Rrtype: TypeA, Class: ClassINET}, A: net.ParseIP("2001:db8::1")}
+	buf := make([]byte, rr.len())
+	if _, err := PackRR(rr, buf, 0, nil, false); err == nil {
+		t.Error("expected error packing an IPv6 address in an A record")
+	}
+}
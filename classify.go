@@ -0,0 +1,82 @@
+package dns
+
+import "strconv"
+
+// ResponseType is the kind of response a message is, as returned by Classify.
+type ResponseType int
+
+// Response types.
+const (
+	ResponseAnswer   ResponseType = iota // Answer to the question.
+	ResponseCNAME                        // CNAME or DNAME chain without an answer to the question.
+	ResponseReferral                     // Referral to a child zone.
+	ResponseNXDomain                     // Name does not exist.
+	ResponseNoData                       // Name exists, but does not have the type from the question.
+	ResponseError                        // Any other rcode.
+)
+
+var responseTypeToString = map[ResponseType]string{
+	ResponseAnswer:   "ANSWER",
+	ResponseCNAME:    "CNAME",
+	ResponseReferral: "REFERRAL",
+	ResponseNXDomain: "NXDOMAIN",
+	ResponseNoData:   "NODATA",
+	ResponseError:    "ERROR",
+}
+
+func (r ResponseType) String() string {
+	if s, ok := responseTypeToString[r]; ok {
+		return s
+	}
+	return "RESPONSE" + strconv.Itoa(int(r))
+}
+
+// Classify returns the type of the response. The rcode decides between NXDOMAIN,
+// errors and the other types. An answer section with records of the type from the
+// question (possibly following a CNAME chain) is an answer; one with only CNAME or
+// DNAME records is a chain that must be followed. An empty answer section is a
+// referral when the AA bit is not set and the authority section has NS records but
+// no SOA record, otherwise it is a NODATA response.
+func (dns *Msg) Classify() ResponseType {
+	switch dns.Rcode {
+	case RcodeSuccess:
+	case RcodeNameError:
+		return ResponseNXDomain
+	default:
+		return ResponseError
+	}
+
+	if len(dns.Answer) > 0 {
+		if len(dns.Question) == 0 {
+			return ResponseAnswer
+		}
+		q := dns.Question[0]
+		for _, r := range dns.Answer {
+			h := r.Header()
+			if q.Qtype == TypeANY || h.Rrtype == q.Qtype {
+				return ResponseAnswer
+			}
+		}
+		for _, r := range dns.Answer {
+			switch r.Header().Rrtype {
+			case TypeCNAME, TypeDNAME:
+				return ResponseCNAME
+			}
+		}
+		return ResponseAnswer
+	}
+
+	ns, soa := false, false
+	for _, r := range dns.Ns {
+		switch r.Header().Rrtype {
+		case TypeNS:
+			ns = true
+		case TypeSOA:
+			soa = true
+		}
+	}
+	if ns && !soa && !dns.Authoritative {
+		return ResponseReferral
+	}
+	return ResponseNoData
+}
@@ -0,0 +1,47 @@
+package dns
+
+import "testing"
+
+func TestMsgClassify(t *testing.T) {
+	soa := "miek.nl. 3600 IN SOA linode.atoom.net. miek.miek.nl. 1282630057 14400 3600 604800 300"
+	tests := []struct {
+		name   string
+		qtype  uint16
+		rcode  int
+		aa     bool
+		answer []string
+		ns     []string
+		want   ResponseType
+	}{
+		{"answer", TypeA, RcodeSuccess, true, []string{"www.miek.nl. 3600 IN A 127.0.0.1"}, nil, ResponseAnswer},
+		{"any", TypeANY, RcodeSuccess, true, []string{"www.miek.nl. 3600 IN A 127.0.0.1"}, nil, ResponseAnswer},
+		{"cname query", TypeCNAME, RcodeSuccess, true, []string{"www.miek.nl. 3600 IN CNAME a.miek.nl."}, nil, ResponseAnswer},
+		{"cname answer", TypeA, RcodeSuccess, true, []string{"www.miek.nl. 3600 IN CNAME a.miek.nl.", "a.miek.nl. 3600 IN A 127.0.0.1"}, nil, ResponseAnswer},
+		{"cname chain", TypeA, RcodeSuccess, true, []string{"www.miek.nl. 3600 IN CNAME a.example.org."}, nil, ResponseCNAME},
+		{"dname chain", TypeA, RcodeSuccess, true, []string{"miek.nl. 3600 IN DNAME example.org.", "www.miek.nl. 3600 IN CNAME www.example.org."}, nil, ResponseCNAME},
+		{"referral", TypeA, RcodeSuccess, false, nil, []string{"miek.nl. 172800 IN NS linode.atoom.net."}, ResponseReferral},
+		{"nodata", TypeMX, RcodeSuccess, true, nil, []string{soa}, ResponseNoData},
+		{"nodata with ns", TypeMX, RcodeSuccess, false, nil, []string{soa, "miek.nl. 3600 IN NS linode.atoom.net."}, ResponseNoData},
+		{"nodata without soa", TypeMX, RcodeSuccess, true, nil, nil, ResponseNoData},
+		{"nxdomain", TypeA, RcodeNameError, true, nil, []string{soa}, ResponseNXDomain},
+		{"nxdomain with cname", TypeA, RcodeNameError, true, []string{"www.miek.nl. 3600 IN CNAME a.miek.nl."}, []string{soa}, ResponseNXDomain},
+		{"servfail", TypeA, RcodeServerFailure, false, nil, nil, ResponseError},
+		{"refused", TypeA, RcodeRefused, false, nil, nil, ResponseError},
+	}
+	for _, tc := range tests {
+		m := new(Msg)
+		m.SetQuestion("www.miek.nl.", tc.qtype)
+		m.Response = true
+		m.Rcode = tc.rcode
+		m.Authoritative = tc.aa
+		for _, s := range tc.answer {
+			m.Answer = append(m.Answer, newRR(t, s))
+		}
+		for _, s := range tc.ns {
+			m.Ns = append(m.Ns, newRR(t, s))
+		}
+		if got := m.Classify(); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
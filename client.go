@@ -8,13 +8,16 @@ import (
 	"encoding/binary"
 	"io"
 	"net"
+	"strings"
 	"time"
 )
 
 const dnsTimeout time.Duration = 2 * time.Second
 const tcpIdleTimeout time.Duration = 8 * time.Second
 
-// A Conn represents a connection to a DNS server.
+// A Conn represents a connection to a DNS server. On a net.PacketConn, such as UDP,
+// each message is sent bare; on any other connection, such as TCP or TLS, it is
+// prefixed with its length.
 type Conn struct {
 	net.Conn                         // a net.Conn holding the connection
 	UDPSize        uint16            // minimum receive buffer for UDP messages
@@ -27,7 +30,7 @@ type Conn struct {
 // A Client defines parameters for a DNS client.
 type Client struct {
 	Net            string            // if "tcp" or "tcp-tls" (DNS over TLS) a TCP query will be initiated, otherwise an UDP one (default is "" for UDP)
-	UDPSize        uint16            // minimum receive buffer for UDP messages
+	UDPSize        uint16            // minimum receive buffer for UDP messages, when larger than 512 UDP queries advertise it in an OPT RR
 	TLSConfig      *tls.Config       // TLS connection configuration
 	Timeout        time.Duration     // a cumulative timeout for dial, write and read, defaults to 0 (disabled) - overrides DialTimeout, ReadTimeout and WriteTimeout when non-zero
 	DialTimeout    time.Duration     // net.DialTimeout, defaults to 2 seconds - overridden by Timeout when that value is non-zero
@@ -130,6 +133,19 @@ func (c *Client) Exchange(m *Msg, a string) (r *Msg, rtt time.Duration, err erro
 	return r, rtt, nil
 }
 
+// ExchangeN sends the message m to each of the servers in turn, until one of them
+// replies. It returns that reply and the address of the server that sent it. When
+// none of the servers reply ExchangeN returns ErrServ. Each exchange is subject to
+// the timeouts of c, so these bound how long it takes to fail over to the next server.
+func ExchangeN(c *Client, m *Msg, servers []string) (r *Msg, server string, err error) {
+	for _, a := range servers {
+		if r, _, err = c.Exchange(m, a); err == nil {
+			return r, a, nil
+		}
+	}
+	return nil, "", ErrServ
+}
+
 func (c *Client) dialTimeout() time.Duration {
 	if c.Timeout != 0 {
 		return c.Timeout
@@ -191,6 +207,10 @@ func (c *Client) exchange(m *Msg, a string) (r *Msg, rtt time.Duration, err erro
 	}
 	defer co.Close()
 
+	if !strings.HasPrefix(network, "tcp") && c.UDPSize > MinMsgSize {
+		m = withUDPSize(m, c.UDPSize)
+	}
+
 	opt := m.IsEdns0()
 	// If EDNS0 is used use that for size.
 	if opt != nil && opt.UDPSize() >= MinMsgSize {
@@ -215,6 +235,28 @@ func (c *Client) exchange(m *Msg, a string) (r *Msg, rtt time.Duration, err erro
 	return r, co.rtt, err
 }
 
+// withUDPSize returns m when it has an OPT RR advertising at least size. Otherwise it
+// returns a copy of m in which the OPT RR advertises size, adding one, before a TSIG RR,
+// when m has none.
+func withUDPSize(m *Msg, size uint16) *Msg {
+	if opt := m.IsEdns0(); opt != nil && opt.UDPSize() >= size {
+		return m
+	}
+	m = m.Copy()
+	if opt := m.IsEdns0(); opt != nil {
+		opt.SetUDPSize(size)
+		return m
+	}
+	opt := &OPT{Hdr: RR_Header{Name: ".", Rrtype: TypeOPT}}
+	opt.SetUDPSize(size)
+	if t := m.IsTsig(); t != nil {
+		m.Extra = append(m.Extra[:len(m.Extra)-1], opt, t)
+		return m
+	}
+	m.Extra = append(m.Extra, opt)
+	return m
+}
+
 // ReadMsg reads a message from the connection co.
 // If the received message contains a TSIG record the transaction
 // signature is verified.
@@ -254,17 +296,16 @@ func (co *Conn) ReadMsgHeader(hdr *Header) ([]byte, error) {
 		err error
 	)
 
-	switch t := co.Conn.(type) {
-	case *net.TCPConn, *tls.Conn:
-		r := t.(io.Reader)
-
+	switch {
+	case co.stream():
 		// First two bytes specify the length of the entire message.
-		l, err := tcpMsgLen(r)
+		var l int
+		l, err = tcpMsgLen(co.Conn)
 		if err != nil {
 			return nil, err
 		}
 		p = make([]byte, l)
-		n, err = tcpRead(r, p)
+		n, err = tcpRead(co.Conn, p)
 		co.rtt = time.Since(co.t)
 	default:
 		if co.UDPSize > MinMsgSize {
@@ -294,15 +335,16 @@ func (co *Conn) ReadMsgHeader(hdr *Header) ([]byte, error) {
 }
 
 // tcpMsgLen is a helper func to read first two bytes of stream as uint16 packet length.
+// It returns io.EOF when the stream ends before the first byte, i.e. between messages,
+// and ErrShortRead when it ends after the first.
 func tcpMsgLen(t io.Reader) (int, error) {
 	p := []byte{0, 0}
-	n, err := t.Read(p)
-	if err != nil {
+	if _, err := io.ReadFull(t, p); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, ErrShortRead
+		}
 		return 0, err
 	}
-	if n != 2 {
-		return 0, ErrShortRead
-	}
 	l := binary.BigEndian.Uint16(p)
 	if l == 0 {
 		return 0, ErrShortRead
@@ -310,18 +352,12 @@ func tcpMsgLen(t io.Reader) (int, error) {
 	return int(l), nil
 }
 
-// tcpRead calls TCPConn.Read enough times to fill allocated buffer.
+// tcpRead calls TCPConn.Read enough times to fill allocated buffer. It returns
+// ErrShortRead when the stream ends before that.
 func tcpRead(t io.Reader, p []byte) (int, error) {
-	n, err := t.Read(p)
-	if err != nil {
-		return n, err
-	}
-	for n < len(p) {
-		j, err := t.Read(p[n:])
-		if err != nil {
-			return n, err
-		}
-		n += j
+	n, err := io.ReadFull(t, p)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return n, ErrShortRead
 	}
 	return n, err
 }
@@ -334,18 +370,15 @@ func (co *Conn) Read(p []byte) (n int, err error) {
 	if len(p) < 2 {
 		return 0, io.ErrShortBuffer
 	}
-	switch t := co.Conn.(type) {
-	case *net.TCPConn, *tls.Conn:
-		r := t.(io.Reader)
-
-		l, err := tcpMsgLen(r)
+	if co.stream() {
+		l, err := tcpMsgLen(co.Conn)
 		if err != nil {
 			return 0, err
 		}
 		if l > len(p) {
 			return int(l), io.ErrShortBuffer
 		}
-		return tcpRead(r, p[:l])
+		return tcpRead(co.Conn, p[:l])
 	}
 	// UDP connection
 	n, err = co.Conn.Read(p)
@@ -383,10 +416,7 @@ func (co *Conn) WriteMsg(m *Msg) (err error) {
 
 // Write implements the net.Conn Write method.
 func (co *Conn) Write(p []byte) (n int, err error) {
-	switch t := co.Conn.(type) {
-	case *net.TCPConn, *tls.Conn:
-		w := t.(io.Writer)
-
+	if co.stream() {
 		lp := len(p)
 		if lp < 2 {
 			return 0, io.ErrShortBuffer
@@ -397,13 +427,29 @@ func (co *Conn) Write(p []byte) (n int, err error) {
 		l := make([]byte, 2, lp+2)
 		binary.BigEndian.PutUint16(l, uint16(lp))
 		p = append(l, p...)
-		n, err := io.Copy(w, bytes.NewReader(p))
+		n, err := io.Copy(co.Conn, bytes.NewReader(p))
 		return int(n), err
 	}
-	n, err = co.Conn.(*net.UDPConn).Write(p)
+	n, err = co.Conn.Write(p)
 	return n, err
 }
 
+// stream returns true when the connection is a stream, such as TCP or TLS, on which
+// each message is prefixed with its length. A net.PacketConn, such as UDP, carries
+// one bare message per packet. A *net.UnixConn is a net.PacketConn for all socket
+// types, so for those the network of the address decides.
+func (co *Conn) stream() bool {
+	if c, ok := co.Conn.(*net.UnixConn); ok {
+		for _, a := range []net.Addr{c.LocalAddr(), c.RemoteAddr()} {
+			if a != nil {
+				return a.Network() == "unix"
+			}
+		}
+	}
+	_, packet := co.Conn.(net.PacketConn)
+	return !packet
+}
+
 // Dial connects to the address on the named network.
 func Dial(network, address string) (conn *Conn, err error) {
 	conn = new(Conn)
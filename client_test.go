@@ -3,7 +3,11 @@ package dns
 import (
 	"crypto/tls"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
@@ -450,3 +454,257 @@ func TestTimeout(t *testing.T) {
 		t.Errorf("exchange took longer (%v) than specified Timeout (%v)", length, timeout)
 	}
 }
+
+func TestExchangeN(t *testing.T) {
+	HandleFunc("miek.nl.", HelloServer)
+	defer HandleRemove("miek.nl.")
+
+	s, addrstr, err := RunLocalUDPServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	// Nothing listens on dead once the connection is closed.
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	dead := pc.LocalAddr().String()
+	pc.Close()
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeSOA)
+	c := &Client{Timeout: 500 * time.Millisecond}
+
+	r, server, err := ExchangeN(c, m, []string{dead, addrstr})
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if server != addrstr {
+		t.Errorf("expected reply from %s, got %s", addrstr, server)
+	}
+	if r == nil || r.Rcode != RcodeSuccess {
+		t.Errorf("failed to get an valid answer\n%v", r)
+	}
+
+	if _, _, err := ExchangeN(c, m, []string{dead}); err != ErrServ {
+		t.Errorf("expected ErrServ, got %v", err)
+	}
+}
+
+func TestClientUDPSize(t *testing.T) {
+	sizes := make(chan uint16, 1)
+	HandleFunc("miek.nl.", func(w ResponseWriter, req *Msg) {
+		size := uint16(0)
+		if opt := req.IsEdns0(); opt != nil {
+			size = opt.UDPSize()
+		}
+		sizes <- size
+		m := new(Msg)
+		m.SetReply(req)
+		w.WriteMsg(m)
+	})
+	defer HandleRemove("miek.nl.")
+
+	s, addrstr, err := RunLocalUDPServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	for _, tc := range []struct {
+		edns0    uint16 // advertised in the query, 0 for no OPT
+		expected uint16
+	}{
+		{0, 4096},
+		{1232, 4096},
+		{8192, 8192},
+	} {
+		m := new(Msg)
+		m.SetQuestion("miek.nl.", TypeSOA)
+		if tc.edns0 != 0 {
+			m.SetEdns0(tc.edns0, false)
+		}
+		c := &Client{UDPSize: 4096}
+		if _, _, err := c.Exchange(m, addrstr); err != nil {
+			t.Fatalf("failed to exchange: %v", err)
+		}
+		if size := <-sizes; size != tc.expected {
+			t.Errorf("expected query with OPT size %d, got %d", tc.expected, size)
+		}
+		if opt := m.IsEdns0(); (opt == nil && tc.edns0 != 0) || (opt != nil && opt.UDPSize() != tc.edns0) {
+			t.Errorf("query passed to Exchange was modified")
+		}
+	}
+
+	// Without UDPSize no OPT is added.
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeSOA)
+	if _, _, err := new(Client).Exchange(m, addrstr); err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if size := <-sizes; size != 0 {
+		t.Errorf("expected query without OPT, got size %d", size)
+	}
+}
+
+func TestConnReadMsgEOF(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeSOA)
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := append([]byte{byte(len(buf) >> 8), byte(len(buf))}, buf...)
+
+	for _, tc := range []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"closed at message boundary", msg, io.EOF},
+		{"closed in length", msg[:1], ErrShortRead},
+		{"closed in message", msg[:len(msg)-3], ErrShortRead},
+	} {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("unable to listen: %v", err)
+		}
+		go func(data []byte) {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			c.Write(data)
+			c.Close()
+		}(tc.data)
+
+		co, err := DialTimeout("tcp", l.Addr().String(), time.Second)
+		if err != nil {
+			t.Fatalf("unable to dial: %v", err)
+		}
+		co.SetReadDeadline(time.Now().Add(time.Second))
+		// The complete message is read first, what follows is the end of the stream.
+		if len(tc.data) == len(msg) {
+			if _, err := co.ReadMsg(); err != nil {
+				t.Errorf("%s: failed to read message: %v", tc.name, err)
+			}
+		}
+		if _, err := co.ReadMsg(); err != tc.expected {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, err)
+		}
+		co.Close()
+		l.Close()
+	}
+}
+
+func TestConnFraming(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeSOA)
+
+	// A pipe is a stream like TCP, messages get a length prefix.
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	co1, co2 := &Conn{Conn: c1}, &Conn{Conn: c2}
+	errc := make(chan error, 1)
+	go func() { errc <- co1.WriteMsg(m) }()
+	r, err := co2.ReadMsg()
+	if err != nil {
+		t.Fatalf("failed to read message from pipe: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("failed to write message to pipe: %v", err)
+	}
+	if r.Id != m.Id || r.Question[0] != m.Question[0] {
+		t.Errorf("expected %v, got %v", m, r)
+	}
+
+	// A UDP socket is a net.PacketConn, messages go out bare.
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer pc.Close()
+	co, err := Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("unable to dial: %v", err)
+	}
+	defer co.Close()
+	if err := co.WriteMsg(m); err != nil {
+		t.Fatalf("failed to write message to UDP: %v", err)
+	}
+	buf := make([]byte, MinMsgSize)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, addr, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to read packet: %v", err)
+	}
+	r = new(Msg)
+	if err := r.Unpack(buf[:n]); err != nil {
+		t.Fatalf("expected a bare message in the packet: %v", err)
+	}
+	r.Response = true
+	out, _ := r.Pack()
+	pc.WriteTo(out, addr)
+	co.SetReadDeadline(time.Now().Add(time.Second))
+	if r, err = co.ReadMsg(); err != nil {
+		t.Fatalf("failed to read message from UDP: %v", err)
+	}
+	if r.Id != m.Id || !r.Response {
+		t.Errorf("expected the reply to %v, got %v", m, r)
+	}
+
+	// A unix socket is a net.PacketConn too, but a stream unless it is unixgram.
+	dir, err := ioutil.TempDir("", "dns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	l, err := net.Listen("unix", filepath.Join(dir, "stream"))
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer l.Close()
+	// The other end reads the raw stream, the message must have a length prefix.
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer c.Close()
+		n, err := tcpMsgLen(c)
+		if err != nil {
+			errc <- err
+			return
+		}
+		p := make([]byte, n)
+		if _, err := tcpRead(c, p); err != nil {
+			errc <- err
+			return
+		}
+		errc <- new(Msg).Unpack(p)
+	}()
+	co, err = Dial("unix", l.Addr().String())
+	if err != nil {
+		t.Fatalf("unable to dial: %v", err)
+	}
+	if err := co.WriteMsg(m); err != nil {
+		t.Fatalf("failed to write message to unix socket: %v", err)
+	}
+	co.Close()
+	if err := <-errc; err != nil {
+		t.Errorf("expected a length prefixed message on the unix socket: %v", err)
+	}
+
+	gc, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: filepath.Join(dir, "dgram"), Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer gc.Close()
+	if (&Conn{Conn: gc}).stream() {
+		t.Error("expected a unixgram socket not to be a stream")
+	}
+}
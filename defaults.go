@@ -2,15 +2,20 @@ package dns
 
 import (
 	"errors"
+	"log"
 	"net"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 const hexDigit = "0123456789abcdef"
 
 // Everything is assumed in ClassINET.
 
-// SetReply creates a reply message from a request message.
+// SetReply creates a reply message from a request message. All questions
+// of the request are copied, although a message with more than one question
+// is usually answered with a FORMERR.
 func (dns *Msg) SetReply(request *Msg) *Msg {
 	dns.Id = request.Id
 	dns.RecursionDesired = request.RecursionDesired // Copy rd bit
@@ -18,8 +23,19 @@ func (dns *Msg) SetReply(request *Msg) *Msg {
 	dns.Opcode = OpcodeQuery
 	dns.Rcode = RcodeSuccess
 	if len(request.Question) > 0 {
-		dns.Question = make([]Question, 1)
-		dns.Question[0] = request.Question[0]
+		dns.Question = make([]Question, len(request.Question))
+		copy(dns.Question, request.Question)
+	}
+	return dns
+}
+
+// SetReplyEdns0 creates a reply message from a request message, like SetReply. When
+// the request has an OPT record, as RFC 6891 requires, an OPT record is added to the
+// reply that advertises udpsize and echoes the DO bit of the request.
+func (dns *Msg) SetReplyEdns0(request *Msg, udpsize uint16) *Msg {
+	dns.SetReply(request)
+	if opt := request.IsEdns0(); opt != nil {
+		dns.SetEdns0(udpsize, opt.Do())
 	}
 	return dns
 }
@@ -28,10 +44,16 @@ func (dns *Msg) SetReply(request *Msg) *Msg {
 // section, generates an Id and sets the RecursionDesired (RD)
 // bit to true.
 func (dns *Msg) SetQuestion(z string, t uint16) *Msg {
+	return dns.SetQuestionClass(z, t, ClassINET)
+}
+
+// SetQuestionClass is like SetQuestion, but the question is for
+// class c, e.g. ClassCHAOS for a "version.bind." TXT query.
+func (dns *Msg) SetQuestionClass(z string, t, c uint16) *Msg {
 	dns.Id = Id()
 	dns.RecursionDesired = true
 	dns.Question = make([]Question, 1)
-	dns.Question[0] = Question{z, t, ClassINET}
+	dns.Question[0] = Question{z, t, c}
 	return dns
 }
 
@@ -64,6 +86,41 @@ func (dns *Msg) SetRcodeFormatError(request *Msg) *Msg {
 	return dns
 }
 
+// SetNameError creates an authoritative NXDOMAIN reply to request, with soa in the
+// authority section. The TTL of the SOA record is set to the negative caching TTL.
+func (dns *Msg) SetNameError(request *Msg, soa *SOA) *Msg {
+	dns.SetRcode(request, RcodeNameError)
+	dns.setNegative(soa)
+	return dns
+}
+
+// SetNoData creates an authoritative NODATA reply to request: a NOERROR reply without
+// answers, with soa in the authority section. The TTL of the SOA record is set to the
+// negative caching TTL.
+func (dns *Msg) SetNoData(request *Msg, soa *SOA) *Msg {
+	dns.SetRcode(request, RcodeSuccess)
+	dns.setNegative(soa)
+	return dns
+}
+
+func (dns *Msg) setNegative(soa *SOA) {
+	dns.Authoritative = true
+	dns.Answer = nil
+	s := soa.copy().(*SOA)
+	s.Hdr.Ttl = NegativeTTL(soa)
+	dns.Ns = []RR{s}
+}
+
+// NegativeTTL returns the TTL for caching a negative answer that has soa in its
+// authority section: the minimum of the TTL of the SOA record and its MINIMUM
+// field, see RFC 2308, section 5.
+func NegativeTTL(soa *SOA) uint32 {
+	if soa.Minttl < soa.Hdr.Ttl {
+		return soa.Minttl
+	}
+	return soa.Hdr.Ttl
+}
+
 // SetUpdate makes the message a dynamic update message. It
 // sets the ZONE section to: z, TypeSOA, ClassINET.
 func (dns *Msg) SetUpdate(z string) *Msg {
@@ -127,6 +184,20 @@ func (dns *Msg) SetEdns0(udpsize uint16, do bool) *Msg {
 	return dns
 }
 
+// SetEdnsBadVers creates a BADVERS reply to request, for a request with an EDNS
+// version that is not supported. The reply has an OPT record with version 0, a UDP
+// size of DefaultMsgSize and the DO bit of the request. The extended rcode is split
+// over the header and the OPT record when the reply is packed.
+func (dns *Msg) SetEdnsBadVers(request *Msg) *Msg {
+	dns.SetRcode(request, RcodeBadVers)
+	do := false
+	if opt := request.IsEdns0(); opt != nil {
+		do = opt.Do()
+	}
+	dns.SetEdns0(DefaultMsgSize, do)
+	return dns
+}
+
 // IsTsig checks if the message has a TSIG record as the last record
 // in the additional section. It returns the TSIG record found or nil.
 func (dns *Msg) IsTsig() *TSIG {
@@ -140,20 +211,294 @@ func (dns *Msg) IsTsig() *TSIG {
 
 // IsEdns0 checks if the message has a EDNS0 (OPT) record, any EDNS0
 // record in the additional section will do. It returns the OPT record
-// found or nil.
+// found or nil. A message with more than one OPT record is malformed, in
+// that case the violation is logged and the first one is returned, see
+// Validate.
 func (dns *Msg) IsEdns0() *OPT {
-	// EDNS0 is at the end of the additional section, start there.
-	// We might want to change this to *only* look at the last two
-	// records. So we see TSIG and/or OPT - this a slightly bigger
-	// change though.
-	for i := len(dns.Extra) - 1; i >= 0; i-- {
-		if dns.Extra[i].Header().Rrtype == TypeOPT {
-			return dns.Extra[i].(*OPT)
+	var opt *OPT
+	for _, r := range dns.Extra {
+		if r.Header().Rrtype != TypeOPT {
+			continue
+		}
+		if opt != nil {
+			log.Printf("%s: more than one OPT RR in the additional section, using the first", ErrFmt)
+			break
 		}
+		opt = r.(*OPT)
+	}
+	return opt
+}
+
+// Normalize reorders the additional section so the OPT RR follows the other RRs
+// and a TSIG or SIG(0) RR is the very last one; some middleboxes drop messages
+// with the OPT RR elsewhere. The order is otherwise kept. Pack always packs the
+// additional section in this order.
+func (dns *Msg) Normalize() {
+	dns.Extra = normalizeExtra(dns.Extra)
+}
+
+// IsAuthoritativeFor checks if the message is an authoritative reply for name: the
+// AA bit must be set and an SOA or NS record in the answer or authority section must
+// have an owner name that is equal to or a parent of name. A referral, which has the
+// NS records of a child zone in the authority section but no AA bit, is not.
+func (dns *Msg) IsAuthoritativeFor(name string) bool {
+	if !dns.Authoritative {
+		return false
+	}
+	for _, section := range [][]RR{dns.Answer, dns.Ns} {
+		for _, r := range section {
+			h := r.Header()
+			if h.Rrtype != TypeSOA && h.Rrtype != TypeNS {
+				continue
+			}
+			if IsSubDomain(strings.ToLower(h.Name), strings.ToLower(name)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Validate checks the message for protocol violations that Unpack accepts. It
+// returns ErrFmt when the additional section holds more than one OPT record.
+func (dns *Msg) Validate() error {
+	opt := 0
+	for _, r := range dns.Extra {
+		if r.Header().Rrtype == TypeOPT {
+			opt++
+		}
+	}
+	if opt > 1 {
+		return ErrFmt
 	}
 	return nil
 }
 
+// MinimalEdns0 removes all EDNS0 options from the OPT record in the message. The
+// OPT record itself is kept, so the UDP buffer size, version, extended rcode and
+// DO bit are still advertised. It does nothing if the message has no OPT record.
+func (dns *Msg) MinimalEdns0() {
+	if opt := dns.IsEdns0(); opt != nil {
+		opt.Option = nil
+	}
+}
+
+// SetAD sets the AuthenticatedData (AD) bit when validated is true, i.e. when the
+// DNSSEC validation of the response succeeded, and clears it otherwise. A response
+// with the CheckingDisabled (CD) bit set is never marked as authenticated.
+func (dns *Msg) SetAD(validated bool) *Msg {
+	dns.AuthenticatedData = validated && !dns.CheckingDisabled
+	return dns
+}
+
+// Minimize turns a positive response into a minimal response: when the rcode is
+// NOERROR and the answer section is not empty, the authority section is cleared and
+// only the OPT and TSIG pseudo records are kept in the additional section.
+func (dns *Msg) Minimize() {
+	if dns.Rcode != RcodeSuccess || len(dns.Answer) == 0 {
+		return
+	}
+	dns.Ns = nil
+	extra := dns.Extra[:0]
+	for _, r := range dns.Extra {
+		if t := r.Header().Rrtype; t == TypeOPT || t == TypeTSIG {
+			extra = append(extra, r)
+		}
+	}
+	dns.Extra = extra
+}
+
+// StripDNSSEC removes the RRSIG, NSEC, NSEC3, NSEC3PARAM and DNSKEY records from all
+// sections of the message, unless their type is the type asked for in the question. This
+// is what a server does when answering a client that did not set the DO bit.
+func (dns *Msg) StripDNSSEC() {
+	qtype := TypeNone
+	if len(dns.Question) > 0 {
+		qtype = dns.Question[0].Qtype
+	}
+	strip := func(rrs []RR) []RR {
+		j := 0
+		for _, r := range rrs {
+			switch t := r.Header().Rrtype; t {
+			case TypeRRSIG, TypeNSEC, TypeNSEC3, TypeNSEC3PARAM, TypeDNSKEY:
+				if t != qtype {
+					continue
+				}
+			}
+			rrs[j] = r
+			j++
+		}
+		return rrs[:j]
+	}
+	dns.Answer = strip(dns.Answer)
+	dns.Ns = strip(dns.Ns)
+	dns.Extra = strip(dns.Extra)
+}
+
+// UDPSize returns the size of the UDP response a server should send for this request:
+// MinMsgSize when the request has no OPT record, otherwise the UDP size from the OPT
+// record, but no less than MinMsgSize and no more than DefaultMsgSize.
+func (dns *Msg) UDPSize() uint16 {
+	opt := dns.IsEdns0()
+	if opt == nil {
+		return MinMsgSize
+	}
+	switch size := opt.UDPSize(); {
+	case size < MinMsgSize:
+		return MinMsgSize
+	case size > DefaultMsgSize:
+		return DefaultMsgSize
+	default:
+		return size
+	}
+}
+
+// FitsUDP returns true when the packed message fits in a UDP response to a client that
+// advertised a buffer size of advertised bytes in its OPT record. Use 0 when the query
+// has no OPT record; any size smaller than MinMsgSize is taken as MinMsgSize. If it
+// does not fit, see Truncate.
+func (dns *Msg) FitsUDP(advertised int) bool {
+	if advertised < MinMsgSize {
+		advertised = MinMsgSize
+	}
+	return dns.Len() <= advertised
+}
+
+// Truncate removes records from the message until it fits in size bytes. Records are
+// removed from the end of the additional, authority and answer section, in that order;
+// the question section, the OPT record and a TSIG or SIG(0) record are kept. When
+// answer or authority records are removed the TC bit is set. As Pack derives the header
+// counts from the sections, the counts in the packed message match the records that
+// are left.
+func (dns *Msg) Truncate(size int) {
+	if dns.Len() <= size {
+		return
+	}
+	answer, ns := dns.Answer, dns.Ns
+	var extra, keepExtra []RR
+	for _, r := range dns.Extra {
+		if extraOrder(r) > 0 {
+			keepExtra = append(keepExtra, r)
+		} else {
+			extra = append(extra, r)
+		}
+	}
+	// keep sets the sections to the first n records of answer, ns and extra.
+	keep := func(n int) {
+		dns.Answer = answer[:minInt(n, len(answer))]
+		n -= len(dns.Answer)
+		dns.Ns = ns[:minInt(n, len(ns))]
+		n -= len(dns.Ns)
+		dns.Extra = extra[:minInt(n, len(extra))]
+		dns.Extra = append(dns.Extra[:len(dns.Extra):len(dns.Extra)], keepExtra...)
+	}
+	total := len(answer) + len(ns) + len(extra)
+	n := sort.Search(total+1, func(n int) bool {
+		keep(n)
+		return dns.Len() > size
+	}) - 1
+	if n < 0 {
+		n = 0
+	}
+	keep(n)
+	if n < len(answer)+len(ns) {
+		dns.Truncated = true
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ForEachRR calls fn for each RR in the answer, authority and additional section
+// of the message, in that order. The section is given as "answer", "authority" or
+// "additional". The iteration stops when fn returns false.
+func (dns *Msg) ForEachRR(fn func(section string, rr RR) bool) {
+	sections := []struct {
+		name string
+		rrs  []RR
+	}{
+		{"answer", dns.Answer},
+		{"authority", dns.Ns},
+		{"additional", dns.Extra},
+	}
+	for _, s := range sections {
+		for _, r := range s.rrs {
+			if !fn(s.name, r) {
+				return
+			}
+		}
+	}
+}
+
+// FilterAnswer returns the RRs of type qtype from the answer section. When the
+// message has a question, only RRs of its class owned by the question name are
+// returned. With followCNAME, RRs owned by a name the question name is aliased to
+// by the CNAMEs in the answer section are returned too; with TypeCNAME as qtype
+// that is the whole CNAME chain.
+func (dns *Msg) FilterAnswer(qtype uint16, followCNAME bool) []RR {
+	var rrs []RR
+	if len(dns.Question) == 0 {
+		for _, r := range dns.Answer {
+			if r.Header().Rrtype == qtype {
+				rrs = append(rrs, r)
+			}
+		}
+		return rrs
+	}
+
+	q := dns.Question[0]
+	names := map[string]bool{strings.ToLower(q.Name): true}
+	if followCNAME {
+		// Follow the chain, the CNAMEs need not be in order.
+		for i := 0; i < len(dns.Answer); i++ {
+			grown := false
+			for _, r := range dns.Answer {
+				c, ok := r.(*CNAME)
+				if !ok || !names[strings.ToLower(c.Hdr.Name)] || names[strings.ToLower(c.Target)] {
+					continue
+				}
+				names[strings.ToLower(c.Target)] = true
+				grown = true
+			}
+			if !grown {
+				break
+			}
+		}
+	}
+	for _, r := range dns.Answer {
+		h := r.Header()
+		if h.Rrtype != qtype || !names[strings.ToLower(h.Name)] {
+			continue
+		}
+		if q.Qclass != ClassANY && h.Class != q.Qclass {
+			continue
+		}
+		rrs = append(rrs, r)
+	}
+	return rrs
+}
+
+// Rrsets groups the RRs in the answer, authority and additional section of the
+// message into RRsets. The key is the lowercased owner name, class and type, as in
+// "miek.nl. IN MX"; RRSIGs are grouped under type RRSIG. The OPT RR is skipped.
+func (dns *Msg) Rrsets() map[string][]RR {
+	sets := make(map[string][]RR)
+	dns.ForEachRR(func(section string, r RR) bool {
+		h := r.Header()
+		if h.Rrtype == TypeOPT {
+			return true
+		}
+		key := strings.ToLower(h.Name) + " " + Class(h.Class).String() + " " + Type(h.Rrtype).String()
+		sets[key] = append(sets[key], r)
+		return true
+	})
+	return sets
+}
+
 // IsDomainName checks if s is a valid domain name, it returns the number of
 // labels and true, when a domain name is valid.  Note that non fully qualified
 // domain name is considered valid, in this case the last label is counted in
@@ -260,6 +605,18 @@ func ReverseAddr(addr string) (arpa string, err error) {
 	return string(buf), nil
 }
 
+// SupportedTypes returns the RR types this package can pack, unpack and parse, in
+// ascending order. This includes the types registered with PrivateHandle. Use the
+// TypeToString and StringToType maps to convert between a type and its mnemonic.
+func SupportedTypes() []uint16 {
+	types := make([]uint16, 0, len(TypeToRR))
+	for t := range TypeToRR {
+		types = append(types, t)
+	}
+	sort.Sort(uint16Slice(types))
+	return types
+}
+
 // String returns the string representation for the type t.
 func (t Type) String() string {
 	if t1, ok := TypeToString[uint16(t)]; ok {
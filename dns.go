@@ -78,7 +78,7 @@ func (h *RR_Header) String() string {
 }
 
 func (h *RR_Header) len() int {
-	l := len(h.Name) + 1
+	l := domainNameLen(h.Name)
 	l += 10 // rrtype(2) + class(2) + ttl(4) + rdlength(2)
 	return l
 }
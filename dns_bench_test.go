@@ -2,6 +2,7 @@ package dns
 
 import (
 	"net"
+	"strconv"
 	"testing"
 )
 
@@ -209,3 +210,36 @@ func BenchmarkIdGeneration(b *testing.B) {
 		_ = id()
 	}
 }
+
+func makeLargeMsg(n int) *Msg {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeAXFR)
+	for i := 0; i < n; i++ {
+		rr := &A{
+			Hdr: RR_Header{Name: "host" + strconv.Itoa(i) + ".example.org.", Rrtype: TypeA, Class: ClassINET, Ttl: 3600},
+			A:   net.IPv4(127, 0, byte(i>>8), byte(i)),
+		}
+		m.Answer = append(m.Answer, rr)
+	}
+	m.Compress = true
+	return m
+}
+
+func BenchmarkPackLargeMsg(b *testing.B) {
+	m := makeLargeMsg(500)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = m.Pack()
+	}
+}
+
+func BenchmarkPackBufferLargeMsg(b *testing.B) {
+	m := makeLargeMsg(500)
+	buf := make([]byte, MaxMsgSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = m.PackBuffer(buf)
+	}
+}
@@ -1,9 +1,15 @@
 package dns
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"net"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestPackUnpack(t *testing.T) {
@@ -259,8 +265,7 @@ func TestMsgLength2(t *testing.T) {
 		// google.com. IN A?
 		"064e81800001000b0004000506676f6f676c6503636f6d0000010001c00c00010001000000050004adc22986c00c00010001000000050004adc22987c00c00010001000000050004adc22988c00c00010001000000050004adc22989c00c00010001000000050004adc2298ec00c00010001000000050004adc22980c00c00010001000000050004adc22981c00c00010001000000050004adc22982c00c00010001000000050004adc22983c00c00010001000000050004adc22984c00c00010001000000050004adc22985c00c00020001000000050006036e7331c00cc00c00020001000000050006036e7332c00cc00c00020001000000050006036e7333c00cc00c00020001000000050006036e7334c00cc0d800010001000000050004d8ef200ac0ea00010001000000050004d8ef220ac0fc00010001000000050004d8ef240ac10e00010001000000050004d8ef260a0000290500000000050000",
 		// amazon.com. IN A? (reply has no EDNS0 record)
-		// TODO(miek): this one is off-by-one, need to find out why
-		//"6de1818000010004000a000806616d617a6f6e03636f6d0000010001c00c000100010000000500044815c2d4c00c000100010000000500044815d7e8c00c00010001000000050004b02062a6c00c00010001000000050004cdfbf236c00c000200010000000500140570646e733408756c747261646e73036f726700c00c000200010000000500150570646e733508756c747261646e7304696e666f00c00c000200010000000500160570646e733608756c747261646e7302636f02756b00c00c00020001000000050014036e7331037033310664796e656374036e657400c00c00020001000000050006036e7332c0cfc00c00020001000000050006036e7333c0cfc00c00020001000000050006036e7334c0cfc00c000200010000000500110570646e733108756c747261646e73c0dac00c000200010000000500080570646e7332c127c00c000200010000000500080570646e7333c06ec0cb00010001000000050004d04e461fc0eb00010001000000050004cc0dfa1fc0fd00010001000000050004d04e471fc10f00010001000000050004cc0dfb1fc12100010001000000050004cc4a6c01c121001c000100000005001020010502f3ff00000000000000000001c13e00010001000000050004cc4a6d01c13e001c0001000000050010261000a1101400000000000000000001",
+		"6de1818000010004000a000806616d617a6f6e03636f6d0000010001c00c000100010000000500044815c2d4c00c000100010000000500044815d7e8c00c00010001000000050004b02062a6c00c00010001000000050004cdfbf236c00c000200010000000500140570646e733408756c747261646e73036f726700c00c000200010000000500150570646e733508756c747261646e7304696e666f00c00c000200010000000500160570646e733608756c747261646e7302636f02756b00c00c00020001000000050014036e7331037033310664796e656374036e657400c00c00020001000000050006036e7332c0cfc00c00020001000000050006036e7333c0cfc00c00020001000000050006036e7334c0cfc00c000200010000000500110570646e733108756c747261646e73c0dac00c000200010000000500080570646e7332c127c00c000200010000000500080570646e7333c06ec0cb00010001000000050004d04e461fc0eb00010001000000050004cc0dfa1fc0fd00010001000000050004d04e471fc10f00010001000000050004cc0dfb1fc12100010001000000050004cc4a6c01c121001c000100000005001020010502f3ff00000000000000000001c13e00010001000000050004cc4a6d01c13e001c0001000000050010261000a1101400000000000000000001",
 		// yahoo.com. IN A?
 		"fc2d81800001000300070008057961686f6f03636f6d0000010001c00c00010001000000050004628afd6dc00c00010001000000050004628bb718c00c00010001000000050004cebe242dc00c00020001000000050006036e7336c00cc00c00020001000000050006036e7338c00cc00c00020001000000050006036e7331c00cc00c00020001000000050006036e7332c00cc00c00020001000000050006036e7333c00cc00c00020001000000050006036e7334c00cc00c00020001000000050006036e7335c00cc07b0001000100000005000444b48310c08d00010001000000050004448eff10c09f00010001000000050004cb54dd35c0b100010001000000050004628a0b9dc0c30001000100000005000477a0f77cc05700010001000000050004ca2bdfaac06900010001000000050004caa568160000290500000000050000",
 		// microsoft.com. IN A?
@@ -285,15 +290,63 @@ func TestMsgLength2(t *testing.T) {
 		lenUnComp := m.Len()
 		b, _ = m.Pack()
 		pacUnComp := len(b)
-		if pacComp+1 != lenComp {
+		if pacComp != lenComp {
 			t.Errorf("msg.Len(compressed)=%d actual=%d for test %d", lenComp, pacComp, i)
 		}
-		if pacUnComp+1 != lenUnComp {
+		if pacUnComp != lenUnComp {
 			t.Errorf("msg.Len(uncompressed)=%d actual=%d for test %d", lenUnComp, pacUnComp, i)
 		}
 	}
 }
 
+func TestMsgLengthQuestionOnly(t *testing.T) {
+	for name, expected := range map[string]int{"miek.nl.": 12 + 9 + 4, ".": 12 + 1 + 4} {
+		m := new(Msg)
+		m.SetQuestion(name, TypeA)
+		for _, compress := range []bool{false, true} {
+			m.Compress = compress
+			b, err := m.Pack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(b) != expected || m.Len() != expected {
+				t.Errorf("%s: expected length %d, got Len() %d and packed length %d", name, expected, m.Len(), len(b))
+			}
+		}
+	}
+}
+
+func TestMsgPackEmptyRdata(t *testing.T) {
+	hdr := func(rrtype uint16) RR_Header {
+		return RR_Header{Name: "miek.nl.", Rrtype: rrtype, Class: ClassINET, Ttl: 3600}
+	}
+	for _, rr := range []RR{
+		&TXT{Hdr: hdr(TypeTXT)},
+		&SPF{Hdr: hdr(TypeSPF)},
+		&NINFO{Hdr: hdr(TypeNINFO)},
+		&AVC{Hdr: hdr(TypeAVC)},
+		newRR(t, "miek.nl. 3600 IN CAA 0 issue \"\""),
+		newRR(t, "miek.nl. 3600 IN URI 10 1 \"\""),
+	} {
+		m := new(Msg)
+		m.SetQuestion("miek.nl.", rr.Header().Rrtype)
+		m.Answer = []RR{rr}
+		buf, err := m.Pack()
+		if err != nil {
+			t.Errorf("failed to pack %s: %v", rr, err)
+			continue
+		}
+		m1 := new(Msg)
+		if err := m1.Unpack(buf); err != nil {
+			t.Errorf("failed to unpack %s: %v", rr, err)
+			continue
+		}
+		if len(m1.Answer) != 1 || m1.Answer[0].String() != rr.String() {
+			t.Errorf("expected %s after unpack, got %v", rr, m1.Answer)
+		}
+	}
+}
+
 func TestMsgLengthCompressionMalformed(t *testing.T) {
 	// SOA with empty hostmaster, which is illegal
 	soa := &SOA{Hdr: RR_Header{Name: ".", Rrtype: TypeSOA, Class: ClassINET, Ttl: 12345},
@@ -384,6 +437,18 @@ func TestCopy(t *testing.T) {
 	}
 }
 
+func TestCopyAPL(t *testing.T) {
+	rr := newRR(t, "example. 3600 IN APL 1:192.0.2.0/24 !2:2001:db8::/32")
+	rr1 := Copy(rr).(*APL)
+	p := &rr1.Prefixes[0].Network
+	p.IP[0], p.Mask[3] = 10, 0xFF
+	p = &rr1.Prefixes[1].Network
+	p.IP[0], p.Mask[15] = 0, 0xFF
+	if s := rr.String(); s != "example.\t3600\tIN\tAPL\t1:192.0.2.0/24 !2:2001:db8::/32" {
+		t.Errorf("modifying the copy changed the original: %s", s)
+	}
+}
+
 func TestMsgCopy(t *testing.T) {
 	m := new(Msg)
 	m.SetQuestion("miek.nl.", TypeA)
@@ -431,3 +496,1167 @@ func TestMsgPackBuffer(t *testing.T) {
 		t.Logf("packet %d %s", i, m.String())
 	}
 }
+
+func TestMsgAutoCompress(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("www.example.com.", TypeA)
+	m.AutoCompress = true
+	m.Answer = append(m.Answer, &A{Hdr: RR_Header{Name: "www.example.com.", Rrtype: TypeA, Class: ClassINET}, A: net.IPv4(127, 0, 0, 1)})
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := m.packLen(false); len(buf) != l {
+		t.Errorf("small message should not be compressed, got %d bytes, expected %d", len(buf), l)
+	}
+
+	for i := 0; i < 40; i++ {
+		m.Answer = append(m.Answer, &A{Hdr: RR_Header{Name: "www.example.com.", Rrtype: TypeA, Class: ClassINET}, A: net.IPv4(127, 0, 0, byte(i))})
+	}
+	if m.packLen(false) <= MinMsgSize {
+		t.Fatalf("message should be larger than %d bytes", MinMsgSize)
+	}
+	buf, err = m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := m.packLen(true); len(buf) != l {
+		t.Errorf("large message should be compressed, got %d bytes, expected %d", len(buf), l)
+	}
+	if len(buf) != m.Len() {
+		t.Errorf("Len() %d does not match packed length %d", m.Len(), len(buf))
+	}
+	if m.Compress {
+		t.Error("Pack should not change Compress")
+	}
+
+	// Without AutoCompress, Compress = false is respected.
+	m.AutoCompress = false
+	buf, err = m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := m.packLen(false); len(buf) != l {
+		t.Errorf("uncompressed message is compressed, got %d bytes, expected %d", len(buf), l)
+	}
+	if len(buf) != m.Len() {
+		t.Errorf("Len() %d does not match packed length %d", m.Len(), len(buf))
+	}
+}
+
+func TestMsgMinimize(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	m.Response = true
+	m.Answer = []RR{newRR(t, "miek.nl. 3600 IN A 192.0.2.1")}
+	m.Ns = []RR{newRR(t, "miek.nl. 3600 IN NS ns.miek.nl.")}
+	m.Extra = []RR{newRR(t, "ns.miek.nl. 3600 IN A 192.0.2.53")}
+	m.SetEdns0(4096, true)
+
+	nodata := m.Copy()
+	nodata.Answer = nil
+
+	m.Minimize()
+	if len(m.Answer) != 1 || len(m.Ns) != 0 {
+		t.Errorf("expected 1 answer and no authority records, got %d and %d", len(m.Answer), len(m.Ns))
+	}
+	if len(m.Extra) != 1 || m.IsEdns0() == nil {
+		t.Errorf("expected only the OPT record in the additional section, got %v", m.Extra)
+	}
+
+	nodata.Minimize()
+	if len(nodata.Ns) != 1 || len(nodata.Extra) != 2 {
+		t.Errorf("negative response should not be minimized, got %d authority and %d additional records", len(nodata.Ns), len(nodata.Extra))
+	}
+}
+
+func TestMsgFitsUDP(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	m.Compress = false
+	// 12 + 13 (question) + 19 (header of the RR) = 44, the rest is for the TXT strings.
+	rdata := strings.Repeat("x", 255)
+	m.Answer = []RR{&TXT{Hdr: RR_Header{Name: "miek.nl.", Rrtype: TypeTXT, Class: ClassINET, Ttl: 3600},
+		Txt: []string{rdata, rdata[:MinMsgSize-44-256-1]}}}
+	if l := m.Len(); l != MinMsgSize {
+		t.Fatalf("expected message of %d bytes, got %d", MinMsgSize, l)
+	}
+	if !m.FitsUDP(0) || !m.FitsUDP(MinMsgSize) {
+		t.Error("message of MinMsgSize bytes should fit without OPT record")
+	}
+	m.Answer[0].(*TXT).Txt[1] += "x"
+	if m.FitsUDP(0) || m.FitsUDP(MinMsgSize) || m.FitsUDP(100) {
+		t.Error("message of MinMsgSize+1 bytes should not fit without OPT record")
+	}
+	if !m.FitsUDP(4096) {
+		t.Error("message of MinMsgSize+1 bytes should fit when 4096 is advertised")
+	}
+
+	for len(m.Answer[0].(*TXT).Txt) < 16 {
+		m.Answer[0].(*TXT).Txt = append(m.Answer[0].(*TXT).Txt, rdata)
+	}
+	if l := m.Len(); l < 4096 {
+		t.Fatalf("expected message larger than 4096 bytes, got %d", l)
+	}
+	if m.FitsUDP(4096) {
+		t.Error("message larger than 4096 bytes should not fit")
+	}
+}
+
+func TestMsgTruncate(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	for i := 0; i < 40; i++ {
+		m.Answer = append(m.Answer, newRR(t, fmt.Sprintf("miek.nl. 3600 IN A 192.0.2.%d", i)))
+	}
+	m.Ns = []RR{newRR(t, "miek.nl. 3600 IN NS ns.miek.nl.")}
+	m.Extra = []RR{newRR(t, "ns.miek.nl. 3600 IN A 192.0.2.53")}
+	m.SetEdns0(4096, false)
+	m.Compress = true
+
+	m.Truncate(MinMsgSize)
+	if !m.Truncated {
+		t.Error("expected TC bit to be set")
+	}
+	if m.IsEdns0() == nil {
+		t.Error("expected OPT record to be kept")
+	}
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) > MinMsgSize {
+		t.Errorf("expected message to fit in %d bytes, got %d", MinMsgSize, len(buf))
+	}
+	if len(m.Answer) == 0 || len(m.Answer) == 40 || len(m.Ns) != 0 || len(m.Extra) != 1 {
+		t.Errorf("unexpected sections after truncation: %d answer, %d authority, %d additional", len(m.Answer), len(m.Ns), len(m.Extra))
+	}
+	counts := []int{len(m.Question), len(m.Answer), len(m.Ns), len(m.Extra)}
+	for i, c := range counts {
+		if n := int(binary.BigEndian.Uint16(buf[4+2*i:])); n != c {
+			t.Errorf("header count %d is %d, expected %d", i, n, c)
+		}
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != ErrTruncated {
+		t.Fatalf("expected ErrTruncated, got %v", err)
+	}
+	if len(m1.Answer) != len(m.Answer) {
+		t.Errorf("expected %d answer records after unpack, got %d", len(m.Answer), len(m1.Answer))
+	}
+}
+
+func TestMsgTruncateTsig(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	for i := 0; i < 40; i++ {
+		m.Answer = append(m.Answer, newRR(t, fmt.Sprintf("miek.nl. 3600 IN A 192.0.2.%d", i)))
+	}
+	m.Extra = []RR{newRR(t, "ns.miek.nl. 3600 IN A 192.0.2.53")}
+	m.SetEdns0(4096, false)
+	m.SetTsig("example.", HmacMD5, 300, time.Now().Unix())
+
+	m.Truncate(MinMsgSize)
+	if !m.Truncated {
+		t.Error("expected TC bit to be set")
+	}
+	if m.IsEdns0() == nil {
+		t.Error("expected OPT record to be kept")
+	}
+	if m.IsTsig() == nil {
+		t.Fatal("expected TSIG record to be kept as the last record")
+	}
+	if len(m.Extra) != 2 {
+		t.Errorf("expected only the OPT and TSIG record in the additional section, got %d records", len(m.Extra))
+	}
+	buf, _, err := TsigGenerate(m, "pRZgBrBvI4NAHZYhxmhs/Q==", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := TsigVerify(buf, "pRZgBrBvI4NAHZYhxmhs/Q==", "", false); err != nil {
+		t.Errorf("failed to verify the truncated message: %v", err)
+	}
+}
+
+func TestMsgForEachRR(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeMX)
+	m.Answer = []RR{newRR(t, "miek.nl. 3600 IN MX 10 mx.miek.nl."), newRR(t, "miek.nl. 3600 IN MX 20 mx2.miek.nl.")}
+	m.Ns = []RR{newRR(t, "miek.nl. 3600 IN NS ns.miek.nl.")}
+	m.Extra = []RR{newRR(t, "mx.miek.nl. 3600 IN A 127.0.0.1")}
+	m.SetEdns0(4096, true)
+
+	count := map[string]int{}
+	m.ForEachRR(func(section string, rr RR) bool {
+		count[section]++
+		return true
+	})
+	if count["answer"] != 2 || count["authority"] != 1 || count["additional"] != 2 {
+		t.Errorf("wrong number of RRs seen: %v", count)
+	}
+
+	var opt *OPT
+	seen := 0
+	m.ForEachRR(func(section string, rr RR) bool {
+		seen++
+		if o, ok := rr.(*OPT); ok {
+			opt = o
+			return false
+		}
+		return true
+	})
+	if opt == nil {
+		t.Fatal("expected to find the OPT RR")
+	}
+	if seen != 5 {
+		t.Errorf("expected to see 5 RRs, saw %d", seen)
+	}
+
+	seen = 0
+	m.ForEachRR(func(section string, rr RR) bool {
+		seen++
+		return false
+	})
+	if seen != 1 {
+		t.Errorf("expected iteration to stop after 1 RR, saw %d", seen)
+	}
+}
+
+func TestMsgFilterAnswer(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("www.example.org.", TypeA)
+	m.Answer = []RR{
+		newRR(t, "www.example.org. 3600 IN CNAME cdn.example.net."),
+		newRR(t, "www.example.org. 3600 IN RRSIG CNAME 8 3 3600 20170101000000 20160101000000 12345 example.org. AwEAAQ=="),
+		newRR(t, "edge.example.com. 300 IN A 192.0.2.2"),
+		newRR(t, "cdn.example.net. 300 IN CNAME EDGE.example.com."),
+		newRR(t, "edge.example.com. 300 IN A 192.0.2.1"),
+		newRR(t, "edge.example.com. 300 IN RRSIG A 8 3 300 20170101000000 20160101000000 54321 example.com. AwEAAQ=="),
+		newRR(t, "other.example.com. 300 IN A 192.0.2.3"),
+	}
+	m.Ns = []RR{newRR(t, "example.com. 3600 IN NS ns.example.com.")}
+	m.Extra = []RR{newRR(t, "ns.example.com. 3600 IN A 192.0.2.53")}
+	m.SetEdns0(4096, true)
+
+	a := m.FilterAnswer(TypeA, true)
+	if len(a) != 2 || a[0] != m.Answer[2] || a[1] != m.Answer[4] {
+		t.Errorf("expected the 2 A records of edge.example.com., got %v", a)
+	}
+	if a := m.FilterAnswer(TypeA, false); len(a) != 0 {
+		t.Errorf("expected no A records owned by the question name, got %v", a)
+	}
+	if c := m.FilterAnswer(TypeCNAME, true); len(c) != 2 || c[0] != m.Answer[0] || c[1] != m.Answer[3] {
+		t.Errorf("expected the CNAME chain, got %v", c)
+	}
+	if c := m.FilterAnswer(TypeCNAME, false); len(c) != 1 || c[0] != m.Answer[0] {
+		t.Errorf("expected only the CNAME owned by the question name, got %v", c)
+	}
+	if aaaa := m.FilterAnswer(TypeAAAA, true); len(aaaa) != 0 {
+		t.Errorf("expected no AAAA records, got %v", aaaa)
+	}
+
+	m.SetQuestion("edge.example.com.", TypeA)
+	if a := m.FilterAnswer(TypeA, false); len(a) != 2 {
+		t.Errorf("expected the 2 A records of edge.example.com. without following, got %v", a)
+	}
+
+	m.Question = nil
+	if a := m.FilterAnswer(TypeA, false); len(a) != 3 {
+		t.Errorf("expected all 3 A records without a question, got %v", a)
+	}
+
+	sets := m.Rrsets()
+	if len(sets) != 8 {
+		t.Errorf("expected 8 RRsets, got %d: %v", len(sets), sets)
+	}
+	if s := sets["edge.example.com. IN A"]; len(s) != 2 {
+		t.Errorf("expected an RRset of 2 A records, got %v", s)
+	}
+	if s := sets["edge.example.com. IN RRSIG"]; len(s) != 1 {
+		t.Errorf("expected an RRset of 1 RRSIG, got %v", s)
+	}
+	if s := sets["ns.example.com. IN A"]; len(s) != 1 {
+		t.Errorf("expected the A record from the additional section, got %v", s)
+	}
+}
+
+func TestMsgNormalize(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	m.SetEdns0(4096, true)
+	m.Extra = append(m.Extra, newRR(t, "ns.miek.nl. 3600 IN A 127.0.0.1"))
+	m.SetTsig("axfr.", HmacMD5, 300, 0)
+	m.Extra[0], m.Extra[2] = m.Extra[2], m.Extra[0] // TSIG, A, OPT
+
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Extra[0].Header().Rrtype != TypeTSIG {
+		t.Error("expected Pack to leave the additional section alone")
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint16{TypeA, TypeOPT, TypeTSIG}
+	if len(m1.Extra) != len(expected) {
+		t.Fatalf("expected %d RRs in the additional section, got %d", len(expected), len(m1.Extra))
+	}
+	for i, typ := range expected {
+		if m1.Extra[i].Header().Rrtype != typ {
+			t.Errorf("expected %s at position %d, got %s", Type(typ), i, Type(m1.Extra[i].Header().Rrtype))
+		}
+	}
+
+	m.Normalize()
+	for i, typ := range expected {
+		if m.Extra[i].Header().Rrtype != typ {
+			t.Errorf("expected %s at position %d after Normalize, got %s", Type(typ), i, Type(m.Extra[i].Header().Rrtype))
+		}
+	}
+	if m.IsTsig() == nil {
+		t.Error("expected the TSIG to be last after Normalize")
+	}
+
+	// Only a SIG(0) is moved to the end, not a SIG covering an RRset.
+	m = new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	m.Extra = []RR{
+		&SIG{RRSIG{Hdr: RR_Header{Name: ".", Rrtype: TypeSIG, Class: ClassANY}, Algorithm: RSASHA256, SignerName: "miek.nl.", Signature: "AwEAAQ=="}},
+		newRR(t, "ns.miek.nl. 3600 IN SIG A 8 3 3600 20170101000000 20160101000000 12345 miek.nl. AwEAAQ=="),
+		newRR(t, "ns.miek.nl. 3600 IN A 127.0.0.1"),
+	}
+	m.Normalize()
+	if sig, ok := m.Extra[0].(*SIG); !ok || sig.TypeCovered != TypeA {
+		t.Errorf("expected the SIG covering A to stay first after Normalize, got %s", m.Extra[0])
+	}
+	if sig, ok := m.Extra[2].(*SIG); !ok || sig.TypeCovered != 0 {
+		t.Errorf("expected the SIG(0) to be last after Normalize, got %s", m.Extra[2])
+	}
+}
+
+func TestMsgHdrPack(t *testing.T) {
+	for _, h := range []MsgHdr{
+		{},
+		{Id: 0xbeef, Response: true, Opcode: OpcodeUpdate, Authoritative: true, Truncated: true, RecursionDesired: true,
+			RecursionAvailable: true, Zero: true, AuthenticatedData: true, CheckingDisabled: true, Rcode: RcodeRefused},
+		{Id: 1, Opcode: OpcodeNotify, Authoritative: true, Rcode: RcodeNameError},
+		{Id: 2, Response: true, Truncated: true, CheckingDisabled: true},
+	} {
+		buf := h.Pack()
+		m := &Msg{MsgHdr: h}
+		wire, err := m.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf[:], wire) {
+			t.Errorf("expected the header of Msg.Pack, %x, got %x", wire, buf)
+		}
+		h1, err := UnpackHeader(buf[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h1 != h {
+			t.Errorf("round trip failed, got %+v, expected %+v", h1, h)
+		}
+	}
+
+	if _, err := UnpackHeader(make([]byte, 11)); err == nil {
+		t.Error("expected error unpacking a short header")
+	}
+}
+
+func TestIdOverride(t *testing.T) {
+	defer func(f func() uint16) { Id = f }(Id)
+	Id = func() uint16 { return 3 }
+
+	m1 := new(Msg).SetQuestion("miek.nl.", TypeMX)
+	m2 := new(Msg).SetQuestion("miek.nl.", TypeMX)
+	if m1.Id != 3 || m2.Id != 3 {
+		t.Fatalf("expected id 3, got %d and %d", m1.Id, m2.Id)
+	}
+	buf1, err := m1.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf2, err := m2.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf1, buf2) {
+		t.Errorf("messages with the same id should pack identically")
+	}
+}
+
+func TestPackedId(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	m.Id = 0xabcd
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := PackedId(buf); id != 0xabcd {
+		t.Errorf("expected id 0xabcd, got %#x", id)
+	}
+	SetPackedId(buf, 0x1234)
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if m1.Id != 0x1234 || m1.Question[0].Name != "miek.nl." {
+		t.Errorf("expected id 0x1234 and an otherwise unchanged message, got %#x\n%s", m1.Id, m1)
+	}
+
+	short := []byte{0xff}
+	if id := PackedId(short); id != 0 {
+		t.Errorf("expected id 0 for a short message, got %#x", id)
+	}
+	SetPackedId(short, 0x1234)
+	if short[0] != 0xff {
+		t.Error("short message should not be modified")
+	}
+}
+
+func TestPackPTRCompression(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("1.2.0.192.in-addr.arpa.", TypePTR)
+	m.Compress = true
+	for _, s := range []string{
+		"1.2.0.192.in-addr.arpa. 3600 IN PTR www.example.org.",
+		"1.2.0.192.in-addr.arpa. 3600 IN PTR mail.example.org.",
+		"1.2.0.192.in-addr.arpa. 3600 IN PTR ftp.example.org.",
+	} {
+		m.Answer = append(m.Answer, newRR(t, s))
+	}
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the first target spells out example.org., the others point to it.
+	if n := bytes.Count(buf, []byte("\x07example\x03org\x00")); n != 1 {
+		t.Errorf("expected example.org. to be present once, got %d", n)
+	}
+	if l := m.packLen(false); len(buf) >= l {
+		t.Errorf("PTR targets should be compressed, got %d bytes, uncompressed is %d", len(buf), l)
+	}
+	if len(buf) != m.Len() {
+		t.Errorf("Len() %d does not match packed length %d", m.Len(), len(buf))
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if m1.Answer[2].(*PTR).Ptr != "ftp.example.org." {
+		t.Errorf("wrong PTR target after unpack: %s", m1.Answer[2].(*PTR).Ptr)
+	}
+}
+
+func TestPackRootCompression(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion(".", TypeDNSKEY)
+	m.Compress = true
+	for _, s := range []string{
+		". 3600 IN DNSKEY 257 3 8 AwEAAagAIKlVZrpC6Ia7gEzahOR+9W29euxhJhVVLOyQbSEW0O8gcCjFFVQUTf6v58fLjwBd0YI0EzrAcQqBGCzh/RStIoO8g0NfnfL2MTJRkxoXbfDaUeVPQuYEhg37NZWAJQ9VnMVDxP/VHL496M/QZxkjf5/Efucp2gaDX6RS6CXpoY68LsvPVjR0ZSwzz1apAzvN9dlzEheX7ICJBBtuA6G3LQpzW5hOA2hzCTMjJPJ8LbqF6dsV6DoBQzgul0sGIcGOYl7OyQdXfZ57relSQageu+ipAdTTJ25AsRTAoub8ONGcLmqrAmRLKBP1dfwhYB4N7knNnulqQxA+Uk1ihz0=",
+		". 3600 IN NS a.root-servers.net.",
+		". 3600 IN NS b.root-servers.net.",
+		"a.root-servers.net. 3600 IN A 198.41.0.4",
+		"example. 3600 IN NS .",
+	} {
+		m.Answer = append(m.Answer, newRR(t, s))
+	}
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Len() < len(buf) {
+		t.Errorf("Len() %d is smaller than packed length %d", m.Len(), len(buf))
+	}
+	// The DNSKEY owner directly follows the question and must be a single zero octet, not a pointer.
+	if buf[17] != 0 {
+		t.Errorf("root owner name should be packed as a zero octet, got %#x", buf[17])
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	for i, rr := range m1.Answer {
+		if rr.String() != m.Answer[i].String() {
+			t.Errorf("record %d differs after unpack: %s", i, rr)
+		}
+	}
+}
+
+func TestMsgPackBufferSizes(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeANY)
+	for _, s := range []string{
+		"miek.nl. 3600 IN SOA open.nlnetlabs.nl. miekg.atoom.net. 1 14400 3600 604800 86400",
+		"miek.nl. 3600 IN MX 10 mx.miek.nl.",
+		"miek.nl. 3600 IN TXT \"a\" \"bb\" \"ccc\"",
+		"miek.nl. 3600 IN AAAA 2001:db8::1",
+		"_sip._tcp.miek.nl. 3600 IN SRV 10 20 5060 sip.miek.nl.",
+		"miek.nl. 3600 IN NSEC www.miek.nl. A MX RRSIG NSEC",
+		"miek.nl. 3600 IN DS 12345 8 2 0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF",
+		"miek.nl. 3600 IN CAA 0 issue \"ca.example.net\"",
+	} {
+		m.Answer = append(m.Answer, newRR(t, s))
+	}
+	m.SetEdns0(4096, true)
+	m.IsEdns0().Option = []EDNS0{&EDNS0_NSID{Code: EDNS0NSID, Nsid: "6e73"}}
+	for _, compress := range []bool{false, true} {
+		m.Compress = compress
+		expected, err := m.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i <= len(expected)+1; i++ {
+			// Use a buffer with garbage in it, as if it was used before.
+			buf, err := m.PackBuffer(bytes.Repeat([]byte{0xff}, i))
+			if err != nil {
+				t.Fatalf("failed to pack in buffer of %d bytes: %v", i, err)
+			}
+			if !bytes.Equal(buf, expected) {
+				t.Fatalf("packing in buffer of %d bytes gives a different message", i)
+			}
+		}
+	}
+}
+
+func TestMsgPackBufferOneShort(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeA)
+	m.Answer = []RR{newRR(t, "example.org. 3600 IN CNAME www.example.net.")}
+	m.Compress = true
+	expected, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The terminating zero of the last name doesn't fit.
+	buf, err := m.PackBuffer(make([]byte, len(expected)-1))
+	if err != nil {
+		t.Fatalf("failed to pack in a buffer one byte short: %v", err)
+	}
+	if !bytes.Equal(buf, expected) {
+		t.Error("packing in a buffer one byte short gives a different message")
+	}
+}
+
+func TestUnpackUintOverflow(t *testing.T) {
+	msg := []byte{0x01, 0x02, 0x03, 0x04}
+	off := len(msg) - 1
+	if _, off1, err := unpackUint16(msg, off); err == nil || off1 != len(msg) {
+		t.Errorf("expected overflow unpacking uint16 at offset %d, got %v", off, err)
+	}
+	if _, off1, err := unpackUint32(msg, off); err == nil || off1 != len(msg) {
+		t.Errorf("expected overflow unpacking uint32 at offset %d, got %v", off, err)
+	}
+	if _, off1, err := unpackUint48(msg, off); err == nil || off1 != len(msg) {
+		t.Errorf("expected overflow unpacking uint48 at offset %d, got %v", off, err)
+	}
+	if _, off1, err := unpackUint64(msg, off); err == nil || off1 != len(msg) {
+		t.Errorf("expected overflow unpacking uint64 at offset %d, got %v", off, err)
+	}
+	if i, off1, err := unpackUint8(msg, off); err != nil || i != 0x04 || off1 != len(msg) {
+		t.Errorf("expected to unpack the last octet, got %d, %v", i, err)
+	}
+	if i, _, err := unpackUint16(msg, off-1); err != nil || i != 0x0304 {
+		t.Errorf("expected to unpack the last two octets, got %d, %v", i, err)
+	}
+}
+
+func TestMsgMultipleQuestions(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	m.Question = append(m.Question, Question{"example.org.", TypeAAAA, ClassINET})
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qdcount := binary.BigEndian.Uint16(buf[4:]); qdcount != 2 {
+		t.Errorf("expected 2 questions in the header, got %d", qdcount)
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m1.Question, m.Question) {
+		t.Errorf("expected questions %v after unpack, got %v", m.Question, m1.Question)
+	}
+
+	r := new(Msg)
+	r.SetRcode(m1, RcodeFormatError)
+	if !reflect.DeepEqual(r.Question, m.Question) {
+		t.Errorf("expected reply to copy questions %v, got %v", m.Question, r.Question)
+	}
+	r.Question[1].Name = "example.net."
+	if m1.Question[1].Name != "example.org." {
+		t.Error("reply should not share the question section with the request")
+	}
+}
+
+func TestSupportedTypes(t *testing.T) {
+	types := SupportedTypes()
+	if len(types) != len(TypeToRR) {
+		t.Fatalf("expected %d types, got %d", len(TypeToRR), len(types))
+	}
+	for i := 1; i < len(types); i++ {
+		if types[i-1] >= types[i] {
+			t.Fatalf("types not sorted: %d before %d", types[i-1], types[i])
+		}
+	}
+	for _, want := range []uint16{TypeA, TypeNS, TypeMX, TypeAAAA, TypeSRV, TypeDNSKEY, TypeAPL} {
+		found := false
+		for _, typ := range types {
+			if typ == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("type %s not in supported types", Type(want))
+		}
+	}
+	for _, typ := range types {
+		s, ok := TypeToString[typ]
+		if !ok {
+			t.Errorf("no string for supported type %d", typ)
+			continue
+		}
+		if typ1, ok := StringToType[s]; !ok || typ1 != typ {
+			t.Errorf("%s does not round-trip: got %d, expected %d", s, typ1, typ)
+		}
+	}
+}
+
+func TestMsgNegativeReply(t *testing.T) {
+	soa := newRR(t, "miek.nl. 3600 IN SOA linode.atoom.net. miek.miek.nl. 1282630057 14400 3600 604800 300").(*SOA)
+	req := new(Msg)
+	req.SetQuestion("nope.miek.nl.", TypeA)
+
+	m := new(Msg)
+	m.SetNameError(req, soa)
+	if m.Rcode != RcodeNameError || !m.Response || !m.Authoritative {
+		t.Errorf("unexpected header for NXDOMAIN reply: %v", m.MsgHdr)
+	}
+	if m.Id != req.Id || len(m.Question) != 1 || m.Question[0] != req.Question[0] {
+		t.Errorf("question not copied from request: %v", m.Question)
+	}
+	if len(m.Answer) != 0 || len(m.Ns) != 1 {
+		t.Fatalf("expected only an SOA in the authority section, got %d answers and %d authority records", len(m.Answer), len(m.Ns))
+	}
+	if s, ok := m.Ns[0].(*SOA); !ok || s.Hdr.Ttl != 300 {
+		t.Errorf("expected SOA with TTL 300 in the authority section, got %s", m.Ns[0])
+	}
+	if soa.Hdr.Ttl != 3600 {
+		t.Errorf("SOA passed in was modified, TTL is %d", soa.Hdr.Ttl)
+	}
+
+	soa.Hdr.Ttl = 60
+	m = new(Msg)
+	m.SetNoData(req, soa)
+	if m.Rcode != RcodeSuccess || !m.Authoritative {
+		t.Errorf("unexpected header for NODATA reply: %v", m.MsgHdr)
+	}
+	if len(m.Answer) != 0 || len(m.Ns) != 1 {
+		t.Fatalf("expected only an SOA in the authority section, got %d answers and %d authority records", len(m.Answer), len(m.Ns))
+	}
+	if ttl := m.Ns[0].Header().Ttl; ttl != 60 {
+		t.Errorf("expected negative TTL 60, got %d", ttl)
+	}
+}
+
+func TestMsgUnpackSize(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeMX)
+	m.Answer = append(m.Answer, newRR(t, "miek.nl. 3600 IN MX 10 mx.miek.nl."))
+	m.SetEdns0(4096, true)
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Size != 0 {
+		t.Errorf("expected Size 0 for a message not received from the wire, got %d", m.Size)
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if m1.Size != len(buf) {
+		t.Errorf("expected Size %d after unpack, got %d", len(buf), m1.Size)
+	}
+	if m2 := m1.Copy(); m2.Size != m1.Size {
+		t.Errorf("expected Size %d after copy, got %d", m1.Size, m2.Size)
+	}
+}
+
+func TestMsgIsAuthoritativeFor(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("www.miek.nl.", TypeA)
+	m.Response = true
+	m.Authoritative = true
+	m.Answer = []RR{newRR(t, "www.miek.nl. 3600 IN A 127.0.0.1")}
+	m.Ns = []RR{newRR(t, "miek.nl. 3600 IN NS linode.atoom.net.")}
+	if !m.IsAuthoritativeFor("www.miek.nl.") {
+		t.Error("expected answer to be authoritative for www.miek.nl.")
+	}
+	if !m.IsAuthoritativeFor("WWW.Miek.NL.") {
+		t.Error("expected answer to be authoritative for WWW.Miek.NL.")
+	}
+	if m.IsAuthoritativeFor("www.example.org.") {
+		t.Error("expected answer not to be authoritative for www.example.org.")
+	}
+
+	// Negative answer with the SOA in the authority section.
+	m.Answer = nil
+	m.Ns = []RR{newRR(t, "miek.nl. 3600 IN SOA linode.atoom.net. miek.miek.nl. 1282630057 14400 3600 604800 300")}
+	if !m.IsAuthoritativeFor("www.miek.nl.") {
+		t.Error("expected negative answer to be authoritative for www.miek.nl.")
+	}
+
+	// Referral from the parent.
+	r := new(Msg)
+	r.SetQuestion("www.miek.nl.", TypeA)
+	r.Response = true
+	r.Ns = []RR{newRR(t, "miek.nl. 172800 IN NS linode.atoom.net.")}
+	if r.IsAuthoritativeFor("www.miek.nl.") {
+		t.Error("expected referral not to be authoritative for www.miek.nl.")
+	}
+	r.Authoritative = true
+	r.Ns = []RR{newRR(t, "sub.miek.nl. 172800 IN NS linode.atoom.net.")}
+	if r.IsAuthoritativeFor("www.miek.nl.") {
+		t.Error("expected NS for sub.miek.nl. not to be authoritative for www.miek.nl.")
+	}
+}
+
+func TestMsgStringDig(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeMX)
+	m.Id = 48404
+	m.Response = true
+	m.Authoritative = true
+	m.RecursionAvailable = true
+	m.Answer = []RR{newRR(t, "miek.nl. 3600 IN MX 1 aspmx.l.google.com."), newRR(t, "miek.nl. 3600 IN MX 5 alt1.aspmx.l.google.com.")}
+
+	expect := `;; opcode: QUERY, status: NOERROR, id: 48404
+;; flags: qr aa rd ra; QUERY: 1, ANSWER: 2, AUTHORITY: 0, ADDITIONAL: 0
+
+;; QUESTION SECTION:
+;miek.nl.	IN	 MX
+
+;; ANSWER SECTION:
+miek.nl.	3600	IN	MX	1 aspmx.l.google.com.
+miek.nl.	3600	IN	MX	5 alt1.aspmx.l.google.com.
+`
+	if s := m.String(); s != expect {
+		t.Errorf("expected\n%s\ngot\n%s", expect, s)
+	}
+
+	h := MsgHdr{Id: 1, Rcode: RcodeNameError}
+	if s := h.String(); s != ";; opcode: QUERY, status: NXDOMAIN, id: 1\n;; flags:;" {
+		t.Errorf("unexpected header without flags: %q", s)
+	}
+}
+
+func TestMsgLenCompressionBoundary(t *testing.T) {
+	// Move a name across maxCompressionOffset: only the parts of it that start before the
+	// boundary can be pointed to by the next name.
+	txt := strings.Repeat("x", 250)
+	for i := 0; i < 64; i++ {
+		m := new(Msg)
+		m.SetQuestion("example.org.", TypeTXT)
+		m.Compress = true
+		for j := 0; j < 62; j++ {
+			m.Answer = append(m.Answer, newRR(t, fmt.Sprintf("example.org. 3600 IN TXT %q", txt)))
+		}
+		m.Answer = append(m.Answer, newRR(t, fmt.Sprintf("example.org. 3600 IN TXT %q", txt[:i])))
+		m.Answer = append(m.Answer, newRR(t, "aaaaaaaaaaaaaaaaaaaa.freshzone.net. 3600 IN A 192.0.2.1"))
+		m.Answer = append(m.Answer, newRR(t, "b.freshzone.net. 3600 IN A 192.0.2.2"))
+		buf, err := m.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l := m.Len(); l != len(buf) {
+			t.Errorf("%d: Len returned %d, expected the packed size %d", i, l, len(buf))
+		}
+	}
+}
+
+func TestPackLargeCompression(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeTXT)
+	m.Compress = true
+	txt := strings.Repeat("x", 200)
+	for i := 0; i < 200; i++ {
+		// Every record adds a name that can only be stored for compression while
+		// the message is smaller than 16KB.
+		name := fmt.Sprintf("host%d.sub%d.example.org.", i, i%7)
+		m.Answer = append(m.Answer, newRR(t, fmt.Sprintf("%s 3600 IN TXT %q", name, txt)))
+		m.Answer = append(m.Answer, newRR(t, fmt.Sprintf("%s 3600 IN CNAME host%d.sub%d.example.org.", name, (i+1)%200, i%5)))
+	}
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) <= maxCompressionOffset {
+		t.Fatalf("expected a message larger than %d octets, got %d", maxCompressionOffset, len(buf))
+	}
+	if l := m.Len(); l != len(buf) {
+		t.Errorf("Len returned %d, expected the packed size %d", l, len(buf))
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatalf("failed to unpack large compressed message: %v", err)
+	}
+	if len(m1.Answer) != len(m.Answer) {
+		t.Fatalf("expected %d answers, got %d", len(m.Answer), len(m1.Answer))
+	}
+	for i := range m.Answer {
+		if m1.Answer[i].String() != m.Answer[i].String() {
+			t.Errorf("record %d changed after round trip: expected %s, got %s", i, m.Answer[i], m1.Answer[i])
+		}
+	}
+
+	// A compression map holding an offset that can't be pointed to, must not be used.
+	cbuf := make([]byte, 100)
+	compression := map[string]int{"example.org.": maxCompressionOffset + 10}
+	off, err := PackDomainName("www.example.org.", cbuf, 0, compression, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off != len("www.example.org.")+1 {
+		t.Errorf("expected uncompressed name of %d octets, got %d: %x", len("www.example.org.")+1, off, cbuf[:off])
+	}
+}
+
+func TestUnpackRRSIGCompressedSigner(t *testing.T) {
+	sig := newRR(t, "miek.nl. 3600 IN RRSIG DNSKEY 8 2 3600 20160426031301 20160327031301 12051 miek.nl. AAECAwQFBgcICQ==").(*RRSIG)
+	rdata, err := PackRdata(sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replace the signer name after the 18 octets of fixed fields with a pointer to the
+	// question name. This is not allowed by RFC 4034, section 3.1.7, so the unpacking
+	// must reject it instead of getting confused by it.
+	signer := len("miek.nl.") + 1
+	compressed := append([]byte{}, rdata[:18]...)
+	compressed = append(compressed, 0xC0, 12)
+	compressed = append(compressed, rdata[18+signer:]...)
+
+	q := new(Msg)
+	q.SetQuestion("miek.nl.", TypeDNSKEY)
+	q.Response = true
+	q.Answer = []RR{&A{Hdr: RR_Header{Name: "miek.nl.", Rrtype: TypeA, Class: ClassINET}, A: net.IPv4(127, 0, 0, 1)}}
+	buf, err := q.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Swap the A record for the RRSIG: keep the owner, type, class and ttl.
+	buf = buf[:len(buf)-4-2]
+	typ := len(buf) - 8
+	binary.BigEndian.PutUint16(buf[typ:], TypeRRSIG)
+	binary.BigEndian.PutUint32(buf[typ+4:], 3600)
+	buf = append(buf, byte(len(compressed)>>8), byte(len(compressed)))
+	buf = append(buf, compressed...)
+
+	m := new(Msg)
+	if err := m.Unpack(buf); err == nil {
+		t.Errorf("expected error for an RRSIG with a compressed signer name, got %v", m.Answer)
+	}
+}
+
+func TestPackRdataCompression(t *testing.T) {
+	// RFC 3597, section 4: only names in the rdata of the RR types defined in RFC 1035
+	// may be compressed.
+	compressible := []string{
+		"www.example.org. 3600 IN CNAME host.example.org.",
+		"www.example.org. 3600 IN MB host.example.org.",
+		"www.example.org. 3600 IN MD host.example.org.",
+		"www.example.org. 3600 IN MF host.example.org.",
+		"www.example.org. 3600 IN MG host.example.org.",
+		"www.example.org. 3600 IN MINFO rmail.example.org. email.example.org.",
+		"www.example.org. 3600 IN MR host.example.org.",
+		"www.example.org. 3600 IN MX 10 host.example.org.",
+		"www.example.org. 3600 IN NS host.example.org.",
+		"www.example.org. 3600 IN PTR host.example.org.",
+		"www.example.org. 3600 IN SOA ns.example.org. mail.example.org. 1 2 3 4 5",
+	}
+	uncompressible := []string{
+		"www.example.org. 3600 IN AFSDB 1 host.example.org.",
+		"www.example.org. 3600 IN DNAME host.example.org.",
+		"www.example.org. 3600 IN KX 10 host.example.org.",
+		"www.example.org. 3600 IN LP 10 host.example.org.",
+		"www.example.org. 3600 IN NAPTR 100 10 \"S\" \"SIP+D2U\" \"\" _sip._udp.example.org.",
+		"www.example.org. 3600 IN NSAP-PTR host.example.org.",
+		"www.example.org. 3600 IN NSEC host.example.org. A RRSIG NSEC",
+		"www.example.org. 3600 IN PX 10 map822.example.org. mapx400.example.org.",
+		"www.example.org. 3600 IN RP mbox.example.org. txt.example.org.",
+		"www.example.org. 3600 IN RRSIG A 8 3 3600 20160426031301 20160327031301 12051 example.org. AAECAwQFBgcICQ==",
+		"www.example.org. 3600 IN RT 10 host.example.org.",
+		"www.example.org. 3600 IN SRV 10 10 5060 host.example.org.",
+		"www.example.org. 3600 IN TALINK prev.example.org. next.example.org.",
+		"www.example.org. 3600 IN HIP 2 200100107b1a74df365639cc39f1d578 AwEAAbdxyhNuSutc5EMzxTs9LBPCIkOFH8cIvM4p9+LrV4e19WzK00+CI6zBCQTdtWsuxKbWIy87UOoJTwkUs7lBu+Upr1gsNrut79ryra+bSRGQb1slImA8YVJyuIDsj7kwzG7jnERNqnWxZ48AWkskmdHaVDP4BcelrTI3rMXdXF5D host.example.org.",
+	}
+	pack := func(s string) ([]byte, []byte) {
+		rr := newRR(t, s)
+		m := new(Msg)
+		m.SetQuestion("example.org.", rr.Header().Rrtype)
+		m.Compress = true
+		m.Answer = []RR{newRR(t, "host.example.org. 3600 IN A 127.0.0.1"), rr}
+		buf, err := m.Pack()
+		if err != nil {
+			t.Fatalf("failed to pack %s: %v", s, err)
+		}
+		if l := m.Len(); l < len(buf) {
+			t.Errorf("Len returned %d for %s, smaller than the packed size %d", l, s, len(buf))
+		}
+		rdata, err := PackRdata(rr)
+		if err != nil {
+			t.Fatalf("failed to pack rdata of %s: %v", s, err)
+		}
+		m1 := new(Msg)
+		if err := m1.Unpack(buf); err != nil {
+			t.Fatalf("failed to unpack %s: %v", s, err)
+		}
+		if m1.Answer[1].String() != rr.String() {
+			t.Errorf("expected %s after round trip, got %s", rr, m1.Answer[1])
+		}
+		return buf, rdata
+	}
+	for _, s := range compressible {
+		buf, rdata := pack(s)
+		if bytes.HasSuffix(buf, rdata) {
+			t.Errorf("expected the rdata of %s to be compressed", s)
+		}
+	}
+	for _, s := range uncompressible {
+		buf, rdata := pack(s)
+		if !bytes.HasSuffix(buf, rdata) {
+			t.Errorf("expected the rdata of %s not to be compressed", s)
+		}
+	}
+}
+
+func TestUnpackLenient(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeCNAME)
+	m.Compress = false
+	m.Answer = []RR{
+		newRR(t, "a.miek.nl. 3600 IN CNAME miek.nl."),
+		newRR(t, "b.miek.nl. 3600 IN CNAME zz."),
+		newRR(t, "c.miek.nl. 3600 IN A 127.0.0.1"),
+	}
+	m.Ns = []RR{newRR(t, "d.miek.nl. 3600 IN CNAME yy.")}
+	m.Extra = []RR{newRR(t, "e.miek.nl. 3600 IN TXT \"good\"")}
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Break the CNAME target zz. with a reserved label type.
+	i := bytes.Index(buf, []byte("\x02zz\x00"))
+	buf[i] = 0x80
+	// Make the CNAME target yy. a pointer to itself.
+	i = bytes.Index(buf, []byte("\x02yy\x00"))
+	buf[i], buf[i+1] = 0xC0|byte(i>>8), byte(i)
+
+	if err := new(Msg).Unpack(buf); err == nil {
+		t.Fatal("expected Unpack to fail")
+	}
+	m1, errs := UnpackLenient(buf)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	for i, want := range []struct {
+		section string
+		index   int
+	}{{"answer", 1}, {"authority", 0}} {
+		e, ok := errs[i].(*UnpackError)
+		if !ok {
+			t.Fatalf("expected *UnpackError, got %T", errs[i])
+		}
+		if e.Section != want.section || e.Index != want.index {
+			t.Errorf("expected error for %s record %d, got %s", want.section, want.index, e)
+		}
+	}
+	if len(m1.Question) != 1 || len(m1.Answer) != 2 || len(m1.Ns) != 0 || len(m1.Extra) != 1 {
+		t.Fatalf("unexpected sections after lenient unpack:\n%s", m1)
+	}
+	if m1.Answer[1].String() != m.Answer[2].String() || m1.Extra[0].String() != m.Extra[0].String() {
+		t.Errorf("good records not unpacked:\n%s", m1)
+	}
+
+	// A broken record header can't be skipped.
+	_, errs = UnpackLenient(buf[:len(buf)-20])
+	if len(errs) != 3 {
+		t.Errorf("expected 3 errors for a short message, got %d: %v", len(errs), errs)
+	}
+}
+
+func TestMsgChaosTXT(t *testing.T) {
+	q := new(Msg)
+	q.SetQuestionClass("version.bind.", TypeTXT, ClassCHAOS)
+	q.RecursionDesired = false
+	buf, err := q.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q1 := new(Msg)
+	if err := q1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if q1.Question[0] != (Question{"version.bind.", TypeTXT, ClassCHAOS}) {
+		t.Errorf("unexpected question %s", q1.Question[0].String())
+	}
+
+	r := new(Msg)
+	r.SetReply(q1)
+	r.Answer = []RR{newRR(t, `version.bind. 0 CH TXT "9.10.3-P4"`)}
+	buf, err = r.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r1 := new(Msg)
+	if err := r1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(r1.Answer) != 1 {
+		t.Fatalf("expected 1 answer, got %d", len(r1.Answer))
+	}
+	txt, ok := r1.Answer[0].(*TXT)
+	if !ok || txt.Hdr.Class != ClassCHAOS || len(txt.Txt) != 1 || txt.Txt[0] != "9.10.3-P4" {
+		t.Errorf("unexpected answer %s", r1.Answer[0])
+	}
+	if s := r1.Answer[0].String(); s != "version.bind.\t0\tCH\tTXT\t\"9.10.3-P4\"" {
+		t.Errorf("unexpected presentation format %q", s)
+	}
+}
+
+func TestCompressionMap(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("www.example.org.", TypeMX)
+	m.Answer = []RR{newRR(t, "www.example.org. 3600 IN MX 10 mail.example.org.")}
+	m.Ns = []RR{newRR(t, "example.org. 3600 IN NS ns.example.net.")}
+
+	// Header is 12 octets, the question name follows. The answer's owner name is a
+	// pointer, so its MX starts at 12+17+4+2+10+2.
+	expected := map[string]int{
+		"www.example.org.":  12,
+		"example.org.":      16,
+		"org.":              24,
+		"mail.example.org.": 47,
+		// The NS owner name is a pointer too, its rdata starts at 47+5+2+2+10.
+		"ns.example.net.": 66,
+		"example.net.":    69,
+		"net.":            77,
+	}
+	c := CompressionMap(m)
+	if len(c) != len(expected) {
+		t.Errorf("expected %d names in the compression map, got %d: %v", len(expected), len(c), c)
+	}
+	for name, off := range expected {
+		if c[name] != off {
+			t.Errorf("expected offset %d for %s, got %d", off, name, c[name])
+		}
+	}
+}
+
+func TestUnpackDomainNameShort(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		msg  []byte
+	}{
+		{"empty message", []byte{}},
+		{"pointer past the end", []byte{3, 'w', 'w', 'w', 0xC0, 0x20}},
+		{"pointer to the end", []byte{3, 'w', 'w', 'w', 0xC0, 6}},
+		{"half a pointer", []byte{3, 'w', 'w', 'w', 0xC0}},
+		{"label past the end", []byte{7, 'e', 'x', 'a'}},
+		{"missing root label", []byte{3, 'w', 'w', 'w'}},
+	} {
+		if _, _, err := UnpackDomainName(tc.msg, 0); err != ErrBuf {
+			t.Errorf("%s: expected ErrBuf, got %v", tc.name, err)
+		}
+	}
+
+	// A pointer to itself must not loop.
+	if _, _, err := UnpackDomainName([]byte{0xC0, 0}, 0); err == nil {
+		t.Error("expected error for a compression pointer loop")
+	}
+}
+
+func TestUnpackDomainNameLong(t *testing.T) {
+	// label appends a label of n octets to msg, followed by a pointer to ptr, or by
+	// the root label when ptr is negative.
+	label := func(msg []byte, n, ptr int) []byte {
+		msg = append(msg, byte(n))
+		msg = append(msg, bytes.Repeat([]byte{'a'}, n)...)
+		if ptr < 0 {
+			return append(msg, 0)
+		}
+		return append(msg, 0xC0|byte(ptr>>8), byte(ptr))
+	}
+
+	// Each label points back at the one before it, the name starts at the last.
+	var msg []byte
+	ptr := -1
+	for _, n := range []int{61, 63, 63, 63} {
+		start := len(msg)
+		msg = label(msg, n, ptr)
+		ptr = start
+	}
+	name, _, err := UnpackDomainName(msg, ptr)
+	if err != nil {
+		t.Fatalf("expected a name of 255 octets to unpack, got %v", err)
+	}
+	if l := len(name) + 1; l != maxDomainNameWireOctets {
+		t.Errorf("expected a name of %d octets, got %d", maxDomainNameWireOctets, l)
+	}
+
+	msg = label(msg, 1, ptr)
+	if _, _, err := UnpackDomainName(msg, len(msg)-4); err != ErrLongDomain {
+		t.Errorf("expected ErrLongDomain, got %v", err)
+	}
+
+	// A pointer loop is stopped by the length as well.
+	if _, _, err := UnpackDomainName(label(nil, 63, 0), 0); err != ErrLongDomain {
+		t.Errorf("expected ErrLongDomain for a pointer loop, got %v", err)
+	}
+}
+
+func TestUnpackRdataCompressionPointer(t *testing.T) {
+	// msg returns a message with the question example.org. A and one answer of type
+	// rrtype, owned by the question name, with rdata rdata.
+	msg := func(rrtype uint16, rdata ...byte) []byte {
+		m := []byte{0, 0, 0, 0, 0, 1, 0, 1, 0, 0, 0, 0}
+		m = append(m, 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'o', 'r', 'g', 0, 0, 1, 0, 1)
+		m = append(m, 0xC0, 12, byte(rrtype>>8), byte(rrtype), 0, 1, 0, 0, 0, 0, 0, byte(len(rdata)))
+		return append(m, rdata...)
+	}
+	www := []byte{3, 'w', 'w', 'w', 0xC0, 12} // www.example.org., compressed
+
+	for _, tc := range []struct {
+		rrtype uint16
+		rdata  []byte
+		ok     bool
+	}{
+		{TypeMX, append([]byte{0, 10}, www...), true},
+		{TypeSRV, append([]byte{0, 0, 0, 0, 0, 53}, www...), true},
+		{TypeDNAME, www, true},
+		{TypeNSEC, append(www, 0, 1, 0x40), false},
+		{TypeNSEC, append([]byte{3, 'w', 'w', 'w', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'o', 'r', 'g', 0}, 0, 1, 0x40), true},
+		{TypeKX, append([]byte{0, 10}, www...), false},
+		{TypeHIP, append([]byte{1, 2, 0, 1, 0xab, 0xcd}, append([]byte{4, 'r', 'v', 's', '1', 0}, www...)...), false},
+		// SIG is a legacy type, RRSIG has the same rdata but may not be compressed.
+		{TypeSIG, append(make([]byte, 18), append(www, 1, 2, 3, 4)...), true},
+		{TypeRRSIG, append(make([]byte, 18), append(www, 1, 2, 3, 4)...), false},
+	} {
+		m := new(Msg)
+		err := m.Unpack(msg(tc.rrtype, tc.rdata...))
+		if tc.ok && err != nil {
+			t.Errorf("%s: expected a compressed name in the rdata to unpack, got %v", Type(tc.rrtype), err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("%s: expected error for a compression pointer in the rdata, got %s", Type(tc.rrtype), m.Answer[0])
+		}
+	}
+}
@@ -185,17 +185,10 @@ func (k *DNSKEY) ToDS(h uint8) *DS {
 	ds.DigestType = h
 	ds.KeyTag = k.KeyTag()
 
-	keywire := new(dnskeyWireFmt)
-	keywire.Flags = k.Flags
-	keywire.Protocol = k.Protocol
-	keywire.Algorithm = k.Algorithm
-	keywire.PublicKey = k.PublicKey
-	wire := make([]byte, DefaultMsgSize)
-	n, err := packKeyWire(keywire, wire)
+	wire, err := PackRdata(k)
 	if err != nil {
 		return nil
 	}
-	wire = wire[:n]
 
 	owner := make([]byte, 255)
 	off, err1 := PackDomainName(strings.ToLower(k.Hdr.Name), owner, 0, nil, false)
@@ -247,6 +240,23 @@ func (d *DS) ToCDS() *CDS {
 	return c
 }
 
+// Verify checks that the DS record is for the key k: the owner name, key tag and
+// algorithm must be those of k and the digest must be the one k.ToDS computes. It
+// returns ErrAlg when the digest type is not supported and ErrKey when k doesn't match.
+func (d *DS) Verify(k *DNSKEY) error {
+	if d.KeyTag != k.KeyTag() || d.Algorithm != k.Algorithm || !strings.EqualFold(d.Hdr.Name, k.Hdr.Name) {
+		return ErrKey
+	}
+	ds := k.ToDS(d.DigestType)
+	if ds == nil {
+		return ErrAlg
+	}
+	if !strings.EqualFold(ds.Digest, d.Digest) {
+		return ErrKey
+	}
+	return nil
+}
+
 // Sign signs an RRSet. The signature needs to be filled in with the values:
 // Inception, Expiration, KeyTag, SignerName and Algorithm.  The rest is copied
 // from the RRset. Sign returns a non-nill error when the signing went OK.
@@ -269,11 +279,7 @@ func (rr *RRSIG) Sign(k crypto.Signer, rrset []RR) error {
 		rr.OrigTtl = rrset[0].Header().Ttl
 	}
 	rr.TypeCovered = rrset[0].Header().Rrtype
-	rr.Labels = uint8(CountLabel(rrset[0].Header().Name))
-
-	if strings.HasPrefix(rrset[0].Header().Name, "*") {
-		rr.Labels-- // wildcard, remove from label count
-	}
+	rr.Labels = uint8(countSigLabels(rrset[0].Header().Name))
 
 	sigwire := new(rrsigWireFmt)
 	sigwire.TypeCovered = rr.TypeCovered
@@ -317,7 +323,37 @@ func (rr *RRSIG) Sign(k crypto.Signer, rrset []RR) error {
 	return nil
 }
 
+// checkKeyAlg checks that the public key of k can be used with the algorithm alg. Keys
+// of a type that is not known are not checked.
+func checkKeyAlg(k crypto.Signer, alg uint8) error {
+	switch pub := k.Public().(type) {
+	case *rsa.PublicKey:
+		switch alg {
+		case RSAMD5, RSASHA1, RSASHA1NSEC3SHA1, RSASHA256, RSASHA512:
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		switch {
+		case alg == ECDSAP256SHA256 && pub.Curve == elliptic.P256():
+			return nil
+		case alg == ECDSAP384SHA384 && pub.Curve == elliptic.P384():
+			return nil
+		}
+	case *dsa.PublicKey:
+		switch alg {
+		case DSA, DSANSEC3SHA1:
+			return nil
+		}
+	default:
+		return nil
+	}
+	return ErrKeyAlg
+}
+
 func sign(k crypto.Signer, hashed []byte, hash crypto.Hash, alg uint8) ([]byte, error) {
+	if err := checkKeyAlg(k, alg); err != nil {
+		return nil, err
+	}
 	signature, err := k.Sign(rand.Reader, hashed, hash)
 	if err != nil {
 		return nil, err
@@ -359,6 +395,36 @@ func sign(k crypto.Signer, hashed []byte, hash crypto.Hash, alg uint8) ([]byte,
 	return nil, ErrAlg
 }
 
+// CheckSigner checks that the signer name of the RRSIG is zone and that the owner
+// name of the RRSIG is zone or a name below it. A signature made by one zone can not be
+// used for records of another zone. It returns ErrSig when the check fails.
+func (rr *RRSIG) CheckSigner(zone string) error {
+	if !strings.EqualFold(Fqdn(rr.SignerName), Fqdn(zone)) {
+		return ErrSig
+	}
+	if !IsSubDomain(strings.ToLower(Fqdn(zone)), strings.ToLower(rr.Hdr.Name)) {
+		return ErrSig
+	}
+	return nil
+}
+
+// IsWildcard reports whether owner, the owner name of the RRset the signature covers,
+// is the result of a wildcard expansion: the Labels field is less than the number of
+// labels in owner (RFC 4035, section 5.3.4). It also returns the wildcard name that
+// was expanded, "*." followed by the rightmost Labels labels of owner. A signature
+// with at least as many labels as owner is not for an expansion, the name is then empty.
+func (rr *RRSIG) IsWildcard(owner string) (bool, string) {
+	owner = Fqdn(owner)
+	if int(rr.Labels) >= countSigLabels(owner) {
+		return false, ""
+	}
+	if rr.Labels == 0 {
+		return true, "*."
+	}
+	idx := Split(owner)
+	return true, "*." + owner[idx[len(idx)-int(rr.Labels)]:]
+}
+
 // Verify validates an RRSet with the signature and key. This is only the
 // cryptographic test, the signature validity period must be checked separately.
 // This function copies the rdata of some RRs (to lowercase domain names) for the validation to work.
@@ -480,6 +546,19 @@ func (rr *RRSIG) ValidityPeriod(t time.Time) bool {
 	return ti <= utc && utc <= te
 }
 
+// SigningInceptionOffset is how far SigningWindow sets the inception back in time, to
+// allow for clock skew between signer and validators.
+const SigningInceptionOffset = time.Hour
+
+// SigningWindow returns the inception and expiration times, as used in RRSIG and SIG
+// records, for a signature made now that is valid for validity. The inception is
+// set SigningInceptionOffset back in time. Times are truncated to 32 bits, see
+// ValidityPeriod for how they are interpreted.
+func SigningWindow(validity time.Duration) (inception, expiration uint32) {
+	now := time.Now().UTC()
+	return uint32(now.Add(-SigningInceptionOffset).Unix()), uint32(now.Add(validity).Unix())
+}
+
 // Return the signatures base64 encodedig sigdata as a byte slice.
 func (rr *RRSIG) sigBuf() []byte {
 	sigbuf, err := fromBase64([]byte(rr.Signature))
@@ -589,6 +668,68 @@ func (p wireSlice) Less(i, j int) bool {
 	return bytes.Compare(p[i][ioff+10:], p[j][joff+10:]) < 0
 }
 
+// canonicalRdata lowercases the domain names in the rdata of r, as required for the
+// canonical form of an RR.
+func canonicalRdata(r RR) {
+	// 6.2. Canonical RR Form. (3) - domain rdata to lowercase.
+	//   NS, MD, MF, CNAME, SOA, MB, MG, MR, PTR,
+	//   HINFO, MINFO, MX, RP, AFSDB, RT, SIG, PX, NXT, NAPTR, KX,
+	//   SRV, DNAME, A6
+	//
+	// RFC 6840 - Clarifications and Implementation Notes for DNS Security (DNSSEC):
+	//	Section 6.2 of [RFC4034] also erroneously lists HINFO as a record
+	//	that needs conversion to lowercase, and twice at that.  Since HINFO
+	//	records contain no domain names, they are not subject to case
+	//	conversion.
+	switch x := r.(type) {
+	case *NS:
+		x.Ns = strings.ToLower(x.Ns)
+	case *CNAME:
+		x.Target = strings.ToLower(x.Target)
+	case *SOA:
+		x.Ns = strings.ToLower(x.Ns)
+		x.Mbox = strings.ToLower(x.Mbox)
+	case *MB:
+		x.Mb = strings.ToLower(x.Mb)
+	case *MG:
+		x.Mg = strings.ToLower(x.Mg)
+	case *MR:
+		x.Mr = strings.ToLower(x.Mr)
+	case *PTR:
+		x.Ptr = strings.ToLower(x.Ptr)
+	case *MINFO:
+		x.Rmail = strings.ToLower(x.Rmail)
+		x.Email = strings.ToLower(x.Email)
+	case *MX:
+		x.Mx = strings.ToLower(x.Mx)
+	case *NAPTR:
+		x.Replacement = strings.ToLower(x.Replacement)
+	case *KX:
+		x.Exchanger = strings.ToLower(x.Exchanger)
+	case *SRV:
+		x.Target = strings.ToLower(x.Target)
+	case *DNAME:
+		x.Target = strings.ToLower(x.Target)
+	}
+}
+
+// PackRdata returns the rdata of rr in canonical wire format (RFC 4034, section 6.2):
+// domain names are not compressed and, for the types that require it, lowercased.
+// The owner name, type, class, TTL and rdlength are not included.
+func PackRdata(rr RR) ([]byte, error) {
+	r := rr.copy()
+	canonicalRdata(r)
+	// Pack with the root as owner, so we know the rdata starts after 11 octets: the
+	// owner, type, class, ttl and rdlength.
+	r.Header().Name = "."
+	wire := make([]byte, r.len())
+	off, err := PackRR(r, wire, 0, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return wire[11:off], nil
+}
+
 // Return the raw signature data.
 func rawSignatureData(rrset []RR, s *RRSIG) (buf []byte, err error) {
 	wires := make(wireSlice, len(rrset))
@@ -604,45 +745,7 @@ func rawSignatureData(rrset []RR, s *RRSIG) (buf []byte, err error) {
 		// RFC 4034: 6.2.  Canonical RR Form. (2) - domain name to lowercase
 		r1.Header().Name = strings.ToLower(r1.Header().Name)
 		// 6.2. Canonical RR Form. (3) - domain rdata to lowercase.
-		//   NS, MD, MF, CNAME, SOA, MB, MG, MR, PTR,
-		//   HINFO, MINFO, MX, RP, AFSDB, RT, SIG, PX, NXT, NAPTR, KX,
-		//   SRV, DNAME, A6
-		//
-		// RFC 6840 - Clarifications and Implementation Notes for DNS Security (DNSSEC):
-		//	Section 6.2 of [RFC4034] also erroneously lists HINFO as a record
-		//	that needs conversion to lowercase, and twice at that.  Since HINFO
-		//	records contain no domain names, they are not subject to case
-		//	conversion.
-		switch x := r1.(type) {
-		case *NS:
-			x.Ns = strings.ToLower(x.Ns)
-		case *CNAME:
-			x.Target = strings.ToLower(x.Target)
-		case *SOA:
-			x.Ns = strings.ToLower(x.Ns)
-			x.Mbox = strings.ToLower(x.Mbox)
-		case *MB:
-			x.Mb = strings.ToLower(x.Mb)
-		case *MG:
-			x.Mg = strings.ToLower(x.Mg)
-		case *MR:
-			x.Mr = strings.ToLower(x.Mr)
-		case *PTR:
-			x.Ptr = strings.ToLower(x.Ptr)
-		case *MINFO:
-			x.Rmail = strings.ToLower(x.Rmail)
-			x.Email = strings.ToLower(x.Email)
-		case *MX:
-			x.Mx = strings.ToLower(x.Mx)
-		case *NAPTR:
-			x.Replacement = strings.ToLower(x.Replacement)
-		case *KX:
-			x.Exchanger = strings.ToLower(x.Exchanger)
-		case *SRV:
-			x.Target = strings.ToLower(x.Target)
-		case *DNAME:
-			x.Target = strings.ToLower(x.Target)
-		}
+		canonicalRdata(r1)
 		// 6.2. Canonical RR Form. (5) - origTTL
 		wire := make([]byte, r1.len()+1) // +1 to be safe(r)
 		off, err1 := PackRR(r1, wire, 0, nil, false)
@@ -1,9 +1,11 @@
 package dns
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/rsa"
+	"net"
 	"reflect"
 	"strings"
 	"testing"
@@ -364,6 +366,35 @@ func TestKeyToDS(t *testing.T) {
 	}
 }
 
+func TestDSVerify(t *testing.T) {
+	key := newRR(t, "miek.nl. 3600 IN DNSKEY 256 3 8 AwEAAcNEU67LJI5GEgF9QLNqLO1SMq1EdoQ6E9f85ha0k0ewQGCblyW2836GiVsm6k8Kr5ECIoMJ6fZWf3CQSQ9ycWfTyOHfmI3eQ/1Covhb2y4bAmL/07PhrL7ozWBW3wBfM335Ft9xjtXHPy7ztCbV9qZ4TVDTW/Iyg0PiwgoXVesz").(*DNSKEY)
+	ds := newRR(t, "MIEK.nl. 3600 IN DS 12051 8 1 b5121bdb5b8d86d0cc5ffafbaaabe26c3e20bac1").(*DS)
+	if ds.KeyTag != key.KeyTag() {
+		t.Fatalf("expected key tag %d, got %d", key.KeyTag(), ds.KeyTag)
+	}
+	if err := ds.Verify(key); err != nil {
+		t.Errorf("failed to verify DS: %v", err)
+	}
+	if err := key.ToDS(SHA256).Verify(key); err != nil {
+		t.Errorf("failed to verify SHA256 DS: %v", err)
+	}
+
+	other := newSignedZone(t, "miek.nl.").key
+	if err := ds.Verify(other); err != ErrKey {
+		t.Errorf("expected ErrKey for another key, got %v", err)
+	}
+	ds1 := *ds
+	ds1.Digest = "B5121BDB5B8D86D0CC5FFAFBAAABE26C3E20BAC2"
+	if err := ds1.Verify(key); err != ErrKey {
+		t.Errorf("expected ErrKey for a wrong digest, got %v", err)
+	}
+	ds1 = *ds
+	ds1.DigestType = 200
+	if err := ds1.Verify(key); err != ErrAlg {
+		t.Errorf("expected ErrAlg for an unknown digest type, got %v", err)
+	}
+}
+
 func TestSignRSA(t *testing.T) {
 	pub := "miek.nl. IN DNSKEY 256 3 5 AwEAAb+8lGNCxJgLS8rYVer6EnHVuIkQDghdjdtewDzU3G5R7PbMbKVRvH2Ma7pQyYceoaqWZQirSj72euPWfPxQnMy9ucCylA+FuH9cSjIcPf4PqJfdupHk9X6EBYjxrCLY4p1/yBwgyBIRJtZtAqM3ceAH2WovEJD6rTtOuHo5AluJ"
 
@@ -731,3 +762,573 @@ func TestInvalidRRSet(t *testing.T) {
 		t.Fatal("Verification did not return ErrRRset with inconsistent records")
 	}
 }
+
+// signedZone holds a generated key for a zone, it is used to build signed test responses.
+type signedZone struct {
+	key  *DNSKEY
+	priv crypto.Signer
+}
+
+func newSignedZone(t *testing.T, zone string) *signedZone {
+	key := &DNSKEY{Hdr: RR_Header{Name: zone, Rrtype: TypeDNSKEY, Class: ClassINET, Ttl: 3600},
+		Flags: 257, Protocol: 3, Algorithm: ECDSAP256SHA256}
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &signedZone{key: key, priv: priv.(crypto.Signer)}
+}
+
+func (z *signedZone) sign(t *testing.T, rrset ...RR) []RR {
+	sig := &RRSIG{Hdr: RR_Header{Ttl: 3600}, KeyTag: z.key.KeyTag(), SignerName: z.key.Hdr.Name, Algorithm: z.key.Algorithm,
+		Inception: uint32(time.Now().Add(-time.Hour).Unix()), Expiration: uint32(time.Now().Add(time.Hour).Unix())}
+	if err := sig.Sign(z.priv, rrset); err != nil {
+		t.Fatal(err)
+	}
+	return append(rrset, sig)
+}
+
+func TestMsgVerify(t *testing.T) {
+	root := newSignedZone(t, ".")
+	example := newSignedZone(t, "example.")
+	anchor := root.key.ToDS(SHA256)
+
+	chain := root.sign(t, root.key)
+	chain = append(chain, root.sign(t, example.key.ToDS(SHA256))...)
+	chain = append(chain, example.sign(t, example.key)...)
+
+	m := new(Msg)
+	m.SetQuestion("www.example.", TypeA)
+	m.Response = true
+	m.Answer = example.sign(t, newRR(t, "www.example. 3600 IN A 192.0.2.1"), newRR(t, "www.example. 3600 IN A 192.0.2.2"))
+	m.Extra = chain
+	if err := m.Verify([]RR{anchor}); err != nil {
+		t.Fatalf("failed to verify signed response: %v", err)
+	}
+	if err := m.Verify([]RR{root.key}); err != nil {
+		t.Errorf("failed to verify signed response with a DNSKEY anchor: %v", err)
+	}
+	if err := m.Verify([]RR{newSignedZone(t, ".").key}); err == nil {
+		t.Error("expected verification to fail with the wrong anchor")
+	}
+
+	m.Answer[1].(*A).A = net.IPv4(192, 0, 2, 3)
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected verification of tampered response to fail")
+	}
+
+	// Negative answer, www.example. does not exist.
+	m = new(Msg)
+	m.SetQuestion("www.example.", TypeA)
+	m.Response = true
+	m.Rcode = RcodeNameError
+	soa := newRR(t, "example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 600 86400 300")
+	nsec := newRR(t, "example. 3600 IN NSEC mail.example. SOA NS RRSIG NSEC DNSKEY")
+	m.Ns = append(example.sign(t, soa), example.sign(t, nsec)...)
+	m.Extra = chain
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected verification to fail when the name is not covered")
+	}
+	nsec = newRR(t, "mail.example. 3600 IN NSEC example. A RRSIG NSEC")
+	m.Ns = append(m.Ns, example.sign(t, nsec)...)
+	if err := m.Verify([]RR{anchor}); err != nil {
+		t.Errorf("failed to verify signed negative response: %v", err)
+	}
+}
+
+func TestMsgVerifyWildcard(t *testing.T) {
+	root := newSignedZone(t, ".")
+	example := newSignedZone(t, "example.")
+	anchor := root.key.ToDS(SHA256)
+
+	chain := root.sign(t, root.key)
+	chain = append(chain, root.sign(t, example.key.ToDS(SHA256))...)
+	chain = append(chain, example.sign(t, example.key)...)
+	soa := newRR(t, "example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 600 86400 300")
+
+	// The zone has *.example., so www.example. should have been synthesized from it.
+	m := new(Msg)
+	m.SetQuestion("www.example.", TypeA)
+	m.Response = true
+	m.Rcode = RcodeNameError
+	m.Ns = example.sign(t, soa)
+	for _, s := range []string{
+		"example. 3600 IN NSEC *.example. SOA NS RRSIG NSEC DNSKEY",
+		"*.example. 3600 IN NSEC mail.example. A RRSIG NSEC",
+		"mail.example. 3600 IN NSEC example. A RRSIG NSEC",
+	} {
+		m.Ns = append(m.Ns, example.sign(t, newRR(t, s))...)
+	}
+	m.Extra = chain
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected NXDOMAIN in a zone with a matching wildcard to fail verification")
+	}
+
+	// The same with NSEC3, the closest encloser example. has a matching record and the
+	// next closer name www.example. is covered, but *.example. exists.
+	nsec3 := func(name, next string) RR {
+		return &NSEC3{Hdr: RR_Header{Name: name, Rrtype: TypeNSEC3, Class: ClassINET, Ttl: 3600},
+			Hash: SHA1, HashLength: 20, NextDomain: next, TypeBitMap: []uint16{TypeA, TypeRRSIG}}
+	}
+	cover := func(name string) RR {
+		h := HashName(name, SHA1, 0, "")
+		r := nsec3(h[:30]+"00.example.", h[:30]+"VV")
+		if !r.(*NSEC3).Cover(name) {
+			t.Fatalf("NSEC3 record should cover %s", name)
+		}
+		return r
+	}
+	match := func(name string) RR {
+		h := HashName(name, SHA1, 0, "")
+		return nsec3(h+".example.", h[:30]+"VV")
+	}
+	m.Ns = example.sign(t, soa)
+	for _, r := range []RR{match("example."), cover("www.example."), match("*.example.")} {
+		m.Ns = append(m.Ns, example.sign(t, r)...)
+	}
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected NSEC3 NXDOMAIN in a zone with a matching wildcard to fail verification")
+	}
+	m.Ns = example.sign(t, soa)
+	for _, r := range []RR{match("example."), cover("www.example.")} {
+		m.Ns = append(m.Ns, example.sign(t, r)...)
+	}
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected NSEC3 NXDOMAIN without proof of the wildcard's absence to fail verification")
+	}
+	m.Ns = append(m.Ns, example.sign(t, cover("*.example."))...)
+	if err := m.Verify([]RR{anchor}); err != nil {
+		t.Errorf("failed to verify NSEC3 NXDOMAIN response: %v", err)
+	}
+}
+
+func TestMsgVerifyEmptyNonTerminal(t *testing.T) {
+	root := newSignedZone(t, ".")
+	example := newSignedZone(t, "example.")
+	anchor := root.key.ToDS(SHA256)
+
+	chain := root.sign(t, root.key)
+	chain = append(chain, root.sign(t, example.key.ToDS(SHA256))...)
+	chain = append(chain, example.sign(t, example.key)...)
+	soa := newRR(t, "example. 3600 IN SOA ns.example. hostmaster.example. 1 3600 600 86400 300")
+
+	// b.example. only exists because a.b.example. does.
+	m := new(Msg)
+	m.SetQuestion("b.example.", TypeA)
+	m.Response = true
+	nsec := newRR(t, "example. 3600 IN NSEC a.b.example. SOA NS RRSIG NSEC DNSKEY")
+	m.Ns = append(example.sign(t, soa), example.sign(t, nsec)...)
+	m.Extra = chain
+	if err := m.Verify([]RR{anchor}); err != nil {
+		t.Errorf("failed to verify NODATA response for an empty non-terminal: %v", err)
+	}
+	m.Rcode = RcodeNameError
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected NXDOMAIN for an empty non-terminal to fail verification")
+	}
+
+	// The NSEC record must come from the zone that holds b.example., not from a sibling zone
+	// or from the parent side of the delegation to example.
+	aaa := newSignedZone(t, "aaa.")
+	forged := append(chain, root.sign(t, aaa.key.ToDS(SHA256))...)
+	forged = append(forged, aaa.sign(t, aaa.key)...)
+	m.Rcode = RcodeSuccess
+	m.Extra = forged
+	m.Ns = append(example.sign(t, soa), aaa.sign(t, newRR(t, "aaa. 3600 IN NSEC a.b.example. SOA NS RRSIG NSEC DNSKEY"))...)
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected NODATA for an empty non-terminal proven by another zone to fail verification")
+	}
+	m.Ns = append(example.sign(t, soa), root.sign(t, newRR(t, "example. 3600 IN NSEC a.b.example. NS DS RRSIG NSEC"))...)
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected NODATA for an empty non-terminal proven by the parent zone to fail verification")
+	}
+
+	// With NSEC3 the empty non-terminal has an NSEC3 record with an empty type bitmap.
+	nsec3 := &NSEC3{Hdr: RR_Header{Name: NSEC3OwnerName("b.example.", "example.", SHA1, 0, ""), Rrtype: TypeNSEC3, Class: ClassINET, Ttl: 3600},
+		Hash: SHA1, Iterations: 0, SaltLength: 0, Salt: "", HashLength: 20, NextDomain: HashName("a.b.example.", SHA1, 0, "")}
+	m = new(Msg)
+	m.SetQuestion("b.example.", TypeA)
+	m.Response = true
+	m.Ns = append(example.sign(t, soa), example.sign(t, nsec3)...)
+	m.Extra = chain
+	if err := m.Verify([]RR{anchor}); err != nil {
+		t.Errorf("failed to verify NSEC3 NODATA response for an empty non-terminal: %v", err)
+	}
+}
+
+func TestMsgVerifyOptOut(t *testing.T) {
+	root := newSignedZone(t, ".")
+	example := newSignedZone(t, "example.")
+	anchor := root.key.ToDS(SHA256)
+
+	chain := root.sign(t, root.key)
+	chain = append(chain, root.sign(t, example.key.ToDS(SHA256))...)
+	chain = append(chain, example.sign(t, example.key)...)
+
+	// Referral to the unsigned sub.example. zone.
+	m := new(Msg)
+	m.SetQuestion("www.sub.example.", TypeA)
+	m.Response = true
+	m.Ns = []RR{newRR(t, "sub.example. 3600 IN NS ns.sub.example.")}
+	m.Extra = append(chain, newRR(t, "ns.sub.example. 3600 IN A 192.0.2.53"))
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected verification of an unproven insecure delegation to fail")
+	}
+
+	// An unsigned DS RRset doesn't make the delegation secure, wherever it is.
+	ds := newRR(t, "sub.example. 3600 IN DS 12345 13 2 "+strings.Repeat("AB", 32))
+	m.Ns = append(m.Ns, ds)
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected verification of a delegation with an unsigned DS in the authority section to fail")
+	}
+	m.Ns = m.Ns[:1]
+	m.Extra = append(m.Extra, ds)
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected verification of a delegation with an unsigned DS in the additional section to fail")
+	}
+	m.Extra = m.Extra[:len(m.Extra)-1]
+
+	// An opt-out NSEC3 record that covers the hash of sub.example., the next closer name.
+	nsec3 := &NSEC3{Hdr: RR_Header{Name: strings.Repeat("0", 32) + ".example.", Rrtype: TypeNSEC3, Class: ClassINET, Ttl: 3600},
+		Hash: SHA1, Flags: 0, Iterations: 0, Salt: "", HashLength: 20, NextDomain: strings.Repeat("V", 32),
+		TypeBitMap: []uint16{TypeA, TypeRRSIG}}
+	if !nsec3.Cover("sub.example.") {
+		t.Fatal("NSEC3 record should cover sub.example.")
+	}
+	nsec3.Flags = 1
+	m.Ns = append(m.Ns, example.sign(t, nsec3)...)
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected verification to fail without a matching NSEC3 record for the closest encloser")
+	}
+
+	// The closest encloser example. has a matching NSEC3 record.
+	h := HashName("example.", SHA1, 0, "")
+	apex := &NSEC3{Hdr: RR_Header{Name: h + ".example.", Rrtype: TypeNSEC3, Class: ClassINET, Ttl: 3600},
+		Hash: SHA1, Iterations: 0, Salt: "", HashLength: 20, NextDomain: h[:30] + "VV",
+		TypeBitMap: []uint16{TypeNS, TypeSOA, TypeRRSIG, TypeDNSKEY, TypeNSEC3PARAM}}
+	m.Ns = append(m.Ns, example.sign(t, apex)...)
+	if err := m.Verify([]RR{anchor}); err != nil {
+		t.Errorf("failed to verify opt-out insecure delegation: %v", err)
+	}
+	nsec3.Flags = 0
+	m.Ns = append(m.Ns[:1], example.sign(t, nsec3)...)
+	m.Ns = append(m.Ns, example.sign(t, apex)...)
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected verification to fail when the covering NSEC3 record is not opt-out")
+	}
+}
+
+func TestMsgVerifyNSEC3Zone(t *testing.T) {
+	root := newSignedZone(t, ".")
+	bank := newSignedZone(t, "bank.")
+	evil := newSignedZone(t, "evil.")
+	anchor := root.key.ToDS(SHA256)
+
+	chain := root.sign(t, root.key)
+	chain = append(chain, root.sign(t, bank.key.ToDS(SHA256))...)
+	chain = append(chain, root.sign(t, evil.key.ToDS(SHA256))...)
+	chain = append(chain, bank.sign(t, bank.key)...)
+	chain = append(chain, evil.sign(t, evil.key)...)
+	soa := newRR(t, "bank. 3600 IN SOA ns.bank. hostmaster.bank. 1 3600 600 86400 300")
+
+	nsec3 := func(z *signedZone, owner, name, next string, bitmap ...uint16) []RR {
+		h := HashName(name, SHA1, 0, "")
+		r := &NSEC3{Hdr: RR_Header{Name: strings.ToLower(h) + "." + owner, Rrtype: TypeNSEC3, Class: ClassINET, Ttl: 3600},
+			Hash: SHA1, Flags: 1, HashLength: 20, NextDomain: next, TypeBitMap: bitmap}
+		if next == "" {
+			r.NextDomain = h[:30] + "VV"
+		}
+		return z.sign(t, r)
+	}
+	cover := func(z *signedZone, owner, name string) []RR {
+		h := HashName(name, SHA1, 0, "")
+		r := &NSEC3{Hdr: RR_Header{Name: h[:30] + "00." + owner, Rrtype: TypeNSEC3, Class: ClassINET, Ttl: 3600},
+			Hash: SHA1, Flags: 1, HashLength: 20, NextDomain: h[:30] + "VV", TypeBitMap: []uint16{TypeA, TypeRRSIG}}
+		return z.sign(t, r)
+	}
+
+	// NODATA for www.bank. A, with an NSEC3 record from evil. with the hash of www.bank.
+	m := new(Msg)
+	m.SetQuestion("www.bank.", TypeA)
+	m.Response = true
+	m.Ns = append(bank.sign(t, soa), nsec3(evil, "evil.", "www.bank.", "", TypeRRSIG)...)
+	m.Extra = chain
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected NODATA with an NSEC3 record from another zone to fail verification")
+	}
+	// The same record in bank., but signed by the root.
+	m.Ns = append(bank.sign(t, soa), nsec3(root, "bank.", "www.bank.", "", TypeRRSIG)...)
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected NODATA with an NSEC3 record signed by the parent zone to fail verification")
+	}
+	m.Ns = append(bank.sign(t, soa), nsec3(bank, "bank.", "www.bank.", "", TypeRRSIG)...)
+	if err := m.Verify([]RR{anchor}); err != nil {
+		t.Errorf("failed to verify NSEC3 NODATA response: %v", err)
+	}
+
+	// NXDOMAIN for www.bank. with a closest encloser proof from evil.
+	m.Rcode = RcodeNameError
+	m.Ns = bank.sign(t, soa)
+	for _, rrs := range [][]RR{nsec3(evil, "evil.", "bank.", ""), cover(evil, "evil.", "www.bank."), cover(evil, "evil.", "*.bank.")} {
+		m.Ns = append(m.Ns, rrs...)
+	}
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected NXDOMAIN with NSEC3 records from another zone to fail verification")
+	}
+	// A proof that mixes the zones.
+	m.Ns = bank.sign(t, soa)
+	for _, rrs := range [][]RR{nsec3(bank, "bank.", "bank.", ""), cover(bank, "bank.", "www.bank."), cover(evil, "evil.", "*.bank.")} {
+		m.Ns = append(m.Ns, rrs...)
+	}
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected NXDOMAIN with a wildcard proof from another zone to fail verification")
+	}
+	m.Ns = append(m.Ns, cover(bank, "bank.", "*.bank.")...)
+	if err := m.Verify([]RR{anchor}); err != nil {
+		t.Errorf("failed to verify NSEC3 NXDOMAIN response: %v", err)
+	}
+
+	// Referral to sub.bank., with an opt-out closest encloser proof from evil.
+	m = new(Msg)
+	m.SetQuestion("www.sub.bank.", TypeA)
+	m.Response = true
+	m.Ns = []RR{newRR(t, "sub.bank. 3600 IN NS ns.sub.bank.")}
+	m.Ns = append(m.Ns, nsec3(evil, "evil.", "bank.", "")...)
+	m.Ns = append(m.Ns, cover(evil, "evil.", "sub.bank.")...)
+	m.Extra = chain
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected an insecure delegation proven by another zone to fail verification")
+	}
+	m.Ns = m.Ns[:1]
+	m.Ns = append(m.Ns, nsec3(bank, "bank.", "bank.", "")...)
+	m.Ns = append(m.Ns, cover(bank, "bank.", "sub.bank.")...)
+	if err := m.Verify([]RR{anchor}); err != nil {
+		t.Errorf("failed to verify opt-out insecure delegation: %v", err)
+	}
+}
+
+func TestMsgVerifyNSECZone(t *testing.T) {
+	root := newSignedZone(t, ".")
+	aaa := newSignedZone(t, "aaa.")
+	bank := newSignedZone(t, "bank.")
+	anchor := root.key.ToDS(SHA256)
+
+	chain := root.sign(t, root.key)
+	chain = append(chain, root.sign(t, aaa.key.ToDS(SHA256))...)
+	chain = append(chain, root.sign(t, bank.key.ToDS(SHA256))...)
+	chain = append(chain, aaa.sign(t, aaa.key)...)
+	chain = append(chain, bank.sign(t, bank.key)...)
+	soa := newRR(t, "bank. 3600 IN SOA ns.bank. hostmaster.bank. 1 3600 600 86400 300")
+
+	// NXDOMAIN for www.bank., with an NSEC record of the sibling zone aaa. that covers it.
+	m := new(Msg)
+	m.SetQuestion("www.bank.", TypeA)
+	m.Response = true
+	m.Rcode = RcodeNameError
+	m.Ns = append(bank.sign(t, soa), aaa.sign(t, newRR(t, "aaa. 3600 IN NSEC zzz.bank. SOA NS RRSIG NSEC DNSKEY"))...)
+	m.Extra = chain
+	if err := m.Verify([]RR{anchor}); err == nil {
+		t.Error("expected NXDOMAIN with an NSEC record from another zone to fail verification")
+	}
+	m.Ns = append(bank.sign(t, soa), bank.sign(t, newRR(t, "bank. 3600 IN NSEC zzz.bank. SOA NS RRSIG NSEC DNSKEY"))...)
+	if err := m.Verify([]RR{anchor}); err != nil {
+		t.Errorf("failed to verify NSEC NXDOMAIN response: %v", err)
+	}
+}
+
+func TestMsgStripDNSSEC(t *testing.T) {
+	example := newSignedZone(t, "example.")
+
+	m := new(Msg)
+	m.SetQuestion("www.example.", TypeA)
+	m.Response = true
+	m.Answer = example.sign(t, newRR(t, "www.example. 3600 IN A 192.0.2.1"), newRR(t, "www.example. 3600 IN A 192.0.2.2"))
+	m.Ns = example.sign(t, newRR(t, "example. 3600 IN NS ns.example."))
+	m.Ns = append(m.Ns, example.sign(t, newRR(t, "example. 3600 IN NSEC ns.example. NS SOA RRSIG NSEC DNSKEY"))...)
+	m.Extra = append(example.sign(t, example.key), newRR(t, "ns.example. 3600 IN A 192.0.2.53"))
+	m.SetEdns0(4096, true)
+
+	m.StripDNSSEC()
+	expected := map[string][]uint16{
+		"answer":     {TypeA, TypeA},
+		"authority":  {TypeNS},
+		"additional": {TypeA, TypeOPT},
+	}
+	got := make(map[string][]uint16)
+	m.ForEachRR(func(section string, rr RR) bool {
+		got[section] = append(got[section], rr.Header().Rrtype)
+		return true
+	})
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected record types %v after stripping, got %v", expected, got)
+	}
+
+	// An explicitly asked for DNSKEY is kept, its signature is not.
+	m = new(Msg)
+	m.SetQuestion("example.", TypeDNSKEY)
+	m.Answer = example.sign(t, example.key)
+	m.StripDNSSEC()
+	if len(m.Answer) != 1 || m.Answer[0].Header().Rrtype != TypeDNSKEY {
+		t.Errorf("expected only the DNSKEY record, got %v", m.Answer)
+	}
+}
+
+func TestMsgVerifyReply(t *testing.T) {
+	root := newSignedZone(t, ".")
+	example := newSignedZone(t, "example.")
+	anchor := root.key.ToDS(SHA256)
+
+	reply := func(req *Msg) *Msg {
+		m := new(Msg)
+		m.SetReply(req)
+		m.Answer = example.sign(t, newRR(t, "www.example. 3600 IN A 192.0.2.1"))
+		m.Extra = root.sign(t, root.key)
+		m.Extra = append(m.Extra, root.sign(t, example.key.ToDS(SHA256))...)
+		m.Extra = append(m.Extra, example.sign(t, example.key)...)
+		return m
+	}
+	req := new(Msg)
+	req.SetQuestion("www.example.", TypeA)
+	req.SetEdns0(4096, true)
+
+	m := reply(req)
+	if err := m.VerifyReply(req, []RR{anchor}); err != nil {
+		t.Fatalf("failed to verify signed response: %v", err)
+	}
+	if !m.AuthenticatedData || len(m.Answer) != 2 {
+		t.Errorf("expected AD and the signed answer, got AD %t and %v", m.AuthenticatedData, m.Answer)
+	}
+
+	m = reply(req)
+	m.Answer[0].(*A).A = net.IPv4(192, 0, 2, 3)
+	if err := m.VerifyReply(req, []RR{anchor}); err == nil {
+		t.Error("expected verification of tampered response to fail")
+	}
+	if m.AuthenticatedData {
+		t.Error("expected no AD for a response that failed validation")
+	}
+
+	// With CD the response isn't validated, so the tampered response is accepted.
+	req.CheckingDisabled = true
+	m.AuthenticatedData = true
+	if err := m.VerifyReply(req, []RR{anchor}); err != nil {
+		t.Errorf("expected no validation with CD, got %v", err)
+	}
+	if m.AuthenticatedData || !m.CheckingDisabled {
+		t.Errorf("expected AD cleared and CD set, got AD %t and CD %t", m.AuthenticatedData, m.CheckingDisabled)
+	}
+
+	// Without DO the DNSSEC records are removed after validation.
+	req = new(Msg)
+	req.SetQuestion("www.example.", TypeA)
+	m = reply(req)
+	if err := m.VerifyReply(req, []RR{anchor}); err != nil {
+		t.Fatalf("failed to verify signed response: %v", err)
+	}
+	if !m.AuthenticatedData || len(m.Answer) != 1 || len(m.Extra) != 1 {
+		t.Errorf("expected AD and no DNSSEC records, got AD %t and\n%s", m.AuthenticatedData, m)
+	}
+}
+
+func TestPackRdata(t *testing.T) {
+	testcases := map[string][]byte{
+		"miek.nl. 3600 IN A 192.0.2.1":           {192, 0, 2, 1},
+		"miek.nl. 3600 IN MX 10 MX.Miek.NL.":     {0, 10, 2, 'm', 'x', 4, 'm', 'i', 'e', 'k', 2, 'n', 'l', 0},
+		"miek.nl. 3600 IN TXT \"Hello\"":         {5, 'H', 'e', 'l', 'l', 'o'},
+		"miek.nl. 3600 IN NS A.miek.nl.":         {1, 'a', 4, 'm', 'i', 'e', 'k', 2, 'n', 'l', 0},
+		"miek.nl. 3600 IN DS 60485 5 1 2BB183AF": {0xec, 0x45, 5, 1, 0x2b, 0xb1, 0x83, 0xaf},
+		"miek.nl. 3600 IN CAA 0 issue \"\"":      {0, 5, 'i', 's', 's', 'u', 'e'},
+		"miek.nl. 3600 IN URI 10 1 \"\"":         {0, 10, 0, 1},
+	}
+	for s, expected := range testcases {
+		rdata, err := PackRdata(newRR(t, s))
+		if err != nil {
+			t.Errorf("failed to pack rdata of %s: %v", s, err)
+			continue
+		}
+		if !bytes.Equal(rdata, expected) {
+			t.Errorf("%s: expected rdata %v, got %v", s, expected, rdata)
+		}
+	}
+	// A TXT record without strings has empty rdata.
+	txt := &TXT{Hdr: RR_Header{Name: "miek.nl.", Rrtype: TypeTXT, Class: ClassINET, Ttl: 3600}}
+	if rdata, err := PackRdata(txt); err != nil || len(rdata) != 0 {
+		t.Errorf("expected empty rdata for %s, got %v: %v", txt, rdata, err)
+	}
+}
+
+func TestRRSIGCheckSigner(t *testing.T) {
+	sig := newRR(t, "www.example.org. 3600 IN RRSIG A 8 3 3600 20170101000000 20160101000000 12345 Example.ORG. AwEAAQ==").(*RRSIG)
+	if err := sig.CheckSigner("example.org."); err != nil {
+		t.Errorf("expected signer example.org. to be accepted, got %v", err)
+	}
+	if err := sig.CheckSigner("org."); err != ErrSig {
+		t.Errorf("expected ErrSig for a zone that is not the signer, got %v", err)
+	}
+
+	// Signer name of a foreign zone.
+	sig.SignerName = "example.net."
+	if err := sig.CheckSigner("example.net."); err != ErrSig {
+		t.Errorf("expected ErrSig for a foreign signer, got %v", err)
+	}
+	sig.Hdr.Name = "example.org."
+	sig.SignerName = "www.example.org."
+	if err := sig.CheckSigner("www.example.org."); err != ErrSig {
+		t.Errorf("expected ErrSig for a signer below the owner, got %v", err)
+	}
+}
+
+func TestRRSIGIsWildcard(t *testing.T) {
+	sig := newRR(t, "www.example.org. 3600 IN RRSIG A 8 3 3600 20170101000000 20160101000000 12345 example.org. AwEAAQ==").(*RRSIG)
+	if wildcard, name := sig.IsWildcard("www.example.org."); wildcard || name != "" {
+		t.Errorf("expected no wildcard expansion, got %t and %q", wildcard, name)
+	}
+
+	// Expanded from *.example.org.
+	sig.Labels = 2
+	for _, owner := range []string{"www.example.org.", "a.b.example.org", "*.example.org."} {
+		wildcard, name := sig.IsWildcard(owner)
+		if expanded := owner != "*.example.org."; wildcard != expanded {
+			t.Errorf("expected wildcard expansion %t for %s, got %t", expanded, owner, wildcard)
+			continue
+		}
+		if wildcard && name != "*.example.org." {
+			t.Errorf("expected wildcard *.example.org. for %s, got %q", owner, name)
+		}
+	}
+
+	sig.Labels = 0
+	if wildcard, name := sig.IsWildcard("example."); !wildcard || name != "*." {
+		t.Errorf("expected expansion of the root wildcard, got %t and %q", wildcard, name)
+	}
+}
+
+func TestSignWildcardLabels(t *testing.T) {
+	z := newSignedZone(t, "example.org.")
+	for name, labels := range map[string]uint8{"*.example.org.": 2, "a.b.example.org.": 4, "*a.example.org.": 3} {
+		rrs := z.sign(t, newRR(t, name+" 3600 IN A 127.0.0.1"))
+		sig := rrs[len(rrs)-1].(*RRSIG)
+		if sig.Labels != labels {
+			t.Errorf("expected %d labels in RRSIG for %s, got %d", labels, name, sig.Labels)
+		}
+	}
+}
+
+func TestSigningWindow(t *testing.T) {
+	validity := 14 * 24 * time.Hour
+	inception, expiration := SigningWindow(validity)
+	now := uint32(time.Now().UTC().Unix())
+	if inception >= now || now >= expiration {
+		t.Errorf("expected inception %d < now %d < expiration %d", inception, now, expiration)
+	}
+	if w := time.Duration(expiration-inception) * time.Second; w != validity+SigningInceptionOffset {
+		t.Errorf("expected window of %s, got %s", validity+SigningInceptionOffset, w)
+	}
+	sig := &RRSIG{Inception: inception, Expiration: expiration}
+	if !sig.ValidityPeriod(time.Time{}) {
+		t.Error("expected signature with signing window to be valid now")
+	}
+	if sig.ValidityPeriod(time.Now().Add(validity + time.Minute)) {
+		t.Error("expected signature with signing window to be expired after the validity")
+	}
+}
@@ -0,0 +1,464 @@
+package dns
+
+import (
+	"strings"
+	"time"
+)
+
+// Verify validates the DNSSEC signatures in the message. The trust anchors are DS or
+// DNSKEY records; starting from those the DNSKEY and DS RRsets found in the message
+// (usually in the additional section) are validated to build a chain of trusted keys.
+// Every other RRset in the answer and authority section must then carry a valid signature
+// made with one of the trusted keys. For a negative response (NXDOMAIN or NODATA) the NSEC
+// or NSEC3 records in the authority section must deny the existence of the name or type
+// from the question. An NXDOMAIN response must also prove that there is no wildcard at the
+// closest encloser of the name, for NSEC3 this takes the closest encloser proof. For NODATA
+// only the matching record is checked, and answers synthesized from a wildcard are not
+// checked for the proof that the name itself does not exist. An unsigned NS RRset in the
+// authority section is a delegation; it must come with a signed DS RRset, or with NSEC or
+// NSEC3 records proving that the delegation is insecure (this includes a closest encloser
+// proof with an opt-out NSEC3 record covering the delegation). The NSEC and NSEC3 records
+// in a proof must be signed by a zone that holds the name they deny.
+func (dns *Msg) Verify(anchors []RR) error {
+	now := time.Now()
+	sigs := make(map[rrsetKey][]*RRSIG)
+	sets := make(map[rrsetKey][]RR)
+	var order []rrsetKey
+	for _, section := range [][]RR{dns.Answer, dns.Ns, dns.Extra} {
+		for _, r := range section {
+			h := r.Header()
+			if sig, ok := r.(*RRSIG); ok {
+				k := rrsetKey{strings.ToLower(h.Name), sig.TypeCovered, h.Class}
+				sigs[k] = append(sigs[k], sig)
+				continue
+			}
+			if h.Rrtype == TypeOPT || h.Rrtype == TypeTSIG || h.Rrtype == TypeSIG {
+				continue
+			}
+			k := rrsetKey{strings.ToLower(h.Name), h.Rrtype, h.Class}
+			if _, ok := sets[k]; !ok {
+				order = append(order, k)
+			}
+			sets[k] = append(sets[k], r)
+		}
+	}
+
+	var (
+		keys []*DNSKEY // trusted keys
+		ds   []*DS     // trusted delegation signers
+	)
+	for _, a := range anchors {
+		switch a := a.(type) {
+		case *DNSKEY:
+			keys = append(keys, a)
+		case *DS:
+			ds = append(ds, a)
+		}
+	}
+
+	// Walk down the chain, until no new keys or delegation signers can be trusted.
+	trusted := make(map[rrsetKey]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, k := range order {
+			if trusted[k] {
+				continue
+			}
+			switch k.rrtype {
+			case TypeDNSKEY:
+				var sep []*DNSKEY
+				for _, r := range sets[k] {
+					if key, ok := r.(*DNSKEY); ok && (keyInSet(key, keys) || keyMatchesDS(key, ds)) {
+						sep = append(sep, key)
+					}
+				}
+				if _, err := verifyRRset(sets[k], sigs[k], sep, now); err != nil {
+					continue
+				}
+				for _, r := range sets[k] {
+					keys = append(keys, r.(*DNSKEY))
+				}
+			case TypeDS:
+				if _, err := verifyRRset(sets[k], sigs[k], keys, now); err != nil {
+					continue
+				}
+				for _, r := range sets[k] {
+					ds = append(ds, r.(*DS))
+				}
+			default:
+				continue
+			}
+			trusted[k] = true
+			changed = true
+		}
+	}
+
+	var (
+		delegations []rrsetKey
+		signers     = make(map[rrsetKey]string) // zone that signed each verified RRset
+	)
+	for _, k := range order {
+		if trusted[k] {
+			continue
+		}
+		if !inSection(dns.Answer, k) && !inSection(dns.Ns, k) {
+			continue
+		}
+		if k.rrtype == TypeNS && len(sigs[k]) == 0 && !inSection(dns.Answer, k) {
+			delegations = append(delegations, k)
+			continue
+		}
+		sig, err := verifyRRset(sets[k], sigs[k], keys, now)
+		if err != nil {
+			return err
+		}
+		signers[k] = strings.ToLower(Fqdn(sig.SignerName))
+	}
+
+	for _, k := range delegations {
+		if trusted[rrsetKey{k.name, TypeDS, k.class}] {
+			// Secure delegation.
+			continue
+		}
+		if err := denyDelegation(denialRecords(dns.Ns, signers, k.name), k.name); err != nil {
+			return err
+		}
+	}
+
+	if len(dns.Question) == 0 {
+		return nil
+	}
+	q := dns.Question[0]
+	switch {
+	case dns.Rcode == RcodeNameError:
+		return denyName(denialRecords(dns.Ns, signers, q.Name), q)
+	case dns.Rcode == RcodeSuccess && len(dns.Answer) == 0 && len(delegations) == 0:
+		return denyType(denialRecords(dns.Ns, signers, q.Name), q)
+	}
+	return nil
+}
+
+// VerifyReply validates the response dns to the request req, as a validating resolver
+// does before sending it to the client, and sets the AD bit with SetAD. The CD bit of
+// req is copied; when it is set the client does its own validation, so dns is not
+// verified and AD is cleared. Otherwise dns is verified with Verify and its error is
+// returned. When req has no OPT record with the DO bit set the DNSSEC records are
+// removed with StripDNSSEC, after validation.
+func (dns *Msg) VerifyReply(req *Msg, anchors []RR) error {
+	dns.CheckingDisabled = req.CheckingDisabled
+	var err error
+	if !dns.CheckingDisabled {
+		err = dns.Verify(anchors)
+	}
+	dns.SetAD(err == nil)
+	if opt := req.IsEdns0(); opt == nil || !opt.Do() {
+		dns.StripDNSSEC()
+	}
+	return err
+}
+
+type rrsetKey struct {
+	name   string
+	rrtype uint16
+	class  uint16
+}
+
+// verifyRRset checks if one of the signatures in sigs validates rrset with one of the keys,
+// and returns that signature.
+func verifyRRset(rrset []RR, sigs []*RRSIG, keys []*DNSKEY, t time.Time) (*RRSIG, error) {
+	if len(sigs) == 0 {
+		return nil, ErrNoSig
+	}
+	err := ErrSig
+	for _, sig := range sigs {
+		if !sig.ValidityPeriod(t) {
+			err = ErrTime
+			continue
+		}
+		for _, key := range keys {
+			if sig.KeyTag != key.KeyTag() || sig.Algorithm != key.Algorithm {
+				continue
+			}
+			if sig.CheckSigner(key.Hdr.Name) != nil {
+				continue
+			}
+			if sig.Verify(key, rrset) == nil {
+				return sig, nil
+			}
+		}
+	}
+	return nil, err
+}
+
+func keyInSet(key *DNSKEY, keys []*DNSKEY) bool {
+	for _, k := range keys {
+		if strings.ToLower(k.Hdr.Name) == strings.ToLower(key.Hdr.Name) && k.Flags == key.Flags &&
+			k.Protocol == key.Protocol && k.Algorithm == key.Algorithm && k.PublicKey == key.PublicKey {
+			return true
+		}
+	}
+	return false
+}
+
+func keyMatchesDS(key *DNSKEY, ds []*DS) bool {
+	for _, d := range ds {
+		if d.Verify(key) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func inSection(section []RR, k rrsetKey) bool {
+	for _, r := range section {
+		h := r.Header()
+		if h.Rrtype == k.rrtype && h.Class == k.class && strings.ToLower(h.Name) == k.name {
+			return true
+		}
+	}
+	return false
+}
+
+// denialRecords returns the NSEC and NSEC3 records in ns that may be used in a proof for
+// name. An NSEC record is only returned when the zone that signed it, as found in signers,
+// holds its owner name, its next name and name, and it isn't the parent side of a delegation
+// above name, which is in the child zone. An NSEC3 record is only returned when it
+// was signed by its own zone, the parent of its owner name; nsec3Sets checks that zone
+// against name. Otherwise any signed zone could deny names in another zone.
+func denialRecords(ns []RR, signers map[rrsetKey]string, name string) []RR {
+	name = strings.ToLower(name)
+	var denial []RR
+	for _, r := range ns {
+		switch x := r.(type) {
+		case *NSEC:
+			zone, ok := signers[rrsetKey{strings.ToLower(x.Hdr.Name), TypeNSEC, x.Hdr.Class}]
+			if !ok || !IsSubDomain(zone, strings.ToLower(x.Hdr.Name)) || !IsSubDomain(zone, strings.ToLower(x.NextDomain)) || !IsSubDomain(zone, name) {
+				continue
+			}
+			if delegationAbove(x, name) {
+				continue
+			}
+			denial = append(denial, x)
+		case *NSEC3:
+			zone := x.zone()
+			if zone != "" && signers[rrsetKey{strings.ToLower(x.Hdr.Name), TypeNSEC3, x.Hdr.Class}] == zone {
+				denial = append(denial, x)
+			}
+		}
+	}
+	return denial
+}
+
+// nsec3Sets groups the NSEC3 records in ns that can be used in a proof for name. All records
+// in a group are from the same zone and use the same hash parameters, and that zone is an
+// ancestor of name. When strict is true the zone must not be name itself, as for a
+// delegation, which is proven by its parent zone.
+func nsec3Sets(ns []RR, name string, strict bool) [][]*NSEC3 {
+	type params struct {
+		zone       string
+		hash       uint8
+		iterations uint16
+		salt       string
+	}
+	var (
+		order []params
+		sets  = make(map[params][]*NSEC3)
+	)
+	for _, r := range ns {
+		nsec3, ok := r.(*NSEC3)
+		if !ok {
+			continue
+		}
+		zone := nsec3.zone()
+		if !IsSubDomain(zone, name) || strict && CountLabel(zone) == CountLabel(name) {
+			continue
+		}
+		p := params{zone, nsec3.Hash, nsec3.Iterations, strings.ToUpper(nsec3.Salt)}
+		if _, ok := sets[p]; !ok {
+			order = append(order, p)
+		}
+		sets[p] = append(sets[p], nsec3)
+	}
+	groups := make([][]*NSEC3, 0, len(order))
+	for _, p := range order {
+		groups = append(groups, sets[p])
+	}
+	return groups
+}
+
+// denyName checks if the NSEC or NSEC3 records in ns prove that the name from q does not exist.
+// The name must be covered and the wildcard at its closest encloser must be covered too,
+// otherwise the answer should have been synthesized from that wildcard. See RFC 4035,
+// section 5.4 and RFC 5155, section 8.4.
+func denyName(ns []RR, q Question) error {
+	name := strings.ToLower(q.Name)
+	if ce, ok := nsecClosestEncloser(ns, name); ok {
+		for _, r := range ns {
+			if nsec, ok := r.(*NSEC); ok && nsec.Cover("*."+ce) {
+				return nil
+			}
+		}
+	}
+	for _, set := range nsec3Sets(ns, name, false) {
+		ce, _, ok := nsec3ClosestEncloser(set, name)
+		if !ok {
+			continue
+		}
+		for _, nsec3 := range set {
+			if nsec3.Cover("*." + ce) {
+				return nil
+			}
+		}
+	}
+	return &Error{err: "no proof of non-existence for " + q.Name}
+}
+
+// nsecClosestEncloser returns the closest encloser of name, the longest existing ancestor,
+// as proven by an NSEC record in ns that covers name.
+func nsecClosestEncloser(ns []RR, name string) (string, bool) {
+	for _, r := range ns {
+		nsec, ok := r.(*NSEC)
+		if !ok || !nsec.Cover(name) || emptyNonTerminal(nsec, name) {
+			// An empty non-terminal exists, it has descendants.
+			continue
+		}
+		// The owner and next name exist and nothing exists between them, so the closest
+		// encloser is the longest ancestor that name shares with either of them.
+		n := CompareDomainName(name, strings.ToLower(nsec.Hdr.Name))
+		if m := CompareDomainName(name, strings.ToLower(nsec.NextDomain)); m > n {
+			n = m
+		}
+		return ancestor(name, n), true
+	}
+	return "", false
+}
+
+// nsec3ClosestEncloser returns the closest encloser of name and the NSEC3 record that covers
+// the next closer name, the name one label longer than the closest encloser. This is the
+// closest encloser proof from RFC 5155, section 7.2.1. Name itself must not have a matching
+// NSEC3 record. The records in set must come from one zone, see nsec3Sets.
+func nsec3ClosestEncloser(set []*NSEC3, name string) (string, *NSEC3, bool) {
+	labels := CountLabel(name)
+	for n := labels; n >= 0; n-- {
+		ce := ancestor(name, n)
+		if !nsec3Match(set, ce) {
+			continue
+		}
+		if n == labels {
+			return "", nil, false
+		}
+		next := ancestor(name, n+1)
+		for _, nsec3 := range set {
+			if nsec3.Cover(next) {
+				return ce, nsec3, true
+			}
+		}
+		return "", nil, false
+	}
+	return "", nil, false
+}
+
+func nsec3Match(set []*NSEC3, name string) bool {
+	for _, nsec3 := range set {
+		if nsec3.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ancestor returns the ancestor of name that has its last n labels.
+func ancestor(name string, n int) string {
+	idx := Split(name)
+	if n <= 0 || len(idx) == 0 {
+		return "."
+	}
+	if n > len(idx) {
+		n = len(idx)
+	}
+	return name[idx[len(idx)-n]:]
+}
+
+// denyType checks if the NSEC or NSEC3 records in ns prove that the type from q does not exist.
+// For an empty non-terminal there is no matching NSEC record; it is proven by the NSEC
+// that covers the name and has one of its descendants as the next name. With NSEC3 an
+// empty non-terminal has a matching NSEC3 record with an empty type bitmap. A record from
+// the parent side of a delegation, with NS but without SOA in its bitmap, can only deny DS.
+func denyType(ns []RR, q Question) error {
+	name := strings.ToLower(q.Name)
+	for _, r := range ns {
+		nsec, ok := r.(*NSEC)
+		if !ok {
+			continue
+		}
+		if emptyNonTerminal(nsec, name) {
+			return nil
+		}
+		if nsec.Match(name) && denyTypeBitMap(nsec.TypeBitMap, q.Qtype) {
+			return nil
+		}
+	}
+	for _, set := range nsec3Sets(ns, name, false) {
+		for _, nsec3 := range set {
+			if nsec3.Match(name) && denyTypeBitMap(nsec3.TypeBitMap, q.Qtype) {
+				return nil
+			}
+		}
+	}
+	return &Error{err: "no proof of non-existence for type " + Type(q.Qtype).String() + " at " + q.Name}
+}
+
+// denyTypeBitMap returns true when bitmap, of the NSEC or NSEC3 record matching a name,
+// proves that there is no record of type t at that name.
+func denyTypeBitMap(bitmap []uint16, t uint16) bool {
+	if typeBitMapHas(bitmap, t) || typeBitMapHas(bitmap, TypeCNAME) {
+		return false
+	}
+	if t != TypeDS && typeBitMapHas(bitmap, TypeNS) && !typeBitMapHas(bitmap, TypeSOA) {
+		return false
+	}
+	return true
+}
+
+// denyDelegation checks if the NSEC or NSEC3 records in ns prove that the delegation to name
+// is insecure: the matching record has no DS type in its bitmap, or there is a closest encloser
+// proof for name in which the NSEC3 record covering the next closer name is opt-out, see
+// RFC 5155, section 8.6. The NSEC3 records must come from a zone above name.
+func denyDelegation(ns []RR, name string) error {
+	name = strings.ToLower(name)
+	for _, r := range ns {
+		if nsec, ok := r.(*NSEC); ok && nsec.Match(name) && !typeBitMapHas(nsec.TypeBitMap, TypeDS) {
+			return nil
+		}
+	}
+	for _, set := range nsec3Sets(ns, name, true) {
+		if _, nsec3, ok := nsec3ClosestEncloser(set, name); ok && nsec3.OptOut() {
+			return nil
+		}
+		for _, nsec3 := range set {
+			if nsec3.Match(name) && !typeBitMapHas(nsec3.TypeBitMap, TypeDS) {
+				return nil
+			}
+		}
+	}
+	return &Error{err: "no proof of insecure delegation for " + name}
+}
+
+// delegationAbove returns true when nsec is the record of a delegation point, with NS but
+// without SOA in its bitmap, that is an ancestor of name.
+func delegationAbove(nsec *NSEC, name string) bool {
+	owner := strings.ToLower(nsec.Hdr.Name)
+	if owner == name || !IsSubDomain(owner, name) {
+		return false
+	}
+	return typeBitMapHas(nsec.TypeBitMap, TypeNS) && !typeBitMapHas(nsec.TypeBitMap, TypeSOA)
+}
+
+// emptyNonTerminal returns true when nsec proves that name is an empty non-terminal: nsec
+// covers name and the next name is a descendant of name.
+func emptyNonTerminal(nsec *NSEC, name string) bool {
+	if !nsec.Cover(name) || CountLabel(nsec.NextDomain) <= CountLabel(name) {
+		return false
+	}
+	return IsSubDomain(name, nsec.NextDomain)
+}
@@ -19,6 +19,7 @@ const (
 	EDNS0SUBNET      = 0x8     // client-subnet (RFC6891)
 	EDNS0EXPIRE      = 0x9     // EDNS0 expire
 	EDNS0COOKIE      = 0xa     // EDNS0 Cookie
+	EDNS0PADDING     = 0xc     // EDNS0 padding (RFC7830)
 	EDNS0SUBNETDRAFT = 0x50fa  // Don't use! Use EDNS0SUBNET
 	EDNS0LOCALSTART  = 0xFDE9  // Beginning of range reserved for local/experimental use (RFC6891)
 	EDNS0LOCALEND    = 0xFFFE  // End of range reserved for local/experimental use (RFC6891)
@@ -70,6 +71,8 @@ func (rr *OPT) String() string {
 			s += "\n; DS HASH UNDERSTOOD: " + o.String()
 		case *EDNS0_N3U:
 			s += "\n; NSEC3 HASH UNDERSTOOD: " + o.String()
+		case *EDNS0_PADDING:
+			s += "\n; PADDING: " + o.String()
 		case *EDNS0_LOCAL:
 			s += "\n; LOCAL OPT: " + o.String()
 		}
@@ -87,6 +90,61 @@ func (rr *OPT) len() int {
 	return l
 }
 
+// Subnet returns the client subnet option, or nil when there is none.
+func (rr *OPT) Subnet() *EDNS0_SUBNET {
+	for _, o := range rr.Option {
+		if e, ok := o.(*EDNS0_SUBNET); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// FindOption returns the first option with option code code. Options the
+// package doesn't know are found as well, they are unpacked as EDNS0_LOCAL.
+// It is not called Option, as that is the name of the field with the options.
+func (rr *OPT) FindOption(code uint16) (EDNS0, bool) {
+	for _, o := range rr.Option {
+		if o.Option() == code {
+			return o, true
+		}
+	}
+	return nil, false
+}
+
+// SubnetScope returns the scope netmask of the client subnet option in the
+// message. If the message has no such option, false is returned.
+func (dns *Msg) SubnetScope() (uint8, bool) {
+	opt := dns.IsEdns0()
+	if opt == nil {
+		return 0, false
+	}
+	e := opt.Subnet()
+	if e == nil {
+		return 0, false
+	}
+	return e.SourceScope, true
+}
+
+// MergeOptions adds the EDNS0 options of other to rr. An option in rr with the same
+// option code as one in other is replaced by it, other options are appended in the
+// order they have in other. The options themselves are not copied.
+func (rr *OPT) MergeOptions(other *OPT) {
+	for _, o := range other.Option {
+		replaced := false
+		for i, e := range rr.Option {
+			if e.Option() == o.Option() {
+				rr.Option[i] = o
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			rr.Option = append(rr.Option, o)
+		}
+	}
+}
+
 // return the old value -> delete SetVersion?
 
 // Version returns the EDNS version used. Only zero is defined.
@@ -100,16 +158,15 @@ func (rr *OPT) SetVersion(v uint8) {
 }
 
 // ExtendedRcode returns the EDNS extended RCODE field (the upper 8 bits of the TTL).
+// These are the upper 8 bits of the 12 bit rcode, see RFC 6891, section 6.1.3.
 func (rr *OPT) ExtendedRcode() int {
-	return int((rr.Hdr.Ttl&0xFF000000)>>24) + 15
+	return int(rr.Hdr.Ttl >> 24)
 }
 
-// SetExtendedRcode sets the EDNS extended RCODE field.
+// SetExtendedRcode sets the EDNS extended RCODE field to v, the upper 8 bits of the
+// 12 bit rcode. Pack sets this from Msg.Rcode when it doesn't fit in the header.
 func (rr *OPT) SetExtendedRcode(v uint8) {
-	if v < RcodeBadVers { // Smaller than 16.. Use the 4 bits you have!
-		return
-	}
-	rr.Hdr.Ttl = rr.Hdr.Ttl&0x00FFFFFF | (uint32(v-15) << 24)
+	rr.Hdr.Ttl = rr.Hdr.Ttl&0x00FFFFFF | uint32(v)<<24
 }
 
 // UDPSize returns the UDP buffer size.
@@ -277,6 +334,44 @@ func (e *EDNS0_SUBNET) unpack(b []byte) error {
 	return nil
 }
 
+// SourceNet returns the address masked with the source netmask, or nil
+// when the family is unknown.
+func (e *EDNS0_SUBNET) SourceNet() *net.IPNet {
+	return e.net(e.SourceNetmask)
+}
+
+// ScopeNet returns the address masked with the scope netmask, or nil when the
+// family is unknown. In a reply this is the network the answer is valid for.
+func (e *EDNS0_SUBNET) ScopeNet() *net.IPNet {
+	return e.net(e.SourceScope)
+}
+
+func (e *EDNS0_SUBNET) net(ones uint8) *net.IPNet {
+	switch e.Family {
+	case 1:
+		if ip := e.Address.To4(); ip != nil && int(ones) <= net.IPv4len*8 {
+			mask := net.CIDRMask(int(ones), net.IPv4len*8)
+			return &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+		}
+	case 2:
+		if ip := e.Address.To16(); ip != nil && int(ones) <= net.IPv6len*8 {
+			mask := net.CIDRMask(int(ones), net.IPv6len*8)
+			return &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+		}
+	}
+	return nil
+}
+
+// Echoes returns true when the reply option r echoes the family, source netmask
+// and source address of the query option e, as required by RFC 7871.
+func (e *EDNS0_SUBNET) Echoes(r *EDNS0_SUBNET) bool {
+	if e.Family != r.Family || e.SourceNetmask != r.SourceNetmask {
+		return false
+	}
+	n, rn := e.SourceNet(), r.SourceNet()
+	return n != nil && rn != nil && n.IP.Equal(rn.IP)
+}
+
 func (e *EDNS0_SUBNET) String() (s string) {
 	if e.Address == nil {
 		s = "<nil>"
@@ -490,6 +585,82 @@ func (e *EDNS0_EXPIRE) unpack(b []byte) error {
 	return nil
 }
 
+// The EDNS0_PADDING option is used to pad a message to a certain size, to hide
+// its real size when it is encrypted, as in DNS over TLS. See RFC 7830. The
+// padding should consist of zero octets.
+type EDNS0_PADDING struct {
+	Code    uint16 // Always EDNS0PADDING
+	Padding []byte
+}
+
+func (e *EDNS0_PADDING) Option() uint16 { return EDNS0PADDING }
+func (e *EDNS0_PADDING) String() string { return strconv.Itoa(len(e.Padding)) + " octets" }
+
+func (e *EDNS0_PADDING) pack() ([]byte, error) {
+	b := make([]byte, len(e.Padding))
+	copy(b, e.Padding)
+	return b, nil
+}
+
+func (e *EDNS0_PADDING) unpack(b []byte) error {
+	e.Padding = make([]byte, len(b))
+	copy(e.Padding, b)
+	return nil
+}
+
+// Block sizes recommended by RFC 8467 to pad queries and responses to.
+const (
+	PaddingQueryBlockSize    = 128
+	PaddingResponseBlockSize = 468
+)
+
+// Pad adds an EDNS0_PADDING option to the OPT record of the message, or resizes an
+// existing one, so that the packed message is a multiple of blockSize octets. When
+// blockSize is zero the RFC 8467 policy is used: queries are padded to a multiple of
+// PaddingQueryBlockSize and responses to a multiple of PaddingResponseBlockSize.
+// The message must have an OPT record.
+func (dns *Msg) Pad(blockSize int) error {
+	if blockSize <= 0 {
+		blockSize = PaddingQueryBlockSize
+		if dns.Response {
+			blockSize = PaddingResponseBlockSize
+		}
+	}
+	opt := dns.IsEdns0()
+	if opt == nil {
+		return &Error{err: "no OPT record to add padding to"}
+	}
+	var pad *EDNS0_PADDING
+	for _, o := range opt.Option {
+		if p, ok := o.(*EDNS0_PADDING); ok {
+			pad = p
+			break
+		}
+	}
+	if pad == nil {
+		pad = &EDNS0_PADDING{Code: EDNS0PADDING}
+		opt.Option = append(opt.Option, pad)
+	}
+	pad.Padding = nil
+	// The length of the padding changes the length of the message, which, for a message
+	// with AutoCompress set, may change if it is compressed. Iterate until it fits.
+	for i := 0; i < 3; i++ {
+		l := dns.Len()
+		if l%blockSize == 0 {
+			return nil
+		}
+		n := len(pad.Padding) + blockSize - l%blockSize
+		if n > 0xFFFF {
+			return ErrBuf
+		}
+		pad.Padding = make([]byte, n)
+	}
+	if dns.Len()%blockSize != 0 {
+		return &Error{err: "failed to pad message"}
+	}
+	return nil
+}
+
 // The EDNS0_LOCAL option is used for local/experimental purposes. The option
 // code is recommended to be within the range [EDNS0LOCALSTART, EDNS0LOCALEND]
 // (RFC6891), although any unassigned code can actually be used.  The content of
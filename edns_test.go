@@ -1,6 +1,14 @@
 package dns
 
-import "testing"
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
 
 func TestOPTTtl(t *testing.T) {
 	e := &OPT{}
@@ -27,6 +35,424 @@ func TestOPTTtl(t *testing.T) {
 
 	e.SetExtendedRcode(42)
 	if e.ExtendedRcode() != 42 {
-		t.Errorf("set 42, expected %d, got %d", 42-15, e.ExtendedRcode())
+		t.Errorf("set 42, expected %d, got %d", 42, e.ExtendedRcode())
+	}
+}
+
+func TestEDNS0UnknownOption(t *testing.T) {
+	// OPT RR with a single option, code 65001, length 4.
+	wire := []byte{
+		0x00,       // owner name: .
+		0x00, 0x29, // type OPT
+		0x10, 0x00, // class, UDP size 4096
+		0x00, 0x00, 0x00, 0x00, // ttl
+		0x00, 0x08, // rdlength
+		0xfd, 0xe9, // option code 65001
+		0x00, 0x04, // option length
+		0xde, 0xad, 0xbe, 0xef,
+	}
+	rr, _, err := UnpackRR(wire, 0)
+	if err != nil {
+		t.Fatalf("failed to unpack OPT: %v", err)
+	}
+	opt := rr.(*OPT)
+	if len(opt.Option) != 1 {
+		t.Fatalf("expected 1 option, got %d", len(opt.Option))
+	}
+	if opt.Option[0].Option() != 65001 {
+		t.Errorf("expected option code 65001, got %d", opt.Option[0].Option())
+	}
+	buf := make([]byte, len(wire))
+	off, err := PackRR(opt, buf, 0, nil, false)
+	if err != nil {
+		t.Fatalf("failed to pack OPT: %v", err)
+	}
+	if !bytes.Equal(buf[:off], wire) {
+		t.Errorf("repacked OPT differs, got %x, expected %x", buf[:off], wire)
+	}
+}
+
+func TestEDNS0SubnetScope(t *testing.T) {
+	q := new(Msg)
+	q.SetQuestion("www.example.org.", TypeA)
+	if _, ok := q.SubnetScope(); ok {
+		t.Error("expected no subnet scope without EDNS0")
+	}
+	q.SetEdns0(4096, false)
+	qe := &EDNS0_SUBNET{Code: EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.77").To4()}
+	q.IsEdns0().Option = append(q.IsEdns0().Option, qe)
+
+	r := new(Msg)
+	r.SetReply(q)
+	r.SetEdns0(4096, false)
+	re := &EDNS0_SUBNET{Code: EDNS0SUBNET, Family: 1, SourceNetmask: 24, SourceScope: 24, Address: net.ParseIP("192.0.2.0").To4()}
+	r.IsEdns0().Option = append(r.IsEdns0().Option, re)
+	buf, err := r.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = new(Msg)
+	if err := r.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	scope, ok := r.SubnetScope()
+	if !ok || scope != 24 {
+		t.Errorf("expected scope 24, got %d (%t)", scope, ok)
+	}
+	re = r.IsEdns0().Subnet()
+	if !qe.Echoes(re) {
+		t.Errorf("reply %s should echo query %s", re, qe)
+	}
+	if n := re.ScopeNet(); n == nil || n.String() != "192.0.2.0/24" {
+		t.Errorf("expected scope network 192.0.2.0/24, got %v", n)
+	}
+	re.SourceNetmask = 16
+	if qe.Echoes(re) {
+		t.Errorf("reply %s should not echo query %s", re, qe)
+	}
+}
+
+func TestOPTFindOption(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("www.example.org.", TypeA)
+	m.SetEdns0(4096, false)
+	opt := m.IsEdns0()
+	opt.Option = []EDNS0{
+		&EDNS0_NSID{Code: EDNS0NSID},
+		&EDNS0_SUBNET{Code: EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4()},
+		&EDNS0_LOCAL{Code: 65001, Data: []byte{0xde, 0xad}},
+	}
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	opt = m.IsEdns0()
+
+	o, ok := opt.FindOption(8)
+	if !ok {
+		t.Fatal("expected to find the option with code 8")
+	}
+	if e, ok := o.(*EDNS0_SUBNET); !ok || e.Address.String() != "192.0.2.0" {
+		t.Errorf("expected the client subnet option, got %s", o)
+	}
+	if o, ok := opt.FindOption(65001); !ok || !bytes.Equal(o.(*EDNS0_LOCAL).Data, []byte{0xde, 0xad}) {
+		t.Errorf("expected the local option with code 65001, got %v", o)
+	}
+	if _, ok := opt.FindOption(EDNS0EXPIRE); ok {
+		t.Error("expected no option with code EXPIRE")
+	}
+}
+
+func TestMinimalEdns0(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeA)
+	m.SetEdns0(1232, true)
+	opt := m.IsEdns0()
+	opt.Option = []EDNS0{
+		&EDNS0_NSID{Code: EDNS0NSID},
+		&EDNS0_COOKIE{Code: EDNS0COOKIE, Cookie: "24a5ac1223b1a7c8"},
+		&EDNS0_SUBNET{Code: EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.ParseIP("192.0.2.0").To4()},
+	}
+	m.MinimalEdns0()
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	opt = m1.IsEdns0()
+	if opt == nil {
+		t.Fatal("expected OPT record to be kept")
+	}
+	if len(opt.Option) != 0 {
+		t.Errorf("expected no options, got %d", len(opt.Option))
+	}
+	if opt.UDPSize() != 1232 || !opt.Do() {
+		t.Errorf("expected UDP size 1232 and DO bit, got %d and %t", opt.UDPSize(), opt.Do())
+	}
+}
+
+func TestMsgValidateDuplicateOPT(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeA)
+	m.SetEdns0(4096, false)
+	if err := m.Validate(); err != nil {
+		t.Errorf("expected a single OPT record to validate, got %v", err)
+	}
+	m.SetEdns0(512, true)
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := m1.Validate(); err != ErrFmt {
+		t.Errorf("expected ErrFmt for two OPT records, got %v", err)
+	}
+	if opt := m1.IsEdns0(); opt == nil || opt.UDPSize() != 4096 {
+		t.Errorf("expected IsEdns0 to return the first OPT record, got %v", opt)
+	}
+	if !strings.Contains(logged.String(), "more than one OPT RR") {
+		t.Errorf("expected the duplicate OPT record to be logged, got %q", logged.String())
+	}
+}
+
+func TestMsgPad(t *testing.T) {
+	for _, name := range []string{"a.", "miek.nl.", strings.Repeat("a", 63) + "." + strings.Repeat("b", 63) + ".example.org."} {
+		m := new(Msg)
+		m.SetQuestion(name, TypeAAAA)
+		m.SetEdns0(4096, true)
+		if err := m.Pad(0); err != nil {
+			t.Fatalf("failed to pad query for %s: %v", name, err)
+		}
+		buf, err := m.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(buf)%PaddingQueryBlockSize != 0 {
+			t.Errorf("query for %s padded to %d octets, expected a multiple of %d", name, len(buf), PaddingQueryBlockSize)
+		}
+
+		r := new(Msg)
+		r.SetReply(m)
+		for i := 0; i < len(name); i++ {
+			r.Answer = append(r.Answer, newRR(t, fmt.Sprintf("%s 3600 IN AAAA 2001:db8::%x", name, i)))
+		}
+		r.Extra = append(r.Extra, m.IsEdns0())
+		// Pad again, the padding from the query is resized.
+		if err := r.Pad(0); err != nil {
+			t.Fatalf("failed to pad response for %s: %v", name, err)
+		}
+		if r.IsEdns0() == nil || len(r.IsEdns0().Option) != 1 {
+			t.Fatalf("expected a single padding option, got %v", r.IsEdns0())
+		}
+		buf, err = r.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(buf)%PaddingResponseBlockSize != 0 {
+			t.Errorf("response for %s padded to %d octets, expected a multiple of %d", name, len(buf), PaddingResponseBlockSize)
+		}
+		r1 := new(Msg)
+		if err := r1.Unpack(buf); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := r1.IsEdns0().Option[0].(*EDNS0_PADDING); !ok {
+			t.Errorf("expected padding option after unpack, got %T", r1.IsEdns0().Option[0])
+		}
+	}
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	if err := m.Pad(0); err == nil {
+		t.Error("expected an error padding a message without OPT record")
+	}
+}
+
+func TestSetReplyEdns0(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeDNSKEY)
+	m.SetEdns0(1232, true)
+
+	r := new(Msg)
+	r.SetReplyEdns0(m, 4096)
+	opt := r.IsEdns0()
+	if opt == nil {
+		t.Fatal("expected an OPT record in the reply")
+	}
+	if !opt.Do() {
+		t.Error("expected DO bit to be echoed in the reply")
+	}
+	if opt.UDPSize() != 4096 {
+		t.Errorf("expected UDP size 4096, got %d", opt.UDPSize())
+	}
+	if r.Id != m.Id || !r.Response {
+		t.Errorf("unexpected header for reply: %v", r.MsgHdr)
+	}
+
+	m.IsEdns0().Hdr.Ttl = 0 // clear DO
+	r = new(Msg)
+	r.SetReplyEdns0(m, 4096)
+	if opt := r.IsEdns0(); opt == nil || opt.Do() {
+		t.Errorf("expected an OPT record without DO bit, got %v", opt)
+	}
+
+	m = new(Msg)
+	m.SetQuestion("miek.nl.", TypeDNSKEY)
+	r = new(Msg)
+	r.SetReplyEdns0(m, 4096)
+	if opt := r.IsEdns0(); opt != nil {
+		t.Errorf("expected no OPT record in reply to a query without one, got %v", opt)
+	}
+}
+
+func TestOPTMergeOptions(t *testing.T) {
+	nsid := new(OPT)
+	nsid.Hdr.Name = "."
+	nsid.Hdr.Rrtype = TypeOPT
+	nsid.Option = []EDNS0{&EDNS0_NSID{Code: EDNS0NSID, Nsid: "6465"}}
+
+	cookie := new(OPT)
+	cookie.Option = []EDNS0{&EDNS0_COOKIE{Code: EDNS0COOKIE, Cookie: "24a5ac1223344556"}}
+
+	nsid.MergeOptions(cookie)
+	if len(nsid.Option) != 2 {
+		t.Fatalf("expected 2 options after merge, got %d", len(nsid.Option))
+	}
+	if _, ok := nsid.Option[0].(*EDNS0_NSID); !ok {
+		t.Errorf("expected NSID as first option, got %T", nsid.Option[0])
+	}
+	if _, ok := nsid.Option[1].(*EDNS0_COOKIE); !ok {
+		t.Errorf("expected COOKIE as second option, got %T", nsid.Option[1])
+	}
+
+	// Merging an option with the same code replaces it.
+	cookie.Option[0] = &EDNS0_COOKIE{Code: EDNS0COOKIE, Cookie: "aabbccddeeff0011"}
+	nsid.MergeOptions(cookie)
+	if len(nsid.Option) != 2 {
+		t.Fatalf("expected 2 options after second merge, got %d", len(nsid.Option))
+	}
+	if c := nsid.Option[1].(*EDNS0_COOKIE).Cookie; c != "aabbccddeeff0011" {
+		t.Errorf("expected cookie to be replaced, got %s", c)
+	}
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	m.Extra = append(m.Extra, nsid)
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if opt := m1.IsEdns0(); opt == nil || len(opt.Option) != 2 {
+		t.Errorf("expected 2 options after unpack, got %v", opt)
+	}
+}
+
+func TestSetEdnsBadVers(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeSOA)
+	m.SetEdns0(1232, true)
+	m.IsEdns0().SetVersion(1)
+
+	r := new(Msg)
+	r.SetEdnsBadVers(m)
+	buf, err := r.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rcode := buf[3] & 0xF; rcode != RcodeBadVers&0xF {
+		t.Errorf("expected rcode %d in the header, got %d", RcodeBadVers&0xF, rcode)
+	}
+	if r.Rcode != RcodeBadVers {
+		t.Errorf("expected rcode to be left at %d after pack, got %d", RcodeBadVers, r.Rcode)
+	}
+
+	r1 := new(Msg)
+	if err := r1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if r1.Rcode != RcodeBadVers {
+		t.Errorf("expected rcode %d, got %d", RcodeBadVers, r1.Rcode)
+	}
+	opt := r1.IsEdns0()
+	if opt == nil {
+		t.Fatal("expected an OPT record in the reply")
+	}
+	if opt.ExtendedRcode() != RcodeBadVers>>4 {
+		t.Errorf("expected extended rcode %d, got %d", RcodeBadVers>>4, opt.ExtendedRcode())
+	}
+	if opt.Version() != 0 {
+		t.Errorf("expected version 0, got %d", opt.Version())
+	}
+	if !opt.Do() {
+		t.Error("expected DO bit to be echoed")
+	}
+
+	// Without an OPT record the rcode can't be packed.
+	r.Extra = nil
+	if _, err := r.Pack(); err != ErrExtendedRcode {
+		t.Errorf("expected ErrExtendedRcode, got %v", err)
+	}
+}
+
+func TestSetExtendedRcodePack(t *testing.T) {
+	// Setting the extended rcode on the OPT record, with a 4 bit rcode in the message,
+	// is left alone by Pack and gives the full rcode after Unpack.
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeSOA)
+	m.Response = true
+	m.SetEdns0(4096, false)
+	m.IsEdns0().SetExtendedRcode(RcodeBadVers >> 4)
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if m1.Rcode != RcodeBadVers {
+		t.Errorf("expected rcode %d, got %d", RcodeBadVers, m1.Rcode)
+	}
+	if e := m1.IsEdns0().ExtendedRcode(); e != RcodeBadVers>>4 {
+		t.Errorf("expected extended rcode %d, got %d", RcodeBadVers>>4, e)
+	}
+}
+
+func TestExtendedRcodeRoundTrip(t *testing.T) {
+	for _, rcode := range []int{RcodeSuccess, RcodeNameError, RcodeBadVers, RcodeBadKey, RcodeBadCookie, 0xFFF} {
+		m := new(Msg)
+		m.SetQuestion("miek.nl.", TypeSOA)
+		m.Response = true
+		m.Rcode = rcode
+		m.SetEdns0(4096, false)
+		buf, err := m.Pack()
+		if err != nil {
+			t.Fatalf("rcode %d: %v", rcode, err)
+		}
+		if r := int(buf[3] & 0xF); r != rcode&0xF {
+			t.Errorf("rcode %d: expected %d in the header, got %d", rcode, rcode&0xF, r)
+		}
+		m1 := new(Msg)
+		if err := m1.Unpack(buf); err != nil {
+			t.Fatalf("rcode %d: %v", rcode, err)
+		}
+		if m1.Rcode != rcode {
+			t.Errorf("rcode %d: got %d after unpack", rcode, m1.Rcode)
+		}
+		if e := m1.IsEdns0().ExtendedRcode(); e != rcode>>4 {
+			t.Errorf("rcode %d: expected extended rcode %d, got %d", rcode, rcode>>4, e)
+		}
+	}
+}
+
+func TestMsgUDPSize(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeA)
+	if s := m.UDPSize(); s != MinMsgSize {
+		t.Errorf("expected %d without OPT record, got %d", MinMsgSize, s)
+	}
+	for advertised, want := range map[uint16]uint16{1232: 1232, 65000: DefaultMsgSize, 100: MinMsgSize, 0: MinMsgSize} {
+		m := new(Msg)
+		m.SetQuestion("miek.nl.", TypeA)
+		m.SetEdns0(advertised, false)
+		if s := m.UDPSize(); s != want {
+			t.Errorf("expected %d for advertised size %d, got %d", want, advertised, s)
+		}
 	}
 }
@@ -0,0 +1,17 @@
+// +build fuzz
+
+package dns
+
+// Fuzz is the entry point for go-fuzz (github.com/dvyukov/go-fuzz): data is unpacked
+// and, when that succeeds, packed again. Neither may panic. The seed corpus, written
+// by fuzz_generate.go, is in testdata/fuzz/corpus; run go-fuzz with -workdir=testdata/fuzz.
+func Fuzz(data []byte) int {
+	m := new(Msg)
+	if err := m.Unpack(data); err != nil {
+		return 0
+	}
+	if _, err := m.Pack(); err != nil {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,90 @@
+//+build ignore
+
+// fuzz_generate.go is meant to run with go generate. It packs messages with many
+// different RR types and writes them, whole and truncated, to testdata/fuzz/corpus.
+// This is the seed corpus for go-fuzz (use -workdir=testdata/fuzz) and for
+// TestFuzzUnpack, and is meant to be checked into git.
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/miekg/dns"
+)
+
+const corpusDir = "testdata/fuzz/corpus"
+
+func newRR(s string) dns.RR {
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		log.Fatalf("failed to parse %q: %v", s, err)
+	}
+	return rr
+}
+
+func main() {
+	q := new(dns.Msg)
+	q.SetQuestion("www.example.org.", dns.TypeA)
+	q.SetEdns0(4096, true)
+
+	r := new(dns.Msg)
+	r.SetReply(q)
+	for _, s := range []string{
+		"www.example.org. 3600 IN A 192.0.2.1",
+		"www.example.org. 3600 IN AAAA 2001:db8::1",
+		"www.example.org. 3600 IN TXT \"v=spf1 -all\" \"second\"",
+		"www.example.org. 3600 IN MX 10 mail.example.org.",
+		"www.example.org. 3600 IN CNAME host.example.org.",
+		"www.example.org. 3600 IN SRV 10 20 5060 sip.example.org.",
+		"www.example.org. 3600 IN NAPTR 100 10 \"U\" \"E2U+sip\" \"!^.*$!sip:info@example.org!\" .",
+		"www.example.org. 3600 IN HINFO \"PC\" \"Linux\"",
+		"www.example.org. 3600 IN LOC 51 30 12.748 N 00 07 39.611 W 0.00m 0.00m 0.00m 0.00m",
+		"www.example.org. 3600 IN CAA 0 issue \"ca.example.net\"",
+		"www.example.org. 3600 IN SSHFP 1 1 dd465c09cfa51fb45020cc83316fff21b9ec74ac",
+		"www.example.org. 3600 IN RRSIG A 8 3 3600 20170101000000 20160101000000 12345 example.org. AwEAAQ==",
+		"www.example.org. 3600 IN NSEC host.example.org. A AAAA RRSIG NSEC TYPE65534",
+	} {
+		r.Answer = append(r.Answer, newRR(s))
+	}
+	r.Ns = []dns.RR{
+		newRR("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 300"),
+		newRR("example.org. 3600 IN NS ns.example.org."),
+		newRR("sk4e8fj94u78smusb40o1n0oltbblu2r.example.org. 3600 IN NSEC3 1 1 5 F10E9F7EA83FC8F3 SK4F38CQ0ATIEI8MH3RGD0P5I4II6QAN NS SOA"),
+		newRR("example.org. 3600 IN DS 12051 8 1 b5121bdb5b8d86d0cc5ffafbaaabe26c3e20bac1"),
+	}
+	r.Extra = []dns.RR{
+		newRR("ns.example.org. 3600 IN A 192.0.2.53"),
+		newRR("example.org. 3600 IN DNSKEY 256 3 8 AwEAAcNEU67LJI5GEgF9QLNqLO1SMq1EdoQ6E9f85ha0k0ewQGCblyW2836GiVsm6k8Kr5ECIoMJ6fZWf3CQSQ9ycWfTyOHfmI3eQ/1Covhb2y4bAmL/07PhrL7ozWBW3wBfM335Ft9xjtXHPy7ztCbV9qZ4TVDTW/Iyg0PiwgoXVesz"),
+	}
+	opt := &dns.OPT{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT}}
+	opt.SetUDPSize(4096)
+	opt.Option = []dns.EDNS0{&dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 24, Address: net.IPv4(192, 0, 2, 0).To4()}, &dns.EDNS0_NSID{Code: dns.EDNS0NSID, Nsid: "6e73"}}
+	r.Extra = append(r.Extra, opt)
+	r.Compress = true
+
+	if err := os.MkdirAll(corpusDir, 0755); err != nil {
+		log.Fatal(err)
+	}
+	for name, m := range map[string]*dns.Msg{"query": q, "reply": r} {
+		buf, err := m.Pack()
+		if err != nil {
+			log.Fatalf("failed to pack %s: %v", name, err)
+		}
+		write(name, buf)
+		// Truncated in the header, the question section and the middle of the RRs.
+		for _, n := range []int{6, 20, len(buf) / 2, len(buf) - 1} {
+			write(fmt.Sprintf("%s-%d", name, n), buf[:n])
+		}
+	}
+}
+
+func write(name string, buf []byte) {
+	if err := ioutil.WriteFile(filepath.Join(corpusDir, name), buf, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -1,6 +1,15 @@
 package dns
 
-import "testing"
+//go:generate go run fuzz_generate.go
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+const fuzzCorpusDir = "testdata/fuzz/corpus"
 
 func TestFuzzString(t *testing.T) {
 	testcases := []string{"", " MINFO ", "	RP ", "	NSEC 0 0", "	\" NSEC 0 0\"", "  \" MINFO \"",
@@ -23,3 +32,64 @@ func TestFuzzString(t *testing.T) {
 		}
 	}
 }
+
+// fuzzCorpus returns the messages in testdata/fuzz/corpus, with many different RR
+// types, the seed corpus for TestFuzzUnpack and for go-fuzz.
+func fuzzCorpus(t *testing.T) [][]byte {
+	files, err := ioutil.ReadDir(fuzzCorpusDir)
+	if err != nil {
+		t.Fatalf("failed to read the fuzz corpus: %v", err)
+	}
+	var corpus [][]byte
+	for _, f := range files {
+		buf, err := ioutil.ReadFile(filepath.Join(fuzzCorpusDir, f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		corpus = append(corpus, buf)
+	}
+	if len(corpus) == 0 {
+		t.Fatal("empty fuzz corpus")
+	}
+	return corpus
+}
+
+// TestFuzzUnpack unpacks truncated, mutated and random messages; unpacking, and
+// packing what was unpacked, must return an error rather than panic.
+func TestFuzzUnpack(t *testing.T) {
+	check := func(data []byte) {
+		defer func() {
+			if p := recover(); p != nil {
+				t.Fatalf("panic for message %x: %v", data, p)
+			}
+		}()
+		m := new(Msg)
+		if m.Unpack(data) == nil {
+			m.Pack()
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	n := 2000
+	if testing.Short() {
+		n = 200
+	}
+	for _, msg := range fuzzCorpus(t) {
+		for i := 0; i <= len(msg); i++ {
+			check(msg[:i])
+		}
+		for i := 0; i < n && len(msg) > 0; i++ {
+			data := append([]byte(nil), msg...)
+			for j := rnd.Intn(8); j >= 0; j-- {
+				data[rnd.Intn(len(data))] = byte(rnd.Intn(256))
+			}
+			check(data)
+			check(data[:rnd.Intn(len(data))])
+		}
+	}
+	for i := 0; i < n; i++ {
+		data := make([]byte, rnd.Intn(512))
+		rnd.Read(data)
+		check(data)
+	}
+}
@@ -3,6 +3,7 @@ package idn
 
 import (
 	"bytes"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -76,11 +77,22 @@ func FromPunycode(s string) string {
 		tokens = append(tokens, "")
 	}
 	for i := range tokens {
-		tokens[i] = string(decode([]byte(tokens[i])))
+		// A label that isn't valid punycode is left as is.
+		if t, err := decode([]byte(tokens[i])); err == nil {
+			tokens[i] = string(t)
+		}
 	}
 	return strings.Join(tokens, ".")
 }
 
+// CorruptInputError is returned for punycode that can't be decoded. Its value is the
+// offset of the offending byte in the input.
+type CorruptInputError int64
+
+func (e CorruptInputError) Error() string {
+	return "idn: illegal punycode data at input byte " + strconv.FormatInt(int64(e), 10)
+}
+
 // digitval converts single byte into meaningful value that's used to calculate decoded unicode character.
 const errdigit = 0xffff
 
@@ -276,12 +288,14 @@ func encode(input []byte) []byte {
 }
 
 // decode transforms punycode input bytes (that represent DNS label) into Unicode bytestream.
-func decode(b []byte) []byte {
+// Input without the "xn--" prefix is returned as is. For invalid punycode a
+// CorruptInputError with the offset in b is returned.
+func decode(b []byte) ([]byte, error) {
 	src := b // b would move and we need to keep it
 
 	n, bias := _N, _BIAS
 	if !bytes.HasPrefix(b, []byte(_PREFIX)) {
-		return b
+		return b, nil
 	}
 	out := make([]rune, 0, len(b))
 	b = b[len(_PREFIX):]
@@ -294,7 +308,7 @@ func decode(b []byte) []byte {
 		}
 	}
 	if len(b) == 0 {
-		return src
+		return nil, CorruptInputError(len(src))
 	}
 	var (
 		i, oldi, w rune
@@ -305,16 +319,20 @@ func decode(b []byte) []byte {
 
 	for i = 0; len(b) > 0; i++ {
 		oldi, w = i, 1
-		for k := _BASE; len(b) > 0; k += _BASE {
+		for k := _BASE; ; k += _BASE {
+			if len(b) == 0 {
+				// the last variable-length integer isn't complete
+				return nil, CorruptInputError(len(src))
+			}
 			ch, b = b[0], b[1:]
 			digit = digitval(rune(ch))
 			if digit == errdigit {
-				return src
+				return nil, CorruptInputError(len(src) - len(b) - 1)
 			}
 			i += digit * w
 			if i < 0 {
 				// safety check for rune overflow
-				return src
+				return nil, CorruptInputError(len(src) - len(b) - 1)
 			}
 
 			t = tfunc(k, bias)
@@ -327,6 +345,9 @@ func decode(b []byte) []byte {
 		ln = len(out) + 1
 		bias = adapt(i-oldi, ln, oldi == 0)
 		n += i / rune(ln)
+		if n < 0 || n > utf8.MaxRune {
+			return nil, CorruptInputError(len(src) - len(b) - 1)
+		}
 		i = i % rune(ln)
 		// insert
 		out = append(out, 0)
@@ -338,7 +359,7 @@ func decode(b []byte) []byte {
 	for _, r := range out {
 		ret.WriteRune(r)
 	}
-	return ret.Bytes()
+	return ret.Bytes(), nil
 }
 
 // isValidRune checks if the character is valid. We will look for the
@@ -36,7 +36,10 @@ func TestEncodeDecodePunycode(t *testing.T) {
 		if string(enc) != tst[1] {
 			t.Errorf("%s encodeded as %s but should be %s", tst[0], enc, tst[1])
 		}
-		dec := decode([]byte(tst[1]))
+		dec, err := decode([]byte(tst[1]))
+		if err != nil {
+			t.Errorf("failed to decode %s: %v", tst[1], err)
+		}
 		if string(dec) != strings.ToLower(tst[0]) {
 			t.Errorf("%s decoded as %s but should be %s", tst[1], dec, strings.ToLower(tst[0]))
 		}
@@ -98,6 +101,33 @@ func TestInvalidPunycode(t *testing.T) {
 	}
 }
 
+func TestDecodeCorruptInput(t *testing.T) {
+	for _, tc := range []struct {
+		in     string
+		offset int64
+	}{
+		{"xn--*", 4},           // not a base36 digit
+		{"xn--", 4},            // nothing to decode
+		{"xn---", 5},           // nothing after the delimiter
+		{"xn--abc-9!", 9},      // not a base36 digit after the basic code points
+		{"xn--9", 5},           // incomplete integer
+		{"xn--a000000000", 13}, // code point overflow
+	} {
+		_, err := decode([]byte(tc.in))
+		e, ok := err.(CorruptInputError)
+		if !ok {
+			t.Errorf("%s: expected a CorruptInputError, got %v", tc.in, err)
+			continue
+		}
+		if int64(e) != tc.offset {
+			t.Errorf("%s: expected offset %d, got %d", tc.in, tc.offset, e)
+		}
+	}
+	if s := CorruptInputError(7).Error(); s != "idn: illegal punycode data at input byte 7" {
+		t.Errorf("unexpected error string %q", s)
+	}
+}
+
 // You can verify the labels that are valid or not comparing to the Verisign
 // website: http://mct.verisign-grs.com/
 var invalidUnicodes = []string{
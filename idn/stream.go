@@ -0,0 +1,139 @@
+package idn
+
+import (
+	"errors"
+	"io"
+)
+
+// errInvalidLabel is returned by the encoder for a label with an invalid character.
+var errInvalidLabel = errors.New("idn: invalid character in label")
+
+// NewEncoder returns a new punycode stream encoder. Data written to the returned
+// writer will be encoded and written to w. Each label, the data up to a '.' or
+// white space, such as the newline after a name, is encoded on its own and labels
+// with only ASCII characters are written unchanged. Callers must Close the writer
+// to flush the last label; Close does not close w.
+func NewEncoder(w io.Writer) io.WriteCloser {
+	return &encoder{w: w}
+}
+
+type encoder struct {
+	w     io.Writer
+	label []byte // the label seen so far, this may end in the middle of a rune
+	err   error
+}
+
+func (e *encoder) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	for i, b := range p {
+		if !isDelimiter(b) {
+			e.label = append(e.label, b)
+			continue
+		}
+		// A delimiter can't be part of a multibyte rune, so the label is complete.
+		if e.err = e.flush(); e.err == nil {
+			_, e.err = e.w.Write([]byte{b})
+		}
+		if e.err != nil {
+			return i, e.err
+		}
+	}
+	return len(p), nil
+}
+
+// Close flushes the last label to the underlying writer.
+func (e *encoder) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	e.err = e.flush()
+	return e.err
+}
+
+// flush encodes the buffered label, writes it and empties the buffer.
+func (e *encoder) flush() error {
+	if len(e.label) == 0 {
+		return nil
+	}
+	out := e.label
+	if needToPunycode(string(e.label)) {
+		if out = encode(e.label); out == nil {
+			return errInvalidLabel
+		}
+	}
+	e.label = e.label[:0]
+	_, err := e.w.Write(out)
+	return err
+}
+
+// NewDecoder returns a new punycode stream decoder. Data read from the returned
+// reader is read from r and each label in it, the data up to a '.' or white space,
+// that starts with "xn--" is decoded to UTF-8. A label that isn't valid punycode
+// gives a CorruptInputError with the offset of the offending byte in the stream.
+func NewDecoder(r io.Reader) io.Reader {
+	return &decoder{r: r}
+}
+
+type decoder struct {
+	r     io.Reader
+	label []byte // the label read so far, it may continue in the next read from r
+	start int64  // offset of the label in the stream
+	out   []byte // decoded data not yet returned
+	buf   [512]byte
+	err   error
+}
+
+func (d *decoder) Read(p []byte) (int, error) {
+	for len(d.out) == 0 && d.err == nil {
+		n, err := d.r.Read(d.buf[:])
+		for _, b := range d.buf[:n] {
+			if !isDelimiter(b) {
+				d.label = append(d.label, b)
+				continue
+			}
+			if d.err = d.flush(); d.err != nil {
+				break
+			}
+			d.out = append(d.out, b)
+			d.start++
+		}
+		if err != nil && d.err == nil {
+			// The last label need not be followed by a delimiter.
+			if d.err = d.flush(); d.err == nil {
+				d.err = err
+			}
+		}
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	if len(d.out) == 0 && d.err != nil {
+		return n, d.err
+	}
+	return n, nil
+}
+
+// flush decodes the buffered label to the output and empties the buffer.
+func (d *decoder) flush() error {
+	if len(d.label) == 0 {
+		return nil
+	}
+	out, err := decode(d.label)
+	if err != nil {
+		return CorruptInputError(d.start + int64(err.(CorruptInputError)))
+	}
+	d.out = append(d.out, out...)
+	d.start += int64(len(d.label))
+	d.label = d.label[:0]
+	return nil
+}
+
+// isDelimiter returns true for the bytes that end a label in a stream.
+func isDelimiter(b byte) bool {
+	switch b {
+	case '.', ' ', '\t', '\n', '\r':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package idn
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestEncoder(t *testing.T) {
+	for _, tc := range []struct {
+		in  string
+		out string
+	}{
+		{"", ""},
+		{".", "."},
+		{"Example.ORG.", "Example.ORG."},
+		{"测试.example.", "xn--0zwm56d.example."},
+		{"mamão-com-açúcar.example.я", "xn--mamo-com-acar-yeb1e6q.example.xn--41a"},
+	} {
+		var buf bytes.Buffer
+		e := NewEncoder(&buf)
+		if _, err := e.Write([]byte(tc.in)); err != nil {
+			t.Fatalf("failed to write %q: %v", tc.in, err)
+		}
+		if err := e.Close(); err != nil {
+			t.Fatalf("failed to close after %q: %v", tc.in, err)
+		}
+		if buf.String() != tc.out {
+			t.Errorf("%q encoded as %q but should be %q", tc.in, buf.String(), tc.out)
+		}
+	}
+
+	// Write the data one byte at a time, splitting every multibyte rune.
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	for _, b := range []byte("испытание.テスト") {
+		if _, err := e.Write([]byte{b}); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+	if buf.String() != "xn--80akhbyknj4f." {
+		t.Errorf("expected only the first label before Close, got %q", buf.String())
+	}
+	e.Close()
+	if buf.String() != "xn--80akhbyknj4f.xn--zckzah" {
+		t.Errorf("expected xn--80akhbyknj4f.xn--zckzah, got %q", buf.String())
+	}
+
+	e = NewEncoder(new(bytes.Buffer))
+	if _, err := e.Write([]byte("Испытание.example.")); err == nil {
+		t.Error("expected error for an invalid label")
+	}
+	if err := e.Close(); err == nil {
+		t.Error("expected the error to stick")
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	// The source returns one byte per read, so every label spans reads.
+	in := "xn--80akhbyknj4f.example.\nxn--zckzah.XN--ZCKZAH"
+	out, err := ioutil.ReadAll(NewDecoder(iotest.OneByteReader(strings.NewReader(in))))
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	expected := "испытание.example.\nテスト.XN--ZCKZAH"
+	if string(out) != expected {
+		t.Errorf("%q decoded as %q but should be %q", in, out, expected)
+	}
+
+	// The labels before the corrupt one are returned, the offset is in the stream.
+	in = "xn--zckzah.example.xn--abc-9!.org."
+	out, err = ioutil.ReadAll(NewDecoder(strings.NewReader(in)))
+	if e, ok := err.(CorruptInputError); !ok || e != 28 {
+		t.Errorf("expected CorruptInputError at 28, got %v", err)
+	}
+	if string(out) != "テスト.example." {
+		t.Errorf("expected the labels before the corrupt one, got %q", out)
+	}
+}
+
+func TestEncoderDecoder(t *testing.T) {
+	names := "mamão-com-açúcar.example.\n测试.example.org\nexample.я"
+	pr, pw := io.Pipe()
+	go func() {
+		e := NewEncoder(pw)
+		for _, s := range strings.SplitAfter(names, "o") {
+			e.Write([]byte(s))
+		}
+		pw.CloseWithError(e.Close())
+	}()
+	out, err := ioutil.ReadAll(NewDecoder(pr))
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if string(out) != names {
+		t.Errorf("expected %q after a round trip, got %q", names, out)
+	}
+}
@@ -1,5 +1,7 @@
 package dns
 
+import "strings"
+
 // Holds a bunch of helper functions for dealing with labels.
 
 // SplitDomainName splits a name string into it's labels.
@@ -105,6 +107,76 @@ func CountLabel(s string) (labels int) {
 	}
 }
 
+// countSigLabels counts the number of labels in the string s as they are counted in the
+// Labels field of an RRSIG record (RFC 4034, section 3.1.3): the root label and a
+// leading wildcard label are not counted. An escaped "\\*" is not a wildcard.
+// s must be a syntactically valid domain name.
+func countSigLabels(s string) int {
+	labels := CountLabel(s)
+	if s == "*" || strings.HasPrefix(s, "*.") {
+		labels--
+	}
+	return labels
+}
+
+// EscapeName returns the presentation format of the domain name made up of labels.
+// The labels hold the raw octets of each label: dots, backslashes, spaces and other
+// special or unprintable octets are escaped. The name is fully qualified; no labels
+// returns the root name.
+func EscapeName(labels []string) string {
+	if len(labels) == 0 {
+		return "."
+	}
+	var s []byte
+	for _, l := range labels {
+		for i := 0; i < len(l); i++ {
+			s = appendDomainNameByte(s, l[i])
+		}
+		s = append(s, '.')
+	}
+	return string(s)
+}
+
+// UnescapeName is the reverse of EscapeName: it splits the domain name s in
+// presentation format into its labels and removes the escapes (\., \\ and \DDD)
+// from them. The root name returns no labels.
+func UnescapeName(s string) ([]string, error) {
+	if s == "" {
+		return nil, &Error{err: "empty name"}
+	}
+	if s == "." {
+		return nil, nil
+	}
+	var (
+		labels []string
+		label  []byte
+	)
+	b := []byte(s)
+	for i := 0; i < len(b); {
+		if b[i] == '.' {
+			if len(label) == 0 {
+				return nil, &Error{err: "empty label in name: " + s}
+			}
+			labels = append(labels, string(label))
+			label = label[:0]
+			i++
+			continue
+		}
+		c, n := nextByte(b, i)
+		if n == 0 {
+			return nil, &Error{err: "dangling escape in name: " + s}
+		}
+		if label = append(label, c); len(label) > 63 {
+			return nil, &Error{err: "label too long in name: " + s}
+		}
+		i += n
+	}
+	if len(label) > 0 {
+		labels = append(labels, string(label))
+	}
+	return labels, nil
+}
+
 // Split splits a name s into its label indexes.
 // www.miek.nl. returns []int{0, 4, 9}, www.miek.nl also returns []int{0, 4, 9}.
 // The root name (.) returns nil. Also see SplitDomainName.
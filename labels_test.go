@@ -1,6 +1,10 @@
 package dns
 
-import "testing"
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestCompareDomainName(t *testing.T) {
 	s1 := "www.miek.nl."
@@ -121,6 +125,25 @@ func TestCountLabel(t *testing.T) {
 	}
 }
 
+func TestCountSigLabels(t *testing.T) {
+	splitter := map[string]int{
+		"*.example.org.":   2,
+		"a.b.example.org.": 4,
+		"a.*.example.org.": 4,
+		"*a.example.org.":  3,
+		"\\*.example.org.": 3,
+		"www\\.miek.nl.":   2,
+		"*.":               0,
+		".":                0,
+	}
+	for s, i := range splitter {
+		x := countSigLabels(s)
+		if x != i {
+			t.Errorf("countSigLabels should return %d for %s, got %d", i, s, x)
+		}
+	}
+}
+
 func TestSplitDomainName(t *testing.T) {
 	labels := map[string][]string{
 		"miek.nl":       {"miek", "nl"},
@@ -198,3 +221,58 @@ func BenchmarkIsSubDomain(b *testing.B) {
 		IsSubDomain("miek.nl", "aa.example.com")
 	}
 }
+
+func TestEscapeName(t *testing.T) {
+	tests := []struct {
+		labels []string
+		name   string
+	}{
+		{nil, "."},
+		{[]string{"www", "miek", "nl"}, "www.miek.nl."},
+		{[]string{"www.miek", "nl"}, `www\.miek.nl.`},
+		{[]string{`back\slash`, "nl"}, `back\\slash.nl.`},
+		{[]string{"with space", "nl"}, `with\ space.nl.`},
+		{[]string{"high\xff\x80", "nl"}, `high\255\128.nl.`},
+		{[]string{"null\x00", "nl"}, `null\000.nl.`},
+		{[]string{"tab\t", "nl"}, `tab\t.nl.`},
+		{[]string{"\"quoted\";(x)@", "nl"}, `\"quoted\"\;\(x\)\@.nl.`},
+	}
+	for _, tc := range tests {
+		name := EscapeName(tc.labels)
+		if name != tc.name {
+			t.Errorf("EscapeName(%q) = %s, expected %s", tc.labels, name, tc.name)
+		}
+		labels, err := UnescapeName(name)
+		if err != nil {
+			t.Errorf("UnescapeName(%s) failed: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(labels, tc.labels) {
+			t.Errorf("UnescapeName(%s) = %q, expected %q", name, labels, tc.labels)
+		}
+		// The escaped name must pack to the same labels.
+		buf := make([]byte, 256)
+		off, err := PackDomainName(name, buf, 0, nil, false)
+		if err != nil {
+			t.Errorf("failed to pack %s: %v", name, err)
+			continue
+		}
+		n, _, err := UnpackDomainName(buf[:off], 0)
+		if err != nil {
+			t.Errorf("failed to unpack %s: %v", name, err)
+			continue
+		}
+		if labels, _ := UnescapeName(n); !reflect.DeepEqual(labels, tc.labels) {
+			t.Errorf("%s packed and unpacked to %q, expected %q", name, labels, tc.labels)
+		}
+	}
+
+	if labels, err := UnescapeName(`www\046miek.nl`); err != nil || !reflect.DeepEqual(labels, []string{"www.miek", "nl"}) {
+		t.Errorf("expected labels of a name that is not fully qualified, got %q: %v", labels, err)
+	}
+	for _, name := range []string{"", "www..nl.", ".nl.", `www.nl\`, strings.Repeat("a", 64) + ".nl."} {
+		if _, err := UnescapeName(name); err == nil {
+			t.Errorf("expected an error for %q", name)
+		}
+	}
+}
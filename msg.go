@@ -32,7 +32,10 @@ func init() {
 	rand.Seed(int64(seed))
 }
 
-const maxCompressionOffset = 2 << 13 // We have 14 bits for the compression pointer
+const (
+	maxCompressionOffset    = 2 << 13 // We have 14 bits for the compression pointer
+	maxDomainNameWireOctets = 255     // See RFC 1035 section 2.3.4
+)
 
 var (
 	ErrAlg           error = &Error{err: "bad algorithm"}                  // ErrAlg indicates an error with the (DNSSEC) algorithm.
@@ -40,17 +43,21 @@ var (
 	ErrBuf           error = &Error{err: "buffer size too small"}          // ErrBuf indicates that the buffer used it too small for the message.
 	ErrConnEmpty     error = &Error{err: "conn has no connection"}         // ErrConnEmpty indicates a connection is being uses before it is initialized.
 	ErrExtendedRcode error = &Error{err: "bad extended rcode"}             // ErrExtendedRcode ...
+	ErrFmt           error = &Error{err: "format error"}                   // ErrFmt indicates that a message violates the protocol, i.e. it has more than one OPT RR.
 	ErrFqdn          error = &Error{err: "domain must be fully qualified"} // ErrFqdn indicates that a domain name does not have a closing dot.
 	ErrId            error = &Error{err: "id mismatch"}                    // ErrId indicates there is a mismatch with the message's ID.
 	ErrKeyAlg        error = &Error{err: "bad key algorithm"}              // ErrKeyAlg indicates that the algorithm in the key is not valid.
 	ErrKey           error = &Error{err: "bad key"}
 	ErrKeySize       error = &Error{err: "bad key size"}
+	ErrLimit         error = &Error{err: "zone transfer limit exceeded"}    // ErrLimit indicates that an incoming transfer has more RRs or bytes than Transfer allows.
+	ErrLongDomain    error = &Error{err: "domain name exceeded 255 octets"} // ErrLongDomain indicates that an unpacked domain name is longer than RFC 1035 allows.
 	ErrNoSig         error = &Error{err: "no signature found"}
 	ErrPrivKey       error = &Error{err: "bad private key"}
 	ErrRcode         error = &Error{err: "bad rcode"}
 	ErrRdata         error = &Error{err: "bad rdata"}
 	ErrRRset         error = &Error{err: "bad rrset"}
 	ErrSecret        error = &Error{err: "no secrets defined"}
+	ErrServ          error = &Error{err: "no servers could be reached"} // ErrServ indicates that none of the servers queried replied.
 	ErrShortRead     error = &Error{err: "short read"}
 	ErrSig           error = &Error{err: "bad signature"}                      // ErrSig indicates that a signature can not be cryptographically validated.
 	ErrSoa           error = &Error{err: "no SOA"}                             // ErrSOA indicates that no SOA RR was seen when doing zone transfers.
@@ -73,6 +80,25 @@ func id() uint16 {
 	return uint16(id32)
 }
 
+// PackedId returns the message id of the packed message msg, without unpacking
+// it. If msg is too short to hold an id, 0 is returned.
+func PackedId(msg []byte) uint16 {
+	if len(msg) < 2 {
+		return 0
+	}
+	return binary.BigEndian.Uint16(msg)
+}
+
+// SetPackedId overwrites the message id of the packed message msg with id. This
+// is cheaper than an Unpack and Pack, i.e. when forwarding queries. If msg is too
+// short to hold an id, it is left untouched.
+func SetPackedId(msg []byte, id uint16) {
+	if len(msg) < 2 {
+		return
+	}
+	binary.BigEndian.PutUint16(msg, id)
+}
+
 // MsgHdr is a a manually-unpacked version of (id, bits).
 type MsgHdr struct {
 	Id                 uint16
@@ -91,11 +117,13 @@ type MsgHdr struct {
 // Msg contains the layout of a DNS message.
 type Msg struct {
 	MsgHdr
-	Compress bool       `json:"-"` // If true, the message will be compressed when converted to wire format.
-	Question []Question // Holds the RR(s) of the question section.
-	Answer   []RR       // Holds the RR(s) of the answer section.
-	Ns       []RR       // Holds the RR(s) of the authority section.
-	Extra    []RR       // Holds the RR(s) of the additional section.
+	Compress     bool       `json:"-"` // If true, the message will be compressed when converted to wire format.
+	AutoCompress bool       `json:"-"` // If true, the message will also be compressed when it is larger than MinMsgSize.
+	Question     []Question // Holds the RR(s) of the question section.
+	Answer       []RR       // Holds the RR(s) of the answer section.
+	Ns           []RR       // Holds the RR(s) of the authority section.
+	Extra        []RR       // Holds the RR(s) of the additional section.
+	Size         int        `json:"-"` // Number of octets in the message received from the wire, set by Unpack.
 }
 
 // ClassToString is a maps Classes to strings for each CLASS wire type.
@@ -248,9 +276,10 @@ func packDomainName(s string, msg []byte, off int, compression map[string]int, c
 			}
 			// Don't try to compress '.'
 			if compress && roBs[begin:] != "." {
-				if p, ok := compression[roBs[begin:]]; !ok {
-					// Only offsets smaller than this can be used.
-					if offset < maxCompressionOffset {
+				if p, ok := compression[roBs[begin:]]; !ok || p >= maxCompressionOffset {
+					// Only offsets smaller than this can be used. The map may
+					// come from the caller, so don't trust what is in it.
+					if !ok && offset < maxCompressionOffset {
 						compression[roBs[begin:]] = offset
 					}
 				} else {
@@ -284,7 +313,10 @@ func packDomainName(s string, msg []byte, off int, compression map[string]int, c
 		off = nameoffset + 1
 		goto End
 	}
-	if msg != nil && off < len(msg) {
+	if msg != nil {
+		if off >= len(msg) {
+			return lenmsg, labels, ErrBuf
+		}
 		msg[off] = 0
 	}
 End:
@@ -292,6 +324,15 @@ End:
 	return off, labels, nil
 }
 
+// domainNameLen returns the (uncompressed) length of s in wire format. The root
+// name is a single zero octet.
+func domainNameLen(s string) int {
+	if s == "." {
+		return 1
+	}
+	return len(s) + 1
+}
+
 // Unpack a domain name.
 // In addition to the simple sequences of counted strings above,
 // domain names are allowed to refer to strings elsewhere in the
@@ -305,13 +346,17 @@ End:
 // which is where the next record will start.
 // In theory, the pointers are only allowed to jump backward.
 // We let them jump anywhere and stop jumping after a while.
+// Labels can't be longer than 63 octets as the top two bits of the length
+// byte are taken, the name itself may not exceed 255 octets in wire format.
 
-// UnpackDomainName unpacks a domain name into a string.
+// UnpackDomainName unpacks a domain name into a string. It returns ErrLongDomain
+// when the name, with any pointers followed, is longer than 255 octets.
 func UnpackDomainName(msg []byte, off int) (string, int, error) {
 	s := make([]byte, 0, 64)
 	off1 := 0
 	lenmsg := len(msg)
-	ptr := 0 // number of pointers followed
+	ptr := 0                              // number of pointers followed
+	budget := maxDomainNameWireOctets - 1 // the root label takes the last octet
 Loop:
 	for {
 		if off >= lenmsg {
@@ -329,6 +374,9 @@ Loop:
 			if off+c > lenmsg {
 				return "", lenmsg, ErrBuf
 			}
+			if budget -= c + 1; budget < 0 {
+				return "", lenmsg, ErrLongDomain
+			}
 			for j := off; j < off+c; j++ {
 				switch b := msg[j]; b {
 				case '.', '(', ')', ';', ' ', '@':
@@ -391,10 +439,6 @@ Loop:
 
 func packTxt(txt []string, msg []byte, offset int, tmp []byte) (int, error) {
 	if len(txt) == 0 {
-		if offset >= len(msg) {
-			return offset, ErrBuf
-		}
-		msg[offset] = 0
 		return offset, nil
 	}
 	var err error
@@ -454,7 +498,7 @@ func packTxtString(s string, msg []byte, offset int, tmp []byte) (int, error) {
 }
 
 func packOctetString(s string, msg []byte, offset int, tmp []byte) (int, error) {
-	if offset >= len(msg) || len(s) > len(tmp) {
+	if offset > len(msg) || len(s) > len(tmp) {
 		return offset, ErrBuf
 	}
 	bs := tmp[:len(s)]
@@ -620,6 +664,9 @@ func unpackRRslice(l int, msg []byte, off int) (dst1 []RR, off1 int, err error)
 //;; opcode: QUERY, status: NOERROR, id: 48404
 //
 //;; flags: qr aa rd ra;
+//
+// A header without flags set has ";; flags:;", as dig prints it. The section
+// counts are not part of the header, Msg.String adds them to the flags line.
 func (h *MsgHdr) String() string {
 	if h == nil {
 		return "<nil> MsgHdr"
@@ -661,115 +708,158 @@ func (h *MsgHdr) String() string {
 
 // Pack packs a Msg: it is converted to to wire format.
 // If the dns.Compress is true the message will be in compressed wire format.
+// If dns.AutoCompress is true a message larger than MinMsgSize is compressed too.
 func (dns *Msg) Pack() (msg []byte, err error) {
 	return dns.PackBuffer(nil)
 }
 
 // PackBuffer packs a Msg, using the given buffer buf. If buf is too small
-// a new buffer is allocated.
+// a new buffer is allocated. The additional section is packed in the order
+// Normalize puts it in, dns.Extra itself is left alone.
 func (dns *Msg) PackBuffer(buf []byte) (msg []byte, err error) {
 	// We use a similar function in tsig.go's stripTsig.
-	var (
-		dh          Header
-		compression map[string]int
-	)
-
-	if dns.Compress {
-		compression = make(map[string]int) // Compression pointer mappings
-	}
+	var dh Header
 
 	if dns.Rcode < 0 || dns.Rcode > 0xFFF {
 		return nil, ErrRcode
 	}
 	if dns.Rcode > 0xF {
-		// Regular RCODE field is 4 bits
+		// Regular RCODE field is 4 bits, the upper 8 bits go in the OPT record.
 		opt := dns.IsEdns0()
 		if opt == nil {
 			return nil, ErrExtendedRcode
 		}
-		opt.SetExtendedRcode(uint8(dns.Rcode >> 4))
-		dns.Rcode &= 0xF
+		opt.Hdr.Ttl = opt.Hdr.Ttl&0x00FFFFFF | uint32(dns.Rcode>>4)<<24
 	}
 
 	// Convert convenient Msg into wire-like Header.
 	dh.Id = dns.Id
-	dh.Bits = uint16(dns.Opcode)<<11 | uint16(dns.Rcode)
-	if dns.Response {
-		dh.Bits |= _QR
-	}
-	if dns.Authoritative {
-		dh.Bits |= _AA
-	}
-	if dns.Truncated {
-		dh.Bits |= _TC
-	}
-	if dns.RecursionDesired {
-		dh.Bits |= _RD
-	}
-	if dns.RecursionAvailable {
-		dh.Bits |= _RA
-	}
-	if dns.Zero {
-		dh.Bits |= _Z
+	dh.Bits = dns.bits()
+
+	dh.Qdcount = uint16(len(dns.Question))
+	dh.Ancount = uint16(len(dns.Answer))
+	dh.Nscount = uint16(len(dns.Ns))
+	dh.Arcount = uint16(len(dns.Extra))
+
+	// When the choice to compress is made by the caller we can pack straight into buf,
+	// only when that fails we need the uncompressed length to allocate a large enough buffer.
+	// Otherwise the uncompressed length decides if the message is compressed.
+	msg = buf
+	if explicit := dns.Compress || !dns.AutoCompress; explicit && len(msg) > 0 {
+		if off, err := dns.packSections(dh, msg, newCompressionMap(dns.Compress)); err == nil {
+			return msg[:off], nil
+		}
 	}
-	if dns.AuthenticatedData {
-		dh.Bits |= _AD
+	packLen := dns.packLen(false)
+	if len(msg) < packLen {
+		msg = make([]byte, packLen)
 	}
-	if dns.CheckingDisabled {
-		dh.Bits |= _CD
+	off, err := dns.packSections(dh, msg, newCompressionMap(dns.Compress || dns.autoCompress(packLen)))
+	if err != nil {
+		return nil, err
 	}
+	return msg[:off], nil
+}
 
-	// Prepare variable sized arrays.
-	question := dns.Question
-	answer := dns.Answer
-	ns := dns.Ns
-	extra := dns.Extra
+// newCompressionMap returns a compression map when compress is true and nil otherwise.
+func newCompressionMap(compress bool) map[string]int {
+	if !compress {
+		return nil
+	}
+	return make(map[string]int)
+}
 
-	dh.Qdcount = uint16(len(question))
-	dh.Ancount = uint16(len(answer))
-	dh.Nscount = uint16(len(ns))
-	dh.Arcount = uint16(len(extra))
+// CompressionMap returns the compression map Pack builds when it compresses dns: every
+// name, and every name suffix, that is written in full maps to its offset in the message.
+// Names that are compressed with a pointer to an earlier name are not in the map. It
+// returns nil when dns can't be packed.
+func CompressionMap(dns *Msg) map[string]int {
+	compression := make(map[string]int)
+	msg := make([]byte, dns.packLen(false))
+	if _, err := dns.packSections(Header{}, msg, compression); err != nil {
+		return nil
+	}
+	return compression
+}
 
-	// We need the uncompressed length here, because we first pack it and then compress it.
-	msg = buf
-	compress := dns.Compress
-	dns.Compress = false
-	if packLen := dns.Len() + 1; len(msg) < packLen {
-		msg = make([]byte, packLen)
-	}
-	dns.Compress = compress
+// packSections packs the header dh and the sections of the message into msg. Names
+// are compressed when compression is not nil.
+func (dns *Msg) packSections(dh Header, msg []byte, compression map[string]int) (int, error) {
+	compress := compression != nil
 
 	// Pack it in: header and then the pieces.
-	off := 0
-	off, err = dh.pack(msg, off, compression, dns.Compress)
+	off, err := dh.pack(msg, 0, compression, compress)
 	if err != nil {
-		return nil, err
+		return off, err
 	}
-	for i := 0; i < len(question); i++ {
-		off, err = question[i].pack(msg, off, compression, dns.Compress)
+	for i := 0; i < len(dns.Question); i++ {
+		off, err = dns.Question[i].pack(msg, off, compression, compress)
 		if err != nil {
-			return nil, err
+			return off, err
 		}
 	}
-	for i := 0; i < len(answer); i++ {
-		off, err = PackRR(answer[i], msg, off, compression, dns.Compress)
+	for i := 0; i < len(dns.Answer); i++ {
+		off, err = PackRR(dns.Answer[i], msg, off, compression, compress)
 		if err != nil {
-			return nil, err
+			return off, err
 		}
 	}
-	for i := 0; i < len(ns); i++ {
-		off, err = PackRR(ns[i], msg, off, compression, dns.Compress)
+	for i := 0; i < len(dns.Ns); i++ {
+		off, err = PackRR(dns.Ns[i], msg, off, compression, compress)
 		if err != nil {
-			return nil, err
+			return off, err
 		}
 	}
+	extra := normalizeExtra(dns.Extra)
 	for i := 0; i < len(extra); i++ {
-		off, err = PackRR(extra[i], msg, off, compression, dns.Compress)
+		off, err = PackRR(extra[i], msg, off, compression, compress)
 		if err != nil {
-			return nil, err
+			return off, err
 		}
 	}
-	return msg[:off], nil
+	return off, nil
+}
+
+// extraOrder returns the position of r in the additional section: the OPT RR comes
+// after the other RRs, a TSIG or SIG(0) comes last.
+func extraOrder(r RR) int {
+	switch x := r.(type) {
+	case *OPT:
+		return 1
+	case *TSIG:
+		return 2
+	case *SIG:
+		// A SIG covering an RRset stays where it is, only SIG(0) covers the message.
+		if x.TypeCovered == 0 {
+			return 2
+		}
+	}
+	return 0
+}
+
+// normalizeExtra returns the RRs of the additional section in the order they must be
+// packed, see extraOrder. The order is otherwise kept. When extra is already in order
+// it is returned as is, otherwise a new slice is returned.
+func normalizeExtra(extra []RR) []RR {
+	sorted := true
+	for i := 1; i < len(extra); i++ {
+		if extraOrder(extra[i-1]) > extraOrder(extra[i]) {
+			sorted = false
+			break
+		}
+	}
+	if sorted {
+		return extra
+	}
+	n := make([]RR, 0, len(extra))
+	for o := 0; o <= 2; o++ {
+		for _, r := range extra {
+			if extraOrder(r) == o {
+				n = append(n, r)
+			}
+		}
+	}
+	return n
 }
 
 // Unpack unpacks a binary message to a Msg structure.
@@ -781,21 +871,11 @@ func (dns *Msg) Unpack(msg []byte) (err error) {
 	if dh, off, err = unpackMsgHdr(msg, off); err != nil {
 		return err
 	}
+	dns.Size = len(msg)
 	if off == len(msg) {
 		return ErrTruncated
 	}
-
-	dns.Id = dh.Id
-	dns.Response = (dh.Bits & _QR) != 0
-	dns.Opcode = int(dh.Bits>>11) & 0xF
-	dns.Authoritative = (dh.Bits & _AA) != 0
-	dns.Truncated = (dh.Bits & _TC) != 0
-	dns.RecursionDesired = (dh.Bits & _RD) != 0
-	dns.RecursionAvailable = (dh.Bits & _RA) != 0
-	dns.Zero = (dh.Bits & _Z) != 0
-	dns.AuthenticatedData = (dh.Bits & _AD) != 0
-	dns.CheckingDisabled = (dh.Bits & _CD) != 0
-	dns.Rcode = int(dh.Bits & 0xF)
+	dns.setMsgHdr(dh)
 
 	// Optimistically use the count given to us in the header
 	dns.Question = make([]Question, 0, int(dh.Qdcount))
@@ -829,6 +909,7 @@ func (dns *Msg) Unpack(msg []byte) (err error) {
 	}
 	// The header counts might have been wrong so we need to update it
 	dh.Arcount = uint16(len(dns.Extra))
+	dns.setExtendedRcode()
 
 	if off != len(msg) {
 		// TODO(miek) make this an error?
@@ -842,6 +923,151 @@ func (dns *Msg) Unpack(msg []byte) (err error) {
 	return err
 }
 
+// setExtendedRcode adds the upper 8 bits of the rcode from the OPT RR, if there is
+// one, to the lower 4 bits in dns.Rcode.
+func (dns *Msg) setExtendedRcode() {
+	if opt := dns.IsEdns0(); opt != nil {
+		dns.Rcode |= opt.ExtendedRcode() << 4
+	}
+}
+
+// setMsgHdr sets the header of the message from the wire format header dh.
+func (h *MsgHdr) setMsgHdr(dh Header) {
+	h.Id = dh.Id
+	h.Response = (dh.Bits & _QR) != 0
+	h.Opcode = int(dh.Bits>>11) & 0xF
+	h.Authoritative = (dh.Bits & _AA) != 0
+	h.Truncated = (dh.Bits & _TC) != 0
+	h.RecursionDesired = (dh.Bits & _RD) != 0
+	h.RecursionAvailable = (dh.Bits & _RA) != 0
+	h.Zero = (dh.Bits & _Z) != 0
+	h.AuthenticatedData = (dh.Bits & _AD) != 0
+	h.CheckingDisabled = (dh.Bits & _CD) != 0
+	h.Rcode = int(dh.Bits & 0xF)
+}
+
+// bits returns the opcode, flags and rcode of the header as the second word of the
+// wire format header. Only the lower 4 bits of the rcode fit in it.
+func (h *MsgHdr) bits() uint16 {
+	bits := uint16(h.Opcode)<<11 | uint16(h.Rcode&0xF)
+	if h.Response {
+		bits |= _QR
+	}
+	if h.Authoritative {
+		bits |= _AA
+	}
+	if h.Truncated {
+		bits |= _TC
+	}
+	if h.RecursionDesired {
+		bits |= _RD
+	}
+	if h.RecursionAvailable {
+		bits |= _RA
+	}
+	if h.Zero {
+		bits |= _Z
+	}
+	if h.AuthenticatedData {
+		bits |= _AD
+	}
+	if h.CheckingDisabled {
+		bits |= _CD
+	}
+	return bits
+}
+
+// Pack returns the header in wire format, with all section counts set to zero. Only
+// the lower 4 bits of the rcode are packed, the upper bits go in the OPT RR.
+func (h *MsgHdr) Pack() [12]byte {
+	var buf [12]byte
+	binary.BigEndian.PutUint16(buf[0:], h.Id)
+	binary.BigEndian.PutUint16(buf[2:], h.bits())
+	return buf
+}
+
+// UnpackHeader unpacks the header at the start of msg, the section counts and the
+// rest of the message are ignored. This is cheaper than a full Unpack when only the
+// id or the flags are needed. Rcode has the lower 4 bits of the rcode only.
+func UnpackHeader(msg []byte) (MsgHdr, error) {
+	var h MsgHdr
+	dh, _, err := unpackMsgHdr(msg, 0)
+	if err != nil {
+		return h, err
+	}
+	h.setMsgHdr(dh)
+	return h, nil
+}
+
+// UnpackError is an error from UnpackLenient for a record in a section of the message.
+type UnpackError struct {
+	Section string // "question", "answer", "authority" or "additional"
+	Index   int    // Index of the record in the section, as counted in the header.
+	Err     error
+}
+
+func (e *UnpackError) Error() string {
+	s := e.Err.Error()
+	if err, ok := e.Err.(*Error); ok {
+		s = err.err
+	}
+	return "dns: " + e.Section + " record " + strconv.Itoa(e.Index) + ": " + s
+}
+
+// UnpackLenient unpacks msg like Unpack, but it does not stop at the first record that
+// can't be unpacked. Such a record is skipped, using the rdlength from its header, and
+// an *UnpackError for it is added to the returned errors. Unpacking does stop when the
+// message header, a question or the header of a record can't be read. The message holds
+// everything that could be unpacked. This is meant for diagnosing malformed messages.
+func UnpackLenient(msg []byte) (*Msg, []error) {
+	dns := new(Msg)
+	dh, off, err := unpackMsgHdr(msg, 0)
+	if err != nil {
+		return dns, []error{err}
+	}
+	dns.Size = len(msg)
+	dns.setMsgHdr(dh)
+
+	var errs []error
+	for i := 0; i < int(dh.Qdcount) && off < len(msg); i++ {
+		var q Question
+		if q, off, err = unpackQuestion(msg, off); err != nil {
+			return dns, append(errs, &UnpackError{"question", i, err})
+		}
+		dns.Question = append(dns.Question, q)
+	}
+	sections := []struct {
+		name  string
+		count uint16
+		rrs   *[]RR
+	}{
+		{"answer", dh.Ancount, &dns.Answer},
+		{"authority", dh.Nscount, &dns.Ns},
+		{"additional", dh.Arcount, &dns.Extra},
+	}
+	for _, s := range sections {
+		for i := 0; i < int(s.count) && off < len(msg); i++ {
+			h, rdStart, _, err := unpackHeader(msg, off)
+			if err != nil {
+				return dns, append(errs, &UnpackError{s.name, i, err})
+			}
+			end := rdStart + int(h.Rdlength)
+			if end > len(msg) {
+				return dns, append(errs, &UnpackError{s.name, i, ErrBuf})
+			}
+			r, _, err := UnpackRR(msg, off)
+			off = end
+			if err != nil {
+				errs = append(errs, &UnpackError{s.name, i, err})
+				continue
+			}
+			*s.rrs = append(*s.rrs, r)
+		}
+	}
+	dns.setExtendedRcode()
+	return dns, errs
+}
+
 // Convert a complete message to a string with dig-like output.
 func (dns *Msg) String() string {
 	if dns == nil {
@@ -889,82 +1115,84 @@ func (dns *Msg) String() string {
 // If dns.Compress is true compression it is taken into account. Len()
 // is provided to be a faster way to get the size of the resulting packet,
 // than packing it, measuring the size and discarding the buffer.
+// If dns.AutoCompress is true and the message is larger than MinMsgSize, the
+// compressed length is returned.
 func (dns *Msg) Len() int {
-	// We always return one more than needed.
+	l := dns.packLen(dns.Compress)
+	if dns.autoCompress(l) {
+		return dns.packLen(true)
+	}
+	return l
+}
+
+// autoCompress returns true when a message of uncompressed length l should be
+// compressed even though dns.Compress is false.
+func (dns *Msg) autoCompress(l int) bool {
+	return !dns.Compress && dns.AutoCompress && l > MinMsgSize
+}
+
+// packLen returns the length of the message in wire format, with or without compression.
+func (dns *Msg) packLen(compress bool) int {
 	l := 12 // Message header is always 12 bytes
 	var compression map[string]int
-	if dns.Compress {
+	if compress {
 		compression = make(map[string]int)
 	}
 	for i := 0; i < len(dns.Question); i++ {
-		l += dns.Question[i].len()
-		if dns.Compress {
-			compressionLenHelper(compression, dns.Question[i].Name)
-		}
-	}
-	for i := 0; i < len(dns.Answer); i++ {
-		if dns.Answer[i] == nil {
-			continue
-		}
-		l += dns.Answer[i].len()
-		if dns.Compress {
-			k, ok := compressionLenSearch(compression, dns.Answer[i].Header().Name)
-			if ok {
-				l += 1 - k
-			}
-			compressionLenHelper(compression, dns.Answer[i].Header().Name)
-			k, ok = compressionLenSearchType(compression, dns.Answer[i])
-			if ok {
-				l += 1 - k
-			}
-			compressionLenHelperType(compression, dns.Answer[i])
-		}
-	}
-	for i := 0; i < len(dns.Ns); i++ {
-		if dns.Ns[i] == nil {
-			continue
-		}
-		l += dns.Ns[i].len()
-		if dns.Compress {
-			k, ok := compressionLenSearch(compression, dns.Ns[i].Header().Name)
-			if ok {
-				l += 1 - k
-			}
-			compressionLenHelper(compression, dns.Ns[i].Header().Name)
-			k, ok = compressionLenSearchType(compression, dns.Ns[i])
-			if ok {
-				l += 1 - k
-			}
-			compressionLenHelperType(compression, dns.Ns[i])
+		if compress {
+			compressionLenHelper(compression, dns.Question[i].Name, l)
 		}
+		l += dns.Question[i].len()
 	}
-	for i := 0; i < len(dns.Extra); i++ {
-		if dns.Extra[i] == nil {
-			continue
-		}
-		l += dns.Extra[i].len()
-		if dns.Compress {
-			k, ok := compressionLenSearch(compression, dns.Extra[i].Header().Name)
-			if ok {
-				l += 1 - k
+	for _, section := range [][]RR{dns.Answer, dns.Ns, dns.Extra} {
+		for _, r := range section {
+			if r == nil {
+				continue
 			}
-			compressionLenHelper(compression, dns.Extra[i].Header().Name)
-			k, ok = compressionLenSearchType(compression, dns.Extra[i])
-			if ok {
-				l += 1 - k
+			if !compress {
+				l += r.len()
+				continue
 			}
-			compressionLenHelperType(compression, dns.Extra[i])
+			l = compressionLenRR(compression, r, l)
 		}
 	}
 	return l
 }
 
-// Put the parts of the name in the compression map.
-func compressionLenHelper(c map[string]int, s string) {
+// compressionLenRR returns the offset after r when r is packed, compressed, at offset l.
+// Like PackDomainName, the parts of names are only added to the compression map when they
+// start before maxCompressionOffset, as names beyond that can't be pointed to.
+func compressionLenRR(compression map[string]int, r RR, l int) int {
+	start := l
+	l += r.len()
+	k, ok := compressionLenSearch(compression, r.Header().Name)
+	if ok {
+		l += 1 - k
+	}
+	compressionLenHelper(compression, r.Header().Name, start)
+	rdata := l - (r.len() - r.Header().len())
+	k, ok = compressionLenSearchType(compression, r)
+	if ok {
+		l += 1 - k
+	}
+	compressionLenHelperType(compression, r, rdata)
+	return l
+}
+
+// Put the parts of the name, that is packed at offset off, in the compression map.
+// Like packDomainName only the parts that start before maxCompressionOffset are added.
+func compressionLenHelper(c map[string]int, s string, off int) {
+	if s == "" || s == "." { // the root label is never compressed
+		return
+	}
 	pref := ""
+	l := domainNameLen(s)
 	lbs := Split(s)
 	for j := len(lbs) - 1; j >= 0; j-- {
 		pref = s[lbs[j]:]
+		if off+l-domainNameLen(pref) >= maxCompressionOffset {
+			continue
+		}
 		if _, ok := c[pref]; !ok {
 			c[pref] = len(pref)
 		}
@@ -976,7 +1204,7 @@ func compressionLenHelper(c map[string]int, s string) {
 func compressionLenSearch(c map[string]int, s string) (int, bool) {
 	off := 0
 	end := false
-	if s == "" { // don't bork on bogus data
+	if s == "" || s == "." { // don't bork on bogus data, and don't compress the root label
 		return 0, false
 	}
 	for {
@@ -992,48 +1220,32 @@ func compressionLenSearch(c map[string]int, s string) (int, bool) {
 }
 
 // TODO(miek): should add all types, because the all can be *used* for compression. Autogenerate from msg_generate and put in zmsg.go
-func compressionLenHelperType(c map[string]int, r RR) {
+func compressionLenHelperType(c map[string]int, r RR, off int) {
 	switch x := r.(type) {
 	case *NS:
-		compressionLenHelper(c, x.Ns)
+		compressionLenHelper(c, x.Ns, off)
 	case *MX:
-		compressionLenHelper(c, x.Mx)
+		compressionLenHelper(c, x.Mx, off+2)
 	case *CNAME:
-		compressionLenHelper(c, x.Target)
+		compressionLenHelper(c, x.Target, off)
 	case *PTR:
-		compressionLenHelper(c, x.Ptr)
+		compressionLenHelper(c, x.Ptr, off)
 	case *SOA:
-		compressionLenHelper(c, x.Ns)
-		compressionLenHelper(c, x.Mbox)
+		compressionLenHelper(c, x.Ns, off)
+		compressionLenHelper(c, x.Mbox, off+domainNameLen(x.Ns))
 	case *MB:
-		compressionLenHelper(c, x.Mb)
+		compressionLenHelper(c, x.Mb, off)
 	case *MG:
-		compressionLenHelper(c, x.Mg)
+		compressionLenHelper(c, x.Mg, off)
 	case *MR:
-		compressionLenHelper(c, x.Mr)
+		compressionLenHelper(c, x.Mr, off)
 	case *MF:
-		compressionLenHelper(c, x.Mf)
+		compressionLenHelper(c, x.Mf, off)
 	case *MD:
-		compressionLenHelper(c, x.Md)
-	case *RT:
-		compressionLenHelper(c, x.Host)
-	case *RP:
-		compressionLenHelper(c, x.Mbox)
-		compressionLenHelper(c, x.Txt)
+		compressionLenHelper(c, x.Md, off)
 	case *MINFO:
-		compressionLenHelper(c, x.Rmail)
-		compressionLenHelper(c, x.Email)
-	case *AFSDB:
-		compressionLenHelper(c, x.Hostname)
-	case *SRV:
-		compressionLenHelper(c, x.Target)
-	case *NAPTR:
-		compressionLenHelper(c, x.Replacement)
-	case *RRSIG:
-		compressionLenHelper(c, x.SignerName)
-	case *NSEC:
-		compressionLenHelper(c, x.NextDomain)
-		// HIP?
+		compressionLenHelper(c, x.Rmail, off)
+		compressionLenHelper(c, x.Email, off+domainNameLen(x.Rmail))
 	}
 }
 
@@ -1046,8 +1258,6 @@ func compressionLenSearchType(c map[string]int, r RR) (int, bool) {
 		return compressionLenSearch(c, x.Mx)
 	case *CNAME:
 		return compressionLenSearch(c, x.Target)
-	case *DNAME:
-		return compressionLenSearch(c, x.Target)
 	case *PTR:
 		return compressionLenSearch(c, x.Ptr)
 	case *SOA:
@@ -1056,6 +1266,9 @@ func compressionLenSearchType(c map[string]int, r RR) (int, bool) {
 		if !ok && !ok1 {
 			return 0, false
 		}
+		if ok && ok1 {
+			return k + k1 - 1, true // the caller accounts for a single pointer only
+		}
 		return k + k1, true
 	case *MB:
 		return compressionLenSearch(c, x.Mb)
@@ -1067,17 +1280,16 @@ func compressionLenSearchType(c map[string]int, r RR) (int, bool) {
 		return compressionLenSearch(c, x.Mf)
 	case *MD:
 		return compressionLenSearch(c, x.Md)
-	case *RT:
-		return compressionLenSearch(c, x.Host)
 	case *MINFO:
 		k, ok := compressionLenSearch(c, x.Rmail)
 		k1, ok1 := compressionLenSearch(c, x.Email)
 		if !ok && !ok1 {
 			return 0, false
 		}
+		if ok && ok1 {
+			return k + k1 - 1, true // the caller accounts for a single pointer only
+		}
 		return k + k1, true
-	case *AFSDB:
-		return compressionLenSearch(c, x.Hostname)
 	}
 	return 0, false
 }
@@ -1095,6 +1307,8 @@ func (dns *Msg) Copy() *Msg { return dns.CopyTo(new(Msg)) }
 func (dns *Msg) CopyTo(r1 *Msg) *Msg {
 	r1.MsgHdr = dns.MsgHdr
 	r1.Compress = dns.Compress
+	r1.AutoCompress = dns.AutoCompress
+	r1.Size = dns.Size
 
 	if len(dns.Question) > 0 {
 		r1.Question = make([]Question, len(dns.Question))
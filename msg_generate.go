@@ -25,6 +25,21 @@ package dns
 
 `
 
+// legacyCompressed holds the types that are not defined in RFC 1035 but whose
+// names in the rdata may be compressed, see RFC 3597, section 4, and RFC 6672,
+// section 2.5 for DNAME. Names tagged "domain-name" in other types are unpacked
+// with unpackDomainNameUncompressed.
+var legacyCompressed = map[string]bool{
+	"AFSDB": true,
+	"DNAME": true,
+	"NAPTR": true,
+	"PX":    true,
+	"RP":    true,
+	"RT":    true,
+	"SIG":   true,
+	"SRV":   true,
+}
+
 // getTypeStruct will take a type and the package scope, and return the
 // (innermost) struct if the type is considered a RR type (currently defined as
 // those structs beginning with a RR_Header, could be redefined as implementing
@@ -88,6 +103,14 @@ if err != nil {
 }
 headerEnd := off
 `)
+		// Length fields of size-* members are set from the packed data, remember where they are.
+		sizes := make(map[string]types.BasicKind)
+		for i := 1; i < st.NumFields(); i++ {
+			if strings.HasPrefix(st.Tag(i), `dns:"size-`) {
+				sizes[structMember(st.Tag(i))] = 0
+			}
+		}
+		sized := false
 		for i := 1; i < st.NumFields(); i++ {
 			o := func(s string) {
 				fmt.Fprintf(b, s, st.Field(i).Name())
@@ -96,6 +119,18 @@ return off, err
 }
 `)
 			}
+			if _, ok := sizes[st.Field(i).Name()]; ok {
+				sizes[st.Field(i).Name()] = st.Field(i).Type().(*types.Basic).Kind()
+				fmt.Fprintf(b, "off%s := off\n", st.Field(i).Name())
+			}
+			if strings.HasPrefix(st.Tag(i), `dns:"size-`) {
+				if !sized {
+					fmt.Fprint(b, "sizeStart := off\n")
+					sized = true
+				} else {
+					fmt.Fprint(b, "sizeStart = off\n")
+				}
+			}
 
 			if _, ok := st.Field(i).Type().(*types.Slice); ok {
 				switch st.Tag(i) {
@@ -106,8 +141,10 @@ return off, err
 					o("off, err = packDataOpt(rr.%s, msg, off)\n")
 				case `dns:"nsec"`:
 					o("off, err = packDataNsec(rr.%s, msg, off)\n")
+				case `dns:"apl"`:
+					o("off, err = packDataApl(rr.%s, msg, off)\n")
 				case `dns:"domain-name"`:
-					o("off, err = packDataDomainNames(rr.%s, msg, off, compression, compress)\n")
+					o("off, err = packDataDomainNames(rr.%s, msg, off, compression, false)\n")
 				default:
 					log.Fatalln(name, st.Field(i).Name(), st.Tag(i))
 				}
@@ -117,9 +154,9 @@ return off, err
 			switch {
 			case st.Tag(i) == `dns:"-"`: // ignored
 			case st.Tag(i) == `dns:"cdomain-name"`:
-				fallthrough
-			case st.Tag(i) == `dns:"domain-name"`:
 				o("off, err = PackDomainName(rr.%s, msg, off, compression, compress)\n")
+			case st.Tag(i) == `dns:"domain-name"`:
+				o("off, err = PackDomainName(rr.%s, msg, off, compression, false)\n")
 			case st.Tag(i) == `dns:"a"`:
 				o("off, err = packDataA(rr.%s, msg, off)\n")
 			case st.Tag(i) == `dns:"aaaa"`:
@@ -164,6 +201,27 @@ return off, err
 			default:
 				log.Fatalln(name, st.Field(i).Name(), st.Tag(i))
 			}
+			if strings.HasPrefix(st.Tag(i), `dns:"size-`) {
+				size := structMember(st.Tag(i))
+				var typ, pack string
+				switch sizes[size] {
+				case types.Uint8:
+					typ, pack = "uint8", "packUint8"
+				case types.Uint16:
+					typ, pack = "uint16", "packUint16"
+				default:
+					log.Fatalln(name, size, "is not a length field")
+				}
+				// Data that doesn't fit its length field can't be packed.
+				fmt.Fprintf(b, `rr.%[1]s = %[2]s(off - sizeStart)
+if int(rr.%[1]s) != off-sizeStart {
+return len(msg), ErrFmt
+}
+if _, err = %[3]s(rr.%[1]s, msg, off%[1]s); err != nil {
+return off, err
+}
+`, size, typ, pack)
+			}
 		}
 		// We have packed everything, only now we know the rdlength of this RR
 		fmt.Fprintln(b, "rr.Header().Rdlength = uint16(off- headerEnd)")
@@ -225,6 +283,8 @@ return rr, off, err
 					o("rr.%s, off, err = unpackDataOpt(msg, off)\n")
 				case `dns:"nsec"`:
 					o("rr.%s, off, err = unpackDataNsec(msg, off)\n")
+				case `dns:"apl"`:
+					o("rr.%s, off, err = unpackDataApl(msg, off)\n")
 				case `dns:"domain-name"`:
 					o("rr.%s, off, err = unpackDataDomainNames(msg, off, rdStart + int(rr.Hdr.Rdlength))\n")
 				default:
@@ -236,9 +296,13 @@ return rr, off, err
 			switch st.Tag(i) {
 			case `dns:"-"`: // ignored
 			case `dns:"cdomain-name"`:
-				fallthrough
-			case `dns:"domain-name"`:
 				o("rr.%s, off, err = UnpackDomainName(msg, off)\n")
+			case `dns:"domain-name"`:
+				if legacyCompressed[name] {
+					o("rr.%s, off, err = UnpackDomainName(msg, off)\n")
+				} else {
+					o("rr.%s, off, err = unpackDomainNameUncompressed(msg, off)\n")
+				}
 			case `dns:"a"`:
 				o("rr.%s, off, err = unpackDataA(msg, off)\n")
 			case `dns:"aaaa"`:
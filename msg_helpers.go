@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"net"
 	"strconv"
+	"strings"
 )
 
 // helper functions called from the generated zmsg.go
@@ -31,7 +32,11 @@ func packDataA(a net.IP, msg []byte, off int) (int, error) {
 	}
 	switch len(a) {
 	case net.IPv4len, net.IPv6len:
-		copy(msg[off:], a.To4())
+		a4 := a.To4()
+		if a4 == nil {
+			return len(msg), &Error{err: "bad ipv4 address packing a"}
+		}
+		copy(msg[off:], a4)
 		off += net.IPv4len
 	case 0:
 		// Allowed, for dynamic updates.
@@ -352,6 +357,10 @@ func unpackStringHex(msg []byte, off, end int) (string, int, error) {
 }
 
 func packStringHex(s string, msg []byte, off int) (int, error) {
+	if strings.ContainsAny(s, " \t\r\n") {
+		// Long blobs may be split in words, as they are in zone files.
+		s = strings.Join(strings.Fields(s), "")
+	}
 	h, err := hex.DecodeString(s)
 	if err != nil {
 		return len(msg), err
@@ -455,6 +464,13 @@ Option:
 		}
 		edns = append(edns, e)
 		off += int(optlen)
+	case EDNS0PADDING:
+		e := new(EDNS0_PADDING)
+		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
+			return nil, len(msg), err
+		}
+		edns = append(edns, e)
+		off += int(optlen)
 	default:
 		e := new(EDNS0_LOCAL)
 		e.Code = code
@@ -475,17 +491,12 @@ Option:
 func packDataOpt(options []EDNS0, msg []byte, off int) (int, error) {
 	for _, el := range options {
 		b, err := el.pack()
-		if err != nil || off+3 > len(msg) {
+		if err != nil || off+4+len(b) > len(msg) {
 			return len(msg), &Error{err: "overflow packing opt"}
 		}
 		binary.BigEndian.PutUint16(msg[off:], el.Option())      // Option code
 		binary.BigEndian.PutUint16(msg[off+2:], uint16(len(b))) // Length
 		off += 4
-		if off+len(b) > len(msg) {
-			copy(msg[off:], b)
-			off = len(msg)
-			continue
-		}
 		// Actual data
 		copy(msg[off:off+len(b)], b)
 		off += len(b)
@@ -587,6 +598,10 @@ func packDataNsec(bitmap []uint16, msg []byte, off int) (int, error) {
 		if off+2+int(length) > len(msg) {
 			return len(msg), &Error{err: "overflow packing nsec"}
 		}
+		// Clear the octets we haven't used yet, msg may hold data from an earlier message
+		for i := off + 2 + int(lastlength); i < off+2+int(length); i++ {
+			msg[i] = 0
+		}
 		// Setting the window #
 		msg[off] = byte(window)
 		// Setting the octets length
@@ -599,6 +614,63 @@ func packDataNsec(bitmap []uint16, msg []byte, off int) (int, error) {
 	return off, nil
 }
 
+func unpackDataApl(msg []byte, off int) ([]APLPrefix, int, error) {
+	var prefixes []APLPrefix
+	for off < len(msg) {
+		if off+4 > len(msg) {
+			return prefixes, len(msg), &Error{err: "overflow unpacking APL prefix"}
+		}
+		family := binary.BigEndian.Uint16(msg[off:])
+		prefix := int(msg[off+2])
+		n := msg[off+3]
+		off += 4
+		var ip net.IP
+		switch family {
+		case 1:
+			ip = make(net.IP, net.IPv4len)
+		case 2:
+			ip = make(net.IP, net.IPv6len)
+		default:
+			return prefixes, len(msg), &Error{err: "bad APL address family"}
+		}
+		if prefix > 8*len(ip) {
+			return prefixes, len(msg), &Error{err: "bad APL prefix length"}
+		}
+		afdlength := int(n & 0x7f)
+		if afdlength > len(ip) || off+afdlength > len(msg) {
+			return prefixes, len(msg), &Error{err: "overflow unpacking APL prefix"}
+		}
+		copy(ip, msg[off:off+afdlength])
+		off += afdlength
+		mask := net.CIDRMask(prefix, 8*len(ip))
+		prefixes = append(prefixes, APLPrefix{Negation: n&0x80 != 0, Network: net.IPNet{IP: ip.Mask(mask), Mask: mask}})
+	}
+	return prefixes, off, nil
+}
+
+func packDataApl(prefixes []APLPrefix, msg []byte, off int) (int, error) {
+	for i := range prefixes {
+		family, addr, err := prefixes[i].afd()
+		if err != nil {
+			return len(msg), err
+		}
+		if off+4+len(addr) > len(msg) {
+			return len(msg), &Error{err: "overflow packing APL prefix"}
+		}
+		ones, _ := prefixes[i].Network.Mask.Size()
+		n := byte(len(addr))
+		if prefixes[i].Negation {
+			n |= 0x80
+		}
+		binary.BigEndian.PutUint16(msg[off:], family)
+		msg[off+2] = byte(ones)
+		msg[off+3] = n
+		off += 4
+		off += copy(msg[off:], addr)
+	}
+	return off, nil
+}
+
 func unpackDataDomainNames(msg []byte, off, end int) ([]string, int, error) {
 	var (
 		servers []string
@@ -609,7 +681,7 @@ func unpackDataDomainNames(msg []byte, off, end int) ([]string, int, error) {
 		return nil, len(msg), &Error{err: "overflow unpacking domain names"}
 	}
 	for off < end {
-		s, off, err = UnpackDomainName(msg, off)
+		s, off, err = unpackDomainNameUncompressed(msg, off)
 		if err != nil {
 			return servers, len(msg), err
 		}
@@ -618,6 +690,23 @@ func unpackDataDomainNames(msg []byte, off, end int) ([]string, int, error) {
 	return servers, off, nil
 }
 
+// unpackDomainNameUncompressed unpacks a domain name from rdata in which names must
+// not be compressed: only RFC 1035 types and a few legacy ones may have compression
+// pointers in their rdata, see RFC 3597, section 4.
+func unpackDomainNameUncompressed(msg []byte, off int) (string, int, error) {
+	s, off1, err := UnpackDomainName(msg, off)
+	if err != nil {
+		return s, off1, err
+	}
+	// Without pointers the labels from off run up to off1, a pointer shows up on the way.
+	for i := off; i < off1; i += int(msg[i]) + 1 {
+		if msg[i]&0xC0 != 0 {
+			return "", len(msg), &Error{err: "compression pointer in rdata"}
+		}
+	}
+	return s, off1, nil
+}
+
 func packDataDomainNames(names []string, msg []byte, off int, compression map[string]int, compress bool) (int, error) {
 	var err error
 	for j := 0; j < len(names); j++ {
@@ -4,6 +4,7 @@ import (
 	"crypto/sha1"
 	"hash"
 	"io"
+	"sort"
 	"strings"
 )
 
@@ -11,8 +12,18 @@ type saltWireFmt struct {
 	Salt string `dns:"size-hex"`
 }
 
+// MaxNSEC3Iterations is the maximum number of additional hash iterations HashName
+// is willing to perform. NSEC3 records asking for more are considered bogus, as
+// hashing with a large number of iterations can be used to burn CPU.
+var MaxNSEC3Iterations uint16 = 150
+
 // HashName hashes a string (label) according to RFC 5155. It returns the hashed string in uppercase.
+// The empty string is returned when the name can not be hashed, for instance when
+// iter is larger than MaxNSEC3Iterations.
 func HashName(label string, ha uint8, iter uint16, salt string) string {
+	if iter > MaxNSEC3Iterations {
+		return ""
+	}
 	saltwire := new(saltWireFmt)
 	saltwire.Salt = salt
 	wire := make([]byte, DefaultMsgSize)
@@ -49,6 +60,21 @@ func HashName(label string, ha uint8, iter uint16, salt string) string {
 	return toBase32(nsec3)
 }
 
+// NSEC3OwnerName returns the owner name of the NSEC3 record for name in zone: the
+// lowercase hash of name, as returned by HashName, prepended to zone. The empty
+// string is returned when the name can not be hashed.
+func NSEC3OwnerName(name, zone string, ha uint8, iter uint16, salt string) string {
+	hash := HashName(name, ha, iter, salt)
+	if hash == "" {
+		return ""
+	}
+	zone = Fqdn(zone)
+	if zone == "." {
+		return strings.ToLower(hash) + "."
+	}
+	return strings.ToLower(hash) + "." + zone
+}
+
 // Denialer is an interface that should be implemented by types that are used to denial
 // answers in DNSSEC.
 type Denialer interface {
@@ -60,56 +86,245 @@ type Denialer interface {
 
 // Cover implements the Denialer interface.
 func (rr *NSEC) Cover(name string) bool {
-	return true
+	owner, err := canonicalLabels(rr.Hdr.Name)
+	if err != nil {
+		return false
+	}
+	next, err := canonicalLabels(rr.NextDomain)
+	if err != nil {
+		return false
+	}
+	n, err := canonicalLabels(name)
+	if err != nil {
+		return false
+	}
+	if canonicalCompare(owner, n) >= 0 {
+		return false
+	}
+	if canonicalCompare(owner, next) < 0 {
+		return canonicalCompare(n, next) < 0
+	}
+	// Last NSEC in the zone, it points back to the apex.
+	return IsSubDomain(rr.NextDomain, name)
 }
 
 // Match implements the Denialer interface.
 func (rr *NSEC) Match(name string) bool {
-	return true
+	owner, err := canonicalLabels(rr.Hdr.Name)
+	if err != nil {
+		return false
+	}
+	n, err := canonicalLabels(name)
+	if err != nil {
+		return false
+	}
+	return canonicalCompare(owner, n) == 0
 }
 
-// Cover implements the Denialer interface.
+// Cover implements the Denialer interface. Name must be in the zone of rr, the parent
+// of its owner name.
 func (rr *NSEC3) Cover(name string) bool {
-	// FIXME(miek): check if the zones match
-	// FIXME(miek): check if we're not dealing with parent nsec3
+	hash, ok := rr.ownerHash(name)
+	if !ok {
+		return false
+	}
 	hname := HashName(name, rr.Hash, rr.Iterations, rr.Salt)
-	labels := Split(rr.Hdr.Name)
-	if len(labels) < 2 {
+	if hname == "" {
 		return false
 	}
-	hash := strings.ToUpper(rr.Hdr.Name[labels[0] : labels[1]-1]) // -1 to remove the dot
-	if hash == rr.NextDomain {
+	next := strings.ToUpper(rr.NextDomain)
+	if hash == next {
 		return false // empty interval
 	}
-	if hash > rr.NextDomain { // last name, points to apex
-		// hname > hash
-		// hname > rr.NextDomain
-		// TODO(miek)
-	}
-	if hname <= hash {
-		return false
-	}
-	if hname >= rr.NextDomain {
-		return false
+	if hash > next { // last name, points to apex
+		return hname > hash || hname < next
 	}
-	return true
+	return hname > hash && hname < next
 }
 
-// Match implements the Denialer interface.
+// Match implements the Denialer interface. Name must be in the zone of rr, the parent
+// of its owner name.
 func (rr *NSEC3) Match(name string) bool {
-	// FIXME(miek): Check if we are in the same zone
+	hash, ok := rr.ownerHash(name)
+	if !ok {
+		return false
+	}
 	hname := HashName(name, rr.Hash, rr.Iterations, rr.Salt)
+	return hname != "" && hname == hash
+}
+
+// zone returns the zone rr belongs to, the parent of its owner name, in lowercase.
+// It returns the empty string when the owner name has less than two labels.
+func (rr *NSEC3) zone() string {
 	labels := Split(rr.Hdr.Name)
-	if len(labels) < 2 {
-		return false
+	switch len(labels) {
+	case 0:
+		return ""
+	case 1: // NSEC3 in the root zone
+		return "."
 	}
-	hash := strings.ToUpper(rr.Hdr.Name[labels[0] : labels[1]-1]) // -1 to remove the .
-	if hash == hname {
-		return true
+	return strings.ToLower(Fqdn(rr.Hdr.Name[labels[1]:]))
+}
+
+// ownerHash returns the hash from the owner name of rr in uppercase, when name is in the
+// zone of rr.
+func (rr *NSEC3) ownerHash(name string) (string, bool) {
+	zone := rr.zone()
+	if zone == "" || !IsSubDomain(zone, strings.ToLower(name)) {
+		return "", false
+	}
+	hash := Fqdn(rr.Hdr.Name)
+	return strings.ToUpper(hash[:strings.IndexByte(hash, '.')]), true
+}
+
+// OptOut returns true when the opt-out flag is set. An opt-out NSEC3 record may
+// cover insecure delegations, see RFC 5155, section 6.
+func (rr *NSEC3) OptOut() bool {
+	return rr.Flags&1 == 1
+}
+
+// TypeBitMapHas returns true when t is in the type bitmap of rr, i.e. when rr asserts
+// that a record of type t exists at the owner name. The bitmap need not be sorted.
+func (rr *NSEC) TypeBitMapHas(t uint16) bool { return typeBitMapHas(rr.TypeBitMap, t) }
+
+// TypeBitMapHas returns true when t is in the type bitmap of rr, i.e. when rr asserts
+// that a record of type t exists at the unhashed owner name. The bitmap need not be sorted.
+func (rr *NSEC3) TypeBitMapHas(t uint16) bool { return typeBitMapHas(rr.TypeBitMap, t) }
+
+// typeBitMapHas returns true when t is in bitmap. A bitmap that is parsed or built by
+// hand may be in any order, so all of it is searched.
+func typeBitMapHas(bitmap []uint16, t uint16) bool {
+	for _, b := range bitmap {
+		if b == t {
+			return true
+		}
 	}
 	return false
 }
 
+// NSEC3Param returns the first NSEC3PARAM record found in the message, the
+// answer section is searched first, then the authority and additional
+// sections. It returns nil when there is no such record.
+func (dns *Msg) NSEC3Param() *NSEC3PARAM {
+	for _, section := range [][]RR{dns.Answer, dns.Ns, dns.Extra} {
+		for _, r := range section {
+			if p, ok := r.(*NSEC3PARAM); ok {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// BuildNSEC creates the NSEC chain for the zone zone from the records in rrs. One NSEC
+// record is returned for each owner name, it points to the next owner name in canonical
+// order and the last one points back to the apex. The type bitmap holds the types
+// found for the owner name plus RRSIG and NSEC, as they will exist once the zone is signed.
+// All records must be in the zone and the apex must be present. Occluded names (i.e. below
+// a delegation point) are not detected and will be part of the chain.
+func BuildNSEC(rrs []RR, zone string, ttl uint32) ([]RR, error) {
+	zone = Fqdn(zone)
+	types := make(map[string]map[uint16]bool)
+	var owners []string
+	for _, r := range rrs {
+		name := strings.ToLower(r.Header().Name)
+		if !IsSubDomain(zone, name) {
+			return nil, &Error{err: "name out of zone: " + r.Header().Name}
+		}
+		if _, ok := types[name]; !ok {
+			types[name] = make(map[uint16]bool)
+			owners = append(owners, name)
+		}
+		types[name][r.Header().Rrtype] = true
+	}
+	if _, ok := types[strings.ToLower(zone)]; !ok {
+		return nil, &Error{err: "no apex records for zone: " + zone}
+	}
+
+	order := canonicalOrder{names: owners, labels: make([][]string, len(owners))}
+	for i, name := range owners {
+		l, err := canonicalLabels(name)
+		if err != nil {
+			return nil, err
+		}
+		order.labels[i] = l
+	}
+	sort.Sort(order)
+
+	nsec := make([]RR, len(owners))
+	for i, name := range owners {
+		rr := &NSEC{Hdr: RR_Header{Name: name, Rrtype: TypeNSEC, Class: ClassINET, Ttl: ttl}}
+		rr.NextDomain = owners[(i+1)%len(owners)]
+		types[name][TypeRRSIG] = true
+		types[name][TypeNSEC] = true
+		for t := range types[name] {
+			rr.TypeBitMap = append(rr.TypeBitMap, t)
+		}
+		sort.Sort(uint16Slice(rr.TypeBitMap))
+		nsec[i] = rr
+	}
+	return nsec, nil
+}
+
+// canonicalOrder sorts names in canonical order, labels holds the labels of each name.
+type canonicalOrder struct {
+	names  []string
+	labels [][]string
+}
+
+func (p canonicalOrder) Len() int { return len(p.names) }
+func (p canonicalOrder) Swap(i, j int) {
+	p.names[i], p.names[j] = p.names[j], p.names[i]
+	p.labels[i], p.labels[j] = p.labels[j], p.labels[i]
+}
+func (p canonicalOrder) Less(i, j int) bool { return canonicalCompare(p.labels[i], p.labels[j]) < 0 }
+
+type uint16Slice []uint16
+
+func (p uint16Slice) Len() int           { return len(p) }
+func (p uint16Slice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p uint16Slice) Less(i, j int) bool { return p[i] < p[j] }
+
+// canonicalCompare compares two domain names, given as the labels returned by
+// canonicalLabels, in the canonical order from RFC 4034, section 6.1. It returns
+// -1, 0 or 1 if a sorts before, equal to or after b.
+func canonicalCompare(a, b []string) int {
+	i, j := len(a)-1, len(b)-1
+	for ; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(a[i], b[j]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case i >= 0:
+		return 1
+	case j >= 0:
+		return -1
+	}
+	return 0
+}
+
+// canonicalLabels returns the labels of name in wire format with upper case
+// US-ASCII letters converted to lower case.
+func canonicalLabels(name string) ([]string, error) {
+	buf := make([]byte, 256)
+	off, err := PackDomainName(Fqdn(name), buf, 0, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	var labels []string
+	for i := 0; i < off && buf[i] != 0; i += int(buf[i]) + 1 {
+		l := buf[i+1 : i+1+int(buf[i])]
+		for k := range l {
+			if l[k] >= 'A' && l[k] <= 'Z' {
+				l[k] += 'a' - 'A'
+			}
+		}
+		labels = append(labels, string(l))
+	}
+	return labels, nil
+}
+
 func packSaltWire(sw *saltWireFmt, msg []byte) (int, error) {
 	off, err := packStringHex(sw.Salt, msg, 0)
 	if err != nil {
@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -26,4 +27,265 @@ func TestNsec3(t *testing.T) {
 	if !nsec3.(*NSEC3).Match("nl.") { // sk4e8fj94u78smusb40o1n0oltbblu2r.nl.
 		t.Error("sk4e8fj94u78smusb40o1n0oltbblu2r.nl. should match sk4e8fj94u78smusb40o1n0oltbblu2r.nl.")
 	}
+	// The hash of com. in nl. doesn't match com., which is not in the zone.
+	nsec3, _ = NewRR(NSEC3OwnerName("com.", "nl.", SHA1, 5, "F10E9F7EA83FC8F3") + " IN NSEC3 1 1 5 F10E9F7EA83FC8F3 SK4F38CQ0ATIEI8MH3RGD0P5I4II6QAN NS")
+	if nsec3.(*NSEC3).Match("com.") {
+		t.Error("NSEC3 record in nl. should not match com.")
+	}
+	// The last NSEC3 in the zone wraps around to the first.
+	nsec3, _ = NewRR("39p90000000000000000000000000000.nl. IN NSEC3 1 1 5 F10E9F7EA83FC8F3 10000000000000000000000000000000 NS DS RRSIG")
+	if !nsec3.(*NSEC3).Cover("snasajsksasasa.nl.") {
+		t.Error("39p94jrinub66hnpem8qdpstrec86pg3. should be covered by the last NSEC3 39p90000000000000000000000000000.nl.")
+	}
+	if nsec3.(*NSEC3).Cover("snasajsksasasa.com.") {
+		t.Error("snasajsksasasa.com. is not in nl. and should not be covered")
+	}
+}
+
+func TestNsec3ParamPackUnpack(t *testing.T) {
+	for _, s := range []string{
+		"nl. 3600 IN NSEC3PARAM 1 0 5 F10E9F7EA83FC8F3",
+		"nl. 3600 IN NSEC3PARAM 1 0 0 -",
+	} {
+		rr, err := NewRR(s)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", s, err)
+		}
+		buf := make([]byte, rr.len())
+		off, err := PackRR(rr, buf, 0, nil, false)
+		if err != nil {
+			t.Fatalf("failed to pack %q: %v", s, err)
+		}
+		rr1, _, err := UnpackRR(buf[:off], 0)
+		if err != nil {
+			t.Fatalf("failed to unpack %q: %v", s, err)
+		}
+		if rr1.String() != rr.String() {
+			t.Errorf("round trip failed, got %q, expected %q", rr1.String(), rr.String())
+		}
+		if rr1.(*NSEC3PARAM).SaltLength != rr.(*NSEC3PARAM).SaltLength {
+			t.Errorf("salt length mismatch for %q", s)
+		}
+	}
+}
+
+func TestNsec3SaltLength(t *testing.T) {
+	salt := strings.Repeat("AB", 255)
+	rr, err := NewRR("nl. 3600 IN NSEC3PARAM 1 0 5 " + salt)
+	if err != nil {
+		t.Fatalf("failed to parse a 255 octet salt: %v", err)
+	}
+	if l := rr.(*NSEC3PARAM).SaltLength; l != 255 {
+		t.Errorf("expected salt length 255, got %d", l)
+	}
+	rr, err = NewRR("sk4e8fj94u78smusb40o1n0oltbblu2r.nl. IN NSEC3 1 1 5 " + salt + " SK4F38CQ0ATIEI8MH3RGD0P5I4II6QAN NS")
+	if err != nil {
+		t.Fatalf("failed to parse a 255 octet salt: %v", err)
+	}
+	if l := rr.(*NSEC3).SaltLength; l != 255 {
+		t.Errorf("expected salt length 255, got %d", l)
+	}
+
+	salt += "AB"
+	if _, err := NewRR("nl. 3600 IN NSEC3PARAM 1 0 5 " + salt); err == nil {
+		t.Error("expected error for a 256 octet NSEC3PARAM salt")
+	}
+	if _, err := NewRR("sk4e8fj94u78smusb40o1n0oltbblu2r.nl. IN NSEC3 1 1 5 " + salt + " SK4F38CQ0ATIEI8MH3RGD0P5I4II6QAN NS"); err == nil {
+		t.Error("expected error for a 256 octet NSEC3 salt")
+	}
+}
+
+func TestNsec3PackUnpack(t *testing.T) {
+	rr, err := NewRR("sk4e8fj94u78smusb40o1n0oltbblu2r.nl. IN NSEC3 1 1 5 F10E9F7EA83FC8F3 SK4F38CQ0ATIEI8MH3RGD0P5I4II6QAN NS SOA TXT RRSIG DNSKEY NSEC3PARAM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, rr.len())
+	off, err := PackRR(rr, buf, 0, nil, false)
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+	rr1, _, err := UnpackRR(buf[:off], 0)
+	if err != nil {
+		t.Fatalf("failed to unpack: %v", err)
+	}
+	if rr1.String() != rr.String() {
+		t.Errorf("round trip failed, got %q, expected %q", rr1.String(), rr.String())
+	}
+}
+
+func TestNsec3PackSizeAutofill(t *testing.T) {
+	nsec3 := &NSEC3{Hdr: RR_Header{Name: "sk4e8fj94u78smusb40o1n0oltbblu2r.nl.", Rrtype: TypeNSEC3, Class: ClassINET, Ttl: 3600},
+		Hash: SHA1, Iterations: 5, SaltLength: 4, Salt: "F10E9F7EA83FC8F3", NextDomain: "SK4F38CQ0ATIEI8MH3RGD0P5I4II6QAN"}
+	buf := make([]byte, MaxMsgSize)
+	off, err := PackRR(nsec3, buf, 0, nil, false)
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+	if nsec3.SaltLength != 8 || nsec3.HashLength != 20 {
+		t.Errorf("expected SaltLength 8 and HashLength 20, got %d and %d", nsec3.SaltLength, nsec3.HashLength)
+	}
+	rr, _, err := UnpackRR(buf[:off], 0)
+	if err != nil {
+		t.Fatalf("failed to unpack: %v", err)
+	}
+	if rr.String() != nsec3.String() {
+		t.Errorf("round trip failed, got %q, expected %q", rr.String(), nsec3.String())
+	}
+
+	param := &NSEC3PARAM{Hdr: RR_Header{Name: "nl.", Rrtype: TypeNSEC3PARAM, Class: ClassINET, Ttl: 3600},
+		Hash: SHA1, Iterations: 5, Salt: "F10E9F7EA83FC8F3"}
+	if _, err := PackRR(param, buf, 0, nil, false); err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+	if param.SaltLength != 8 {
+		t.Errorf("expected SaltLength 8, got %d", param.SaltLength)
+	}
+
+	// A salt longer than 255 octets doesn't fit SaltLength.
+	param.Salt = strings.Repeat("AB", 256)
+	if _, err := PackRR(param, buf, 0, nil, false); err != ErrFmt {
+		t.Errorf("expected ErrFmt for a too long salt, got %v", err)
+	}
+}
+
+func TestMsgNsec3Param(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("nl.", TypeNSEC3PARAM)
+	if m.NSEC3Param() != nil {
+		t.Fatal("expected no NSEC3PARAM in empty message")
+	}
+	soa, _ := NewRR("nl. 3600 IN SOA ns1.dns.nl. hostmaster.domain-registry.nl. 2015060300 3600 600 2419200 600")
+	param, _ := NewRR("nl. 3600 IN NSEC3PARAM 1 0 5 F10E9F7EA83FC8F3")
+	m.Ns = []RR{soa}
+	m.Extra = []RR{param}
+	p := m.NSEC3Param()
+	if p == nil {
+		t.Fatal("expected NSEC3PARAM to be found")
+	}
+	if p.Hash != SHA1 || p.Iterations != 5 || p.Salt != "F10E9F7EA83FC8F3" || p.SaltLength != 8 {
+		t.Errorf("wrong NSEC3PARAM returned: %s", p)
+	}
+}
+
+func TestHashNameMaxIterations(t *testing.T) {
+	if h := HashName("nl.", SHA1, MaxNSEC3Iterations, "DEAD"); h == "" {
+		t.Errorf("expected hash for %d iterations", MaxNSEC3Iterations)
+	}
+	if h := HashName("nl.", SHA1, 65535, "DEAD"); h != "" {
+		t.Errorf("expected no hash for 65535 iterations, got %s", h)
+	}
+	nsec3, _ := NewRR("sk4e8fj94u78smusb40o1n0oltbblu2r.nl. IN NSEC3 1 1 65535 F10E9F7EA83FC8F3 SK4F38CQ0ATIEI8MH3RGD0P5I4II6QAN NS SOA TXT RRSIG DNSKEY NSEC3PARAM")
+	if nsec3.(*NSEC3).Match("nl.") {
+		t.Error("NSEC3 with too many iterations should not match")
+	}
+	if nsec3.(*NSEC3).Cover("snasajsksasasa.nl.") {
+		t.Error("NSEC3 with too many iterations should not cover")
+	}
+}
+
+func TestBuildNSEC(t *testing.T) {
+	zone := `$ORIGIN example.
+@	IN	SOA	ns.example. hostmaster.example. 1 3600 600 86400 300
+@	IN	NS	ns.example.
+ns	IN	A	192.0.2.1
+z	IN	A	192.0.2.3
+*.a	IN	TXT	"wildcard"
+a	IN	MX	10 ns.example.
+yljkjljk.a	IN	A	192.0.2.2
+\001.z	IN	A	192.0.2.4
+Z.a	IN	A	192.0.2.5
+`
+	var rrs []RR
+	for x := range ParseZone(strings.NewReader(zone), "", "") {
+		if x.Error != nil {
+			t.Fatal(x.Error)
+		}
+		rrs = append(rrs, x.RR)
+	}
+	nsec, err := BuildNSEC(rrs, "example.", 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Canonical order example from RFC 4034, section 6.1.
+	expected := []string{
+		"example.\t300\tIN\tNSEC\ta.example. NS SOA RRSIG NSEC",
+		"a.example.\t300\tIN\tNSEC\t*.a.example. MX RRSIG NSEC",
+		"*.a.example.\t300\tIN\tNSEC\tyljkjljk.a.example. TXT RRSIG NSEC",
+		"yljkjljk.a.example.\t300\tIN\tNSEC\tz.a.example. A RRSIG NSEC",
+		"z.a.example.\t300\tIN\tNSEC\tns.example. A RRSIG NSEC",
+		"ns.example.\t300\tIN\tNSEC\tz.example. A RRSIG NSEC",
+		"z.example.\t300\tIN\tNSEC\t\\001.z.example. A RRSIG NSEC",
+		"\\001.z.example.\t300\tIN\tNSEC\texample. A RRSIG NSEC",
+	}
+	if len(nsec) != len(expected) {
+		t.Fatalf("expected %d NSEC records, got %d", len(expected), len(nsec))
+	}
+	for i := range nsec {
+		if nsec[i].String() != expected[i] {
+			t.Errorf("NSEC %d should be\n`%s', but is\n`%s'", i, expected[i], nsec[i].String())
+		}
+	}
+
+	if _, err := BuildNSEC(rrs[2:], "example.", 300); err == nil {
+		t.Error("expected error when the apex is missing")
+	}
+	if _, err := BuildNSEC(rrs, "example.org.", 300); err == nil {
+		t.Error("expected error for out of zone records")
+	}
+}
+
+func TestNSEC3OwnerName(t *testing.T) {
+	// From RFC 5155, Appendix A.
+	testcases := map[string]string{
+		"example.":     "0p9mhaveqvm6t7vbl5lop2u3t2rp3tom.example.",
+		"a.example.":   "35mthgpgcu1qg68fab165klnsnk3dpvl.example.",
+		"*.w.example.": "r53bq7cc2uvmubfu5ocmm6pers9tk9en.example.",
+	}
+	for name, owner := range testcases {
+		if o := NSEC3OwnerName(name, "example", SHA1, 12, "aabbccdd"); o != owner {
+			t.Errorf("expected NSEC3 owner %s for %s, got %s", owner, name, o)
+		}
+	}
+	// In the root zone the hash is the only label.
+	root := strings.ToLower(HashName("example.", SHA1, 12, "aabbccdd")) + "."
+	for _, zone := range []string{".", ""} {
+		if o := NSEC3OwnerName("example.", zone, SHA1, 12, "aabbccdd"); o != root {
+			t.Errorf("expected NSEC3 owner %s in the root zone %q, got %s", root, zone, o)
+		}
+	}
+	nsec3 := &NSEC3{Hdr: RR_Header{Name: root, Rrtype: TypeNSEC3, Class: ClassINET},
+		Hash: SHA1, Iterations: 12, Salt: "aabbccdd", NextDomain: HashName("example.", SHA1, 12, "aabbccdd")}
+	if !nsec3.Match("example.") {
+		t.Errorf("expected NSEC3 %s in the root zone to match example.", root)
+	}
+	if o := NSEC3OwnerName("example.", "example.", SHA1, MaxNSEC3Iterations+1, "aabbccdd"); o != "" {
+		t.Errorf("expected no owner name with too many iterations, got %s", o)
+	}
+}
+
+func TestTypeBitMapHas(t *testing.T) {
+	// Parsed records keep the order of the zone file, the bitmap is not sorted.
+	nsec := newRR(t, "example.org. 3600 IN NSEC a.example.org. TXT SOA A RRSIG NS NSEC").(*NSEC)
+	for _, typ := range []uint16{TypeA, TypeNS, TypeSOA, TypeTXT, TypeRRSIG, TypeNSEC} {
+		if !nsec.TypeBitMapHas(typ) {
+			t.Errorf("expected %s in the bitmap of %s", Type(typ), nsec)
+		}
+	}
+	for _, typ := range []uint16{TypeAAAA, TypeMX, TypeDS, TypeCNAME} {
+		if nsec.TypeBitMapHas(typ) {
+			t.Errorf("expected no %s in the bitmap of %s", Type(typ), nsec)
+		}
+	}
+
+	nsec3 := &NSEC3{TypeBitMap: []uint16{TypeRRSIG, TypeDS, TypeNS}}
+	if !nsec3.TypeBitMapHas(TypeNS) || !nsec3.TypeBitMapHas(TypeDS) {
+		t.Errorf("expected NS and DS in the bitmap %v", nsec3.TypeBitMap)
+	}
+	if nsec3.TypeBitMapHas(TypeSOA) {
+		t.Errorf("expected no SOA in the bitmap %v", nsec3.TypeBitMap)
+	}
+	if (&NSEC3{}).TypeBitMapHas(TypeA) {
+		t.Error("expected no types in an empty bitmap")
+	}
 }
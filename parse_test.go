@@ -375,6 +375,36 @@ func TestNSEC(t *testing.T) {
 	}
 }
 
+func TestNSECBitmapPackUnpack(t *testing.T) {
+	for _, s := range []string{
+		"localhost.dnssex.nl.\t3600\tIN\tNSEC\twww.dnssex.nl. A MX AAAA RRSIG NSEC TYPE262 TYPE1234 TYPE65534",
+		"p2209hipbpnm681knjnu0m1febshlv4e.nl.\t3600\tIN\tNSEC3\t1 1 5 30923C44C6CBBB8F P90DG1KE8QEAN0B01613LHQDG0SOJ0TA NS RRSIG TYPE62 TYPE4321",
+	} {
+		rr, err := NewRR(s)
+		if err != nil {
+			t.Errorf("failed to parse RR: %v", err)
+			continue
+		}
+		if rr.String() != s {
+			t.Errorf("`%s' should be equal to\n`%s'", rr.String(), s)
+		}
+		buf := make([]byte, rr.len())
+		off, err := PackRR(rr, buf, 0, nil, false)
+		if err != nil {
+			t.Errorf("failed to pack RR: %v", err)
+			continue
+		}
+		rr1, _, err := UnpackRR(buf[:off], 0)
+		if err != nil {
+			t.Errorf("failed to unpack RR: %v", err)
+			continue
+		}
+		if rr1.String() != s {
+			t.Errorf("`%s' should be equal to\n`%s' after a round trip", rr1.String(), s)
+		}
+	}
+}
+
 func TestParseLOC(t *testing.T) {
 	lt := map[string]string{
 		"SW1A2AA.find.me.uk.	LOC	51 30 12.748 N 00 07 39.611 W 0.00m 0.00m 0.00m 0.00m": "SW1A2AA.find.me.uk.\t3600\tIN\tLOC\t51 30 12.748 N 00 07 39.611 W 0m 0.00m 0.00m 0.00m",
@@ -541,6 +571,29 @@ func TestParseFailure(t *testing.T) {
 	}
 }
 
+func TestParseUnknownType(t *testing.T) {
+	for _, s := range []string{"example. IN FOObar 1.2.3.4", "example. FOObar 1.2.3.4", "example. 3600 IN FOObar 1.2.3.4"} {
+		_, err := NewRR(s)
+		if err == nil {
+			t.Errorf("expected an error for %q", s)
+			continue
+		}
+		if !strings.Contains(err.Error(), "unknown RR type") || !strings.Contains(err.Error(), `"FOObar"`) {
+			t.Errorf("expected an unknown RR type error naming FOObar for %q, got: %v", s, err)
+		}
+		if pe, ok := err.(*ParseError); !ok || pe.UnknownType != "FOObar" {
+			t.Errorf("expected a *ParseError with UnknownType FOObar for %q, got: %#v", s, err)
+		}
+	}
+	_, err := NewRR("example. 3x600 IN A 1.2.3.4")
+	if err == nil || !strings.Contains(err.Error(), "not a TTL") {
+		t.Errorf("expected a TTL error, got: %v", err)
+	}
+	if pe, ok := err.(*ParseError); !ok || pe.UnknownType != "" {
+		t.Errorf("expected a *ParseError without UnknownType, got: %#v", err)
+	}
+}
+
 func TestZoneParsing(t *testing.T) {
 	// parse_test.db
 	db := `
@@ -726,6 +779,30 @@ func TestLineNumberError2(t *testing.T) {
 	}
 }
 
+func TestLineNumberErrorZone(t *testing.T) {
+	zone := `$ORIGIN example.org.
+$TTL 3600
+@	IN	SOA	ns.example.org. hostmaster.example.org. 1 3600 600 86400 300
+	IN	NS	ns.example.org.
+ns	IN	A	300.0.2.1
+www	IN	A	192.0.2.2
+`
+	for x := range ParseZone(strings.NewReader(zone), "", "example.org.zone") {
+		if x.Error == nil {
+			continue
+		}
+		pe := x.Error
+		if pe.lex.line != 5 {
+			t.Errorf("expected error on line 5, got line %d", pe.lex.line)
+		}
+		if s := "example.org.zone: dns: bad A A: \"300.0.2.1\" at line: 5:17"; pe.Error() != s {
+			t.Errorf("error should be %s is %s", s, pe.Error())
+		}
+		return
+	}
+	t.Error("expected an error when parsing the zone")
+}
+
 // Test if the calculations are correct
 func TestRfc1982(t *testing.T) {
 	// If the current time and the timestamp are more than 68 years apart
@@ -767,6 +844,41 @@ func TestRfc1982(t *testing.T) {
 	}
 }
 
+func TestStringToTTL(t *testing.T) {
+	tests := map[string]uint32{
+		"3600":   3600,
+		"2w":     1209600,
+		"1h30m":  5400,
+		"1H30M":  5400,
+		"1d2h3s": 93603,
+		"10":     10,
+	}
+	for s, expected := range tests {
+		ttl, err := StringToTTL(s)
+		if err != nil {
+			t.Errorf("failed to parse %q: %v", s, err)
+			continue
+		}
+		if ttl != expected {
+			t.Errorf("%q should be %d, got %d", s, expected, ttl)
+		}
+	}
+	for _, s := range []string{"", "1x", "-1", "1h 30m"} {
+		if _, err := StringToTTL(s); err == nil {
+			t.Errorf("expected error when parsing %q", s)
+		}
+	}
+
+	rr, err := NewRR("example.org. 1h IN SOA ns.example.org. hostmaster.example.org. 1 1h 30m 2w 1h30m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "example.org.\t3600\tIN\tSOA\tns.example.org. hostmaster.example.org. 1 3600 1800 1209600 5400"
+	if rr.String() != expected {
+		t.Errorf("SOA should be\n`%s', but is\n`%s'", expected, rr.String())
+	}
+}
+
 func TestEmpty(t *testing.T) {
 	for range ParseZone(strings.NewReader(""), "", "") {
 		t.Errorf("should be empty")
@@ -821,6 +933,39 @@ func ExampleParseZone_generate() {
 	// 8.0.0.192.IN-ADDR.ARPA.	3600	IN	CNAME	8.0.0.0.192.IN-ADDR.ARPA.
 }
 
+func TestParseZoneGenerate(t *testing.T) {
+	zone := `$ORIGIN 2.0.192.in-addr.arpa.
+$GENERATE 1-3 $ PTR host-$.example.
+$GENERATE 10-12 ${0,3,d} PTR host-${100,2,x}.example.
+`
+	expected := []string{
+		"1.2.0.192.in-addr.arpa.\t3600\tIN\tPTR\thost-1.example.",
+		"2.2.0.192.in-addr.arpa.\t3600\tIN\tPTR\thost-2.example.",
+		"3.2.0.192.in-addr.arpa.\t3600\tIN\tPTR\thost-3.example.",
+		"010.2.0.192.in-addr.arpa.\t3600\tIN\tPTR\thost-6e.example.",
+		"011.2.0.192.in-addr.arpa.\t3600\tIN\tPTR\thost-6f.example.",
+		"012.2.0.192.in-addr.arpa.\t3600\tIN\tPTR\thost-70.example.",
+	}
+	var got []string
+	for x := range ParseZone(strings.NewReader(zone), "", "") {
+		if x.Error != nil {
+			t.Fatal(x.Error)
+		}
+		got = append(got, x.RR.String())
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected records\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	for _, bad := range []string{"$GENERATE 3-1 $ PTR host-$.example.", "$GENERATE 1-3 ${0,3,z} PTR host.example."} {
+		for x := range ParseZone(strings.NewReader(bad), "2.0.192.in-addr.arpa.", "") {
+			if x.Error == nil {
+				t.Errorf("expected error for %q, got %s", bad, x.RR)
+			}
+		}
+	}
+}
+
 func TestSRVPacking(t *testing.T) {
 	msg := Msg{}
 
@@ -860,6 +1005,41 @@ func TestSRVPacking(t *testing.T) {
 	}
 }
 
+func TestSRVTargetUncompressed(t *testing.T) {
+	rr, err := NewRR("_sip._tcp.example.com. SRV 10 60 5060 sipserver.example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, ok := rr.(*SRV)
+	if !ok {
+		t.Fatalf("expected *SRV, got %T", rr)
+	}
+	if srv.Hdr.Name != "_sip._tcp.example.com." || srv.Priority != 10 || srv.Weight != 60 || srv.Port != 5060 || srv.Target != "sipserver.example.com." {
+		t.Errorf("wrong SRV record parsed: %s", srv)
+	}
+
+	m := new(Msg)
+	m.SetQuestion("_sip._tcp.example.com.", TypeSRV)
+	m.Answer = []RR{srv}
+	m.Compress = true
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The owner name is compressed against the question, the target is written out in full.
+	target := []byte("\x09sipserver\x07example\x03com\x00")
+	if !bytes.HasSuffix(buf, target) {
+		t.Errorf("SRV target should not be compressed, got %x", buf)
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if m1.Answer[0].String() != srv.String() {
+		t.Errorf("expected %s after round trip, got %s", srv, m1.Answer[0])
+	}
+}
+
 func TestParseBackslash(t *testing.T) {
 	if r, err := NewRR("nul\\000gap.test.globnix.net. 600 IN	A 192.0.2.10"); err != nil {
 		t.Errorf("could not create RR with \\000 in it")
@@ -1090,6 +1270,34 @@ func TestTXT(t *testing.T) {
 	t.Log(rr.String())
 }
 
+func TestTXTBinaryRoundTrip(t *testing.T) {
+	s := "binary.example.\t3600\tIN\tTXT\t\"a\\000b\\\"c\\\\d\\255\" \"\\\"quoted\\\"\""
+	rr, err := NewRR(s)
+	if err != nil {
+		t.Fatalf("failed to parse TXT: %v", err)
+	}
+	if rr.String() != s {
+		t.Errorf("TXT should be\n`%s', but is\n`%s'", s, rr.String())
+	}
+	buf := make([]byte, rr.len())
+	off, err := PackRR(rr, buf, 0, nil, false)
+	if err != nil {
+		t.Fatalf("failed to pack TXT: %v", err)
+	}
+	// The rdata are two character-strings, holding the raw bytes.
+	rdata := []byte("\x08a\x00b\"c\\d\xff\x08\"quoted\"")
+	if !bytes.HasSuffix(buf[:off], rdata) {
+		t.Errorf("wrong TXT rdata, got %q, expected %q", buf[:off], rdata)
+	}
+	rr1, _, err := UnpackRR(buf[:off], 0)
+	if err != nil {
+		t.Fatalf("failed to unpack TXT: %v", err)
+	}
+	if rr1.String() != s {
+		t.Errorf("TXT should be\n`%s', but is\n`%s' after a round trip", s, rr1.String())
+	}
+}
+
 func TestTypeXXXX(t *testing.T) {
 	_, err := NewRR("example.com IN TYPE1234 \\# 4 aabbccdd")
 	if err != nil {
@@ -1105,6 +1313,120 @@ func TestTypeXXXX(t *testing.T) {
 	}
 }
 
+func TestTypeXXXXSpacedHex(t *testing.T) {
+	rr, err := NewRR("example.com. IN TYPE1234 \\# 11 0a000001 ( 0203 04050607\n 08 )")
+	if err != nil {
+		t.Fatalf("failed to parse TYPE1234 RR with spaced hex: %v", err)
+	}
+	if rdata := rr.(*RFC3597).Rdata; rdata != "0a00000102030405060708" {
+		t.Errorf("expected rdata 0a00000102030405060708, got %s", rdata)
+	}
+
+	// Rdata set directly, with spaces, should pack too.
+	rr.(*RFC3597).Rdata = "0a000001 0203\t0405 0607"
+	buf := make([]byte, 100)
+	off, err := PackRR(rr, buf, 0, nil, false)
+	if err != nil {
+		t.Fatalf("failed to pack rdata with spaces: %v", err)
+	}
+	if !bytes.HasSuffix(buf[:off], []byte{0, 10, 0x0a, 0, 0, 1, 2, 3, 4, 5, 6, 7}) {
+		t.Errorf("wrong rdata packed: %x", buf[:off])
+	}
+}
+
+func TestUnpackUnknownType(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("example.com.", 65262)
+	m.Answer = append(m.Answer, newRR(t, "example.com. 3600 IN TYPE65262 \\# 4 0a000001"), newRR(t, "example.com. 3600 IN TYPE65262 \\# 0"))
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(m1.Answer) != 2 {
+		t.Fatalf("expected 2 answers, got %d", len(m1.Answer))
+	}
+	for i, rdata := range []string{"\\# 4 0a000001", "\\# 0"} {
+		rr, ok := m1.Answer[i].(*RFC3597)
+		if !ok {
+			t.Fatalf("expected unknown type to unpack into RFC3597, got %T", m1.Answer[i])
+		}
+		if s := rr.String(); s != "example.com.\t3600\tCLASS1\tTYPE65262\t"+rdata {
+			t.Errorf("unexpected presentation format: %s", s)
+		}
+		rbuf := make([]byte, 100)
+		off, err := PackRR(rr, rbuf, 0, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l := rr.len(); l != off {
+			t.Errorf("expected length %d for %s, got %d", off, rr, l)
+		}
+	}
+	if s := m1.String(); !strings.Contains(s, "TYPE65262\t\\# 4 0a000001") {
+		t.Errorf("unknown type not in message output:\n%s", s)
+	}
+}
+
+func TestParseDNSKEYSplitBase64(t *testing.T) {
+	const key = "AwEAAcNEU67LJI5GEgF9QLNqLO1SMq1EdoQ6E9f85ha0k0ewQGCblyW2836GiVsm6k8Kr5ECIoMJ6fZWf3CQSQ9ycWfTyOHfmI3eQ/1Covhb2y4bAmL/07PhrL7ozWBW3wBfM335Ft9xjtXHPy7ztCbV9qZ4TVDTW/Iyg0PiwgoXVesz"
+	for _, s := range []string{
+		"miek.nl. IN DNSKEY 256 3 5 " + key[:40] + " " + key[40:100] + " " + key[100:],
+		"miek.nl. IN DNSKEY 256 3 5 " + key[:40] + "\t" + key[40:100] + "   " + key[100:] + " ; comment",
+		"miek.nl. IN DNSKEY 256 3 5 ( " + key[:40] + "\n " + key[40:100] + "\n\t" + key[100:] + " )",
+	} {
+		rr, err := NewRR(s)
+		if err != nil {
+			t.Errorf("failed to parse DNSKEY with split key %q: %v", s, err)
+			continue
+		}
+		if k := rr.(*DNSKEY).PublicKey; k != key {
+			t.Errorf("expected public key %s, got %s", key, k)
+		}
+	}
+}
+
+func TestParseRelativeOwner(t *testing.T) {
+	tests := []struct {
+		zone, origin string
+		want         string
+	}{
+		{"www IN A 1.2.3.4\n", "example.org.", "www.example.org.\t3600\tIN\tA\t1.2.3.4"},
+		{"www IN A 1.2.3.4\n", "example.org", "www.example.org.\t3600\tIN\tA\t1.2.3.4"},
+		{"www.example.org. IN A 1.2.3.4\n", "example.net.", "www.example.org.\t3600\tIN\tA\t1.2.3.4"},
+		{"@ IN A 1.2.3.4\n", "example.org.", "example.org.\t3600\tIN\tA\t1.2.3.4"},
+		{"www IN CNAME web\n", "example.org.", "www.example.org.\t3600\tIN\tCNAME\tweb.example.org."},
+		{"$ORIGIN example.net.\nwww IN A 1.2.3.4\n", "example.org.", "www.example.net.\t3600\tIN\tA\t1.2.3.4"},
+		// Without an origin relative names are qualified with the root.
+		{"www IN A 1.2.3.4\n", "", "www.\t3600\tIN\tA\t1.2.3.4"},
+	}
+	for _, tc := range tests {
+		for x := range ParseZone(strings.NewReader(tc.zone), tc.origin, "") {
+			if x.Error != nil {
+				t.Errorf("failed to parse %q with origin %q: %v", tc.zone, tc.origin, x.Error)
+				continue
+			}
+			if s := x.RR.String(); s != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, s)
+			}
+		}
+	}
+
+	rr, err := NewRR("$ORIGIN example.org.\nwww IN A 1.2.3.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr.Header().Name != "www.example.org." {
+		t.Errorf("expected owner www.example.org., got %s", rr.Header().Name)
+	}
+	if _, err := PackRR(rr, make([]byte, 100), 0, nil, false); err != nil {
+		t.Errorf("failed to pack record with qualified owner: %v", err)
+	}
+}
+
 func TestPTR(t *testing.T) {
 	_, err := NewRR("144.2.0.192.in-addr.arpa. 900 IN PTR ilouse03146p0\\(.example.com.")
 	if err != nil {
@@ -1153,6 +1475,43 @@ func TestParseRRSIGTimestamp(t *testing.T) {
 	}
 }
 
+func TestParseRRSIGTypeCovered(t *testing.T) {
+	for s, covered := range map[string]uint16{
+		"example.org. 3600 IN RRSIG A 8 2 3600 20170101000000 20160101000000 12345 example.org. AwEAAQ==":         TypeA,
+		"example.org. 3600 IN RRSIG mx 8 2 3600 20170101000000 20160101000000 12345 example.org. AwEAAQ==":        TypeMX,
+		"example.org. 3600 IN RRSIG TYPE65280 8 2 3600 20170101000000 20160101000000 12345 example.org. AwEAAQ==": 65280,
+		"example.org. 3600 IN SIG NSEC3PARAM 8 2 3600 20170101000000 20160101000000 12345 example.org. AwEAAQ==":  TypeNSEC3PARAM,
+	} {
+		rr, err := NewRR(s)
+		if err != nil {
+			t.Errorf("failed to parse %q: %v", s, err)
+			continue
+		}
+		var sig *RRSIG
+		switch x := rr.(type) {
+		case *RRSIG:
+			sig = x
+		case *SIG:
+			sig = &x.RRSIG
+		}
+		if sig.TypeCovered != covered {
+			t.Errorf("expected type covered %d for %q, got %d", covered, s, sig.TypeCovered)
+		}
+		rr1, err := NewRR(rr.String())
+		if err != nil {
+			t.Errorf("failed to parse %q: %v", rr.String(), err)
+			continue
+		}
+		if rr1.String() != rr.String() {
+			t.Errorf("round trip failed, got %q, expected %q", rr1.String(), rr.String())
+		}
+	}
+
+	if _, err := NewRR("example.org. 3600 IN RRSIG FOO 8 2 3600 20170101000000 20160101000000 12345 example.org. AwEAAQ=="); err == nil {
+		t.Error("expected error for an unknown type covered")
+	}
+}
+
 func TestTxtEqual(t *testing.T) {
 	rr1 := new(TXT)
 	rr1.Hdr = RR_Header{Name: ".", Rrtype: TypeTXT, Class: ClassINET, Ttl: 0}
@@ -1490,3 +1849,74 @@ func TestParseURI(t *testing.T) {
 		}
 	}
 }
+
+func TestAPLRoundTrip(t *testing.T) {
+	s := "apl.example.\t3600\tIN\tAPL\t1:192.168.32.0/21 !1:192.168.38.0/28 2:2001:db8::/32 !2:2001:db8:1::/48 1:0.0.0.0/0"
+	rr, err := NewRR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr.String() != s {
+		t.Errorf("expected %s, got %s", s, rr)
+	}
+	apl := rr.(*APL)
+	if len(apl.Prefixes) != 5 || !apl.Prefixes[1].Negation || apl.Prefixes[2].Negation {
+		t.Fatalf("wrong prefixes parsed: %v", apl.Prefixes)
+	}
+
+	buf := make([]byte, rr.len())
+	off, err := PackRR(rr, buf, 0, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off != len(buf) {
+		t.Errorf("expected length %d, packed %d bytes", len(buf), off)
+	}
+	// !1:192.168.38.0/28 is sent as family 1, prefix 28, negated length 3 and 192.168.38.
+	if !bytes.Contains(buf, []byte{0, 1, 28, 0x83, 192, 168, 38}) {
+		t.Errorf("negated prefix not packed correctly: %x", buf)
+	}
+	rr1, _, err := UnpackRR(buf[:off], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr1.String() != s {
+		t.Errorf("expected %s after round trip, got %s", s, rr1)
+	}
+
+	for _, s := range []string{"apl.example. APL 1:2001:db8::/32", "apl.example. APL 3:192.0.2.0/24", "apl.example. APL 1:192.0.2.0"} {
+		if _, err := NewRR(s); err == nil {
+			t.Errorf("expected error parsing %q", s)
+		}
+	}
+}
+
+func TestParseAVC(t *testing.T) {
+	avcs := map[string]string{
+		`example.org. IN AVC "app-name:WOLFGANG|app-class:OAM|business=yes" "another string"`: "example.org.\t3600\tIN\tAVC\t\"app-name:WOLFGANG|app-class:OAM|business=yes\" \"another string\"",
+	}
+	for avc, o := range avcs {
+		rr, err := NewRR(avc)
+		if err != nil {
+			t.Fatalf("failed to parse AVC: %v", err)
+		}
+		if rr.String() != o {
+			t.Errorf("`%s' should be equal to\n`%s', but is `%s'", avc, o, rr.String())
+		}
+		if a, ok := rr.(*AVC); !ok || len(a.Txt) != 2 {
+			t.Fatalf("expected AVC with two strings, got %#v", rr)
+		}
+		buf := make([]byte, rr.len())
+		off, err := PackRR(rr, buf, 0, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr1, _, err := UnpackRR(buf[:off], 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rr1.String() != o {
+			t.Errorf("AVC does not round-trip: expected `%s', got `%s'", o, rr1.String())
+		}
+	}
+}
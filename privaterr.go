@@ -80,6 +80,54 @@ func (r *PrivateRR) pack(msg []byte, off int, compression map[string]int, compre
 	return off, nil
 }
 
+// RdataBuffer helps to pack the rdata of a private RR in PrivateRdata.Pack, without
+// keeping track of the offset in the buffer. Each method packs a field at the current
+// offset and moves past it, or returns an error when the field does not fit.
+type RdataBuffer struct {
+	buf []byte
+	off int
+}
+
+// NewRdataBuffer returns an RdataBuffer that packs into buf.
+func NewRdataBuffer(buf []byte) *RdataBuffer { return &RdataBuffer{buf: buf} }
+
+// Len returns the number of octets packed so far.
+func (b *RdataBuffer) Len() int { return b.off }
+
+// Uint8 packs i.
+func (b *RdataBuffer) Uint8(i uint8) (err error) {
+	b.off, err = packUint8(i, b.buf, b.off)
+	return err
+}
+
+// Uint16 packs i in network byte order.
+func (b *RdataBuffer) Uint16(i uint16) (err error) {
+	b.off, err = packUint16(i, b.buf, b.off)
+	return err
+}
+
+// Uint32 packs i in network byte order.
+func (b *RdataBuffer) Uint32(i uint32) (err error) {
+	b.off, err = packUint32(i, b.buf, b.off)
+	return err
+}
+
+// DomainName packs the fully qualified name s, without compression.
+func (b *RdataBuffer) DomainName(s string) (err error) {
+	b.off, err = PackDomainName(s, b.buf, b.off, nil, false)
+	return err
+}
+
+// Bytes packs p as is.
+func (b *RdataBuffer) Bytes(p []byte) error {
+	if b.off+len(p) > len(b.buf) {
+		b.off = len(b.buf)
+		return ErrBuf
+	}
+	b.off += copy(b.buf[b.off:], p)
+	return nil
+}
+
 // PrivateHandle registers a private resource record type. It requires
 // string and numeric representation of private RR type and generator function as argument.
 func PrivateHandle(rtypestr string, rtype uint16, generator func() PrivateRdata) {
@@ -126,7 +174,7 @@ func PrivateHandle(rtypestr string, rtype uint16, generator func() PrivateRdata)
 
 		err := rr.Data.Parse(text)
 		if err != nil {
-			return nil, &ParseError{f, err.Error(), l}, ""
+			return nil, &ParseError{file: f, err: err.Error(), lex: l}, ""
 		}
 
 		return rr, nil, ""
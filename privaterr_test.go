@@ -1,6 +1,9 @@
 package dns_test
 
 import (
+	"bytes"
+	"errors"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -64,6 +67,43 @@ func TestPrivateText(t *testing.T) {
 	}
 }
 
+func TestPrivateHandleRegistry(t *testing.T) {
+	dns.PrivateHandle("ISBN", TypeISBN, NewISBN)
+
+	if _, ok := dns.TypeToRR[TypeISBN]; !ok {
+		t.Error("ISBN not registered in TypeToRR")
+	}
+	if s := dns.TypeToString[TypeISBN]; s != "ISBN" {
+		t.Errorf("expected ISBN in TypeToString, got %q", s)
+	}
+	if typ := dns.StringToType["ISBN"]; typ != TypeISBN {
+		t.Errorf("expected %d in StringToType, got %d", TypeISBN, typ)
+	}
+	if s := dns.Type(TypeISBN).String(); s != "ISBN" {
+		t.Errorf("expected ISBN as type string, got %q", s)
+	}
+	found := false
+	for _, typ := range dns.SupportedTypes() {
+		if typ == TypeISBN {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("ISBN not in supported types")
+	}
+
+	dns.PrivateHandleRemove(TypeISBN)
+	if _, ok := dns.TypeToRR[TypeISBN]; ok {
+		t.Error("ISBN still registered in TypeToRR after removal")
+	}
+	if _, ok := dns.TypeToString[TypeISBN]; ok {
+		t.Error("ISBN still registered in TypeToString after removal")
+	}
+	if _, ok := dns.StringToType["ISBN"]; ok {
+		t.Error("ISBN still registered in StringToType after removal")
+	}
+}
+
 func TestPrivateByteSlice(t *testing.T) {
 	dns.PrivateHandle("ISBN", TypeISBN, NewISBN)
 	defer dns.PrivateHandleRemove(TypeISBN)
@@ -169,3 +209,100 @@ func TestPrivateZoneParser(t *testing.T) {
 		t.Log(x.RR)
 	}
 }
+
+const TypePRIO uint16 = 0x0F03
+
+// PRIO has a priority and a target host, packed with an RdataBuffer.
+type PRIO struct {
+	Priority uint16
+	Target   string
+}
+
+func NewPRIO() dns.PrivateRdata { return new(PRIO) }
+
+func (rd *PRIO) Len() int       { return 2 + len(rd.Target) + 1 }
+func (rd *PRIO) String() string { return strconv.Itoa(int(rd.Priority)) + " " + rd.Target }
+
+func (rd *PRIO) Parse(txt []string) error {
+	if len(txt) != 2 {
+		return errors.New("PRIO needs a priority and a target")
+	}
+	i, err := strconv.ParseUint(txt[0], 10, 16)
+	if err != nil {
+		return err
+	}
+	rd.Priority = uint16(i)
+	rd.Target = dns.Fqdn(txt[1])
+	return nil
+}
+
+func (rd *PRIO) Pack(buf []byte) (int, error) {
+	b := dns.NewRdataBuffer(buf)
+	if err := b.Uint16(rd.Priority); err != nil {
+		return b.Len(), err
+	}
+	if err := b.DomainName(rd.Target); err != nil {
+		return b.Len(), err
+	}
+	return b.Len(), nil
+}
+
+func (rd *PRIO) Unpack(buf []byte) (int, error) {
+	if len(buf) < 2 {
+		return 0, dns.ErrBuf
+	}
+	rd.Priority = uint16(buf[0])<<8 | uint16(buf[1])
+	var (
+		off int
+		err error
+	)
+	rd.Target, off, err = dns.UnpackDomainName(buf, 2)
+	return off, err
+}
+
+func (rd *PRIO) Copy(dest dns.PrivateRdata) error {
+	prio, ok := dest.(*PRIO)
+	if !ok {
+		return dns.ErrRdata
+	}
+	*prio = *rd
+	return nil
+}
+
+func TestPrivateRdataBuffer(t *testing.T) {
+	dns.PrivateHandle("PRIO", TypePRIO, NewPRIO)
+	defer dns.PrivateHandleRemove(TypePRIO)
+
+	rr, err := dns.NewRR("example.org. 3600 IN PRIO 10 host.example.org.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", TypePRIO)
+	m.Answer = []dns.RR{rr}
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(buf, []byte("\x00\x0a\x04host\x07example\x03org\x00")) {
+		t.Errorf("unexpected rdata packed: %x", buf)
+	}
+	m1 := new(dns.Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if m1.Answer[0].String() != rr.String() {
+		t.Errorf("expected %s, got %s", rr, m1.Answer[0])
+	}
+
+	b := dns.NewRdataBuffer(make([]byte, 3))
+	if err := b.Uint16(10); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Uint16(10); err == nil {
+		t.Error("expected an error packing past the end of the buffer")
+	}
+	if err := dns.NewRdataBuffer(make([]byte, 3)).Bytes([]byte("four")); err != dns.ErrBuf {
+		t.Errorf("expected ErrBuf, got %v", err)
+	}
+}
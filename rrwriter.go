@@ -0,0 +1,94 @@
+package dns
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// RRWriter packs RRs, one at a time, in messages that are written to w with a two
+// octet length prefix, as on a TCP connection. It is meant for streaming large
+// answers, like an AXFR, without building and packing one large Msg: an RR is packed
+// in the current message until that is full, the message is then written and a new
+// one started. Each message has the header and question section of the reply it is
+// created with and is compressed on its own. There is no support for TSIG.
+type RRWriter struct {
+	w           io.Writer
+	reply       *Msg
+	out         []byte // length prefix and message
+	buf         []byte // message, out[2:]
+	off         int
+	count       int // RRs in the answer section of the current message
+	compression map[string]int
+}
+
+// NewRRWriter returns an RRWriter that writes to w. The header and question section
+// of each message are copied from reply, its other sections are ignored.
+func NewRRWriter(w io.Writer, reply *Msg) *RRWriter {
+	out := make([]byte, 2+MaxMsgSize)
+	return &RRWriter{w: w, reply: reply, out: out, buf: out[2:]}
+}
+
+// start starts a new message.
+func (rw *RRWriter) start() error {
+	m := &Msg{MsgHdr: rw.reply.MsgHdr, Question: rw.reply.Question}
+	hdr, err := m.Pack()
+	if err != nil {
+		return err
+	}
+	rw.off = copy(rw.buf, hdr[:headerSize])
+	rw.count = 0
+	rw.compression = make(map[string]int)
+	for i := range m.Question {
+		if rw.off, err = m.Question[i].pack(rw.buf, rw.off, rw.compression, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write packs rr in the current message. If rr may not fit, the current message is
+// written first.
+func (rw *RRWriter) Write(rr RR) error {
+	if rw.compression == nil {
+		if err := rw.start(); err != nil {
+			return err
+		}
+	}
+	if rw.count > 0 && rw.off+rr.len() > len(rw.buf) {
+		if err := rw.Flush(); err != nil {
+			return err
+		}
+		if err := rw.start(); err != nil {
+			return err
+		}
+	}
+	off, err := PackRR(rr, rw.buf, rw.off, rw.compression, true)
+	if err != nil {
+		// Names of the partly packed rr are overwritten by the next one.
+		for name, p := range rw.compression {
+			if p >= rw.off {
+				delete(rw.compression, name)
+			}
+		}
+		return err
+	}
+	rw.off = off
+	rw.count++
+	return nil
+}
+
+// Flush writes the current message, if it has any RRs. Call it after writing the
+// last RR.
+func (rw *RRWriter) Flush() error {
+	if rw.count == 0 {
+		return nil
+	}
+	binary.BigEndian.PutUint16(rw.out, uint16(rw.off))
+	binary.BigEndian.PutUint16(rw.buf[6:], uint16(rw.count))
+	if _, err := rw.w.Write(rw.out[:2+rw.off]); err != nil {
+		return err
+	}
+	rw.compression = nil
+	rw.count = 0
+	return nil
+}
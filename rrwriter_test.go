@@ -0,0 +1,97 @@
+package dns
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestRRWriter(t *testing.T) {
+	q := new(Msg)
+	q.SetAxfr("example.org.")
+	r := new(Msg)
+	r.SetReply(q)
+	r.Authoritative = true
+
+	c1, c2 := net.Pipe()
+	const n = 10000
+	go func() {
+		defer c1.Close()
+		w := NewRRWriter(c1, r)
+		for i := 0; i < n; i++ {
+			rr := &A{Hdr: RR_Header{Name: fmt.Sprintf("host%d.example.org.", i), Rrtype: TypeA, Class: ClassINET, Ttl: 3600}, A: net.IPv4(127, 0, byte(i>>8), byte(i))}
+			if err := w.Write(rr); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	co := &Conn{Conn: c2}
+	msgs, i := 0, 0
+	for {
+		m, err := co.ReadMsg()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("failed to read message %d: %v", msgs, err)
+		}
+		msgs++
+		if m.Id != q.Id || !m.Authoritative || len(m.Question) != 1 || m.Question[0] != q.Question[0] {
+			t.Errorf("unexpected header or question in message %d:\n%s", msgs, m.MsgHdr.String())
+		}
+		for _, rr := range m.Answer {
+			if name := fmt.Sprintf("host%d.example.org.", i); rr.Header().Name != name {
+				t.Fatalf("expected RR %d to have name %s, got %s", i, name, rr.Header().Name)
+			}
+			i++
+		}
+	}
+	if i != n {
+		t.Errorf("expected %d RRs, got %d", n, i)
+	}
+	if msgs < 2 {
+		t.Errorf("expected the RRs to be spread over several messages, got %d", msgs)
+	}
+}
+
+func TestRRWriterPackError(t *testing.T) {
+	q := new(Msg)
+	q.SetAxfr("example.org.")
+	r := new(Msg)
+	r.SetReply(q)
+
+	c1, c2 := net.Pipe()
+	go func() {
+		defer c1.Close()
+		w := NewRRWriter(c1, r)
+		// The owner name is packed, and put in the compression map, before the
+		// too long string makes packing fail.
+		bad := &TXT{Hdr: RR_Header{Name: "bad.example.org.", Rrtype: TypeTXT, Class: ClassINET, Ttl: 3600}, Txt: []string{strings.Repeat("x", 256)}}
+		if err := w.Write(bad); err == nil {
+			t.Error("expected error for a TXT string longer than 255 octets")
+		}
+		rr := &A{Hdr: RR_Header{Name: "www.bad.example.org.", Rrtype: TypeA, Class: ClassINET, Ttl: 3600}, A: net.IPv4(127, 0, 0, 1)}
+		if err := w.Write(rr); err != nil {
+			t.Error(err)
+			return
+		}
+		if err := w.Flush(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	co := &Conn{Conn: c2}
+	m, err := co.ReadMsg()
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	if len(m.Answer) != 1 || m.Answer[0].Header().Name != "www.bad.example.org." {
+		t.Errorf("expected only the A record for www.bad.example.org., got %v", m.Answer)
+	}
+}
@@ -1,5 +1,7 @@
 package dns
 
+import "strings"
+
 // Dedup removes identical RRs from rrs. It preserves the original ordering.
 // The lowest TTL of any duplicates is used in the remaining one. Dedup modifies
 // rrs.
@@ -47,6 +49,90 @@ func Dedup(rrs []RR, m map[string]RR) []RR {
 	return rrs[:j]
 }
 
+// ZoneDiff compares two versions of a zone and returns the RRs that are only in new,
+// added, and those that are only in old, removed, in their original order. RRs are
+// compared like Dedup does, so a change in TTL alone is not seen. When the serial
+// was increased the old SOA is removed and the new one added, which makes added and
+// removed the two halves of an IXFR difference sequence.
+func ZoneDiff(old, new []RR) (added, removed []RR) {
+	o := make(map[string]bool, len(old))
+	for _, r := range old {
+		o[normalizedString(r)] = true
+	}
+	n := make(map[string]bool, len(new))
+	for _, r := range new {
+		key := normalizedString(r)
+		n[key] = true
+		if !o[key] {
+			added = append(added, r)
+		}
+	}
+	for _, r := range old {
+		if !n[normalizedString(r)] {
+			removed = append(removed, r)
+		}
+	}
+	return added, removed
+}
+
+// DetectLoop returns true when the CNAME and DNAME records in rrs form a loop, i.e.
+// following the chain from one of the owner names comes back to a name seen before.
+// A chain ends when a DNAME rewrites a name to one longer than 255 octets, which is
+// answered with YXDOMAIN (RFC 6672, section 2.2); this is not a loop, not even for a
+// DNAME that rewrites names to below itself. Other records are ignored.
+func DetectLoop(rrs []RR) bool {
+	cname := make(map[string]string)
+	var dname []*DNAME
+	for _, r := range rrs {
+		switch x := r.(type) {
+		case *CNAME:
+			cname[strings.ToLower(x.Hdr.Name)] = strings.ToLower(x.Target)
+		case *DNAME:
+			dname = append(dname, x)
+		}
+	}
+	next := func(name string) (string, bool) {
+		if t, ok := cname[name]; ok {
+			return t, true
+		}
+		for _, d := range dname {
+			owner := strings.ToLower(d.Hdr.Name)
+			if name != owner && IsSubDomain(owner, name) {
+				return name[:len(name)-len(owner)] + strings.ToLower(d.Target), true
+			}
+		}
+		return "", false
+	}
+	for _, r := range rrs {
+		var name string
+		switch x := r.(type) {
+		case *CNAME:
+			name = strings.ToLower(x.Target)
+		case *DNAME:
+			name = strings.ToLower(x.Target)
+		default:
+			continue
+		}
+		// The chain is a loop when we see a name for the second time.
+		seen := map[string]bool{name: true}
+		for {
+			n, ok := next(name)
+			if !ok {
+				break
+			}
+			if len(n) > maxDomainNameWireOctets {
+				break // YXDOMAIN
+			}
+			if seen[n] {
+				return true
+			}
+			seen[n] = true
+			name = n
+		}
+	}
+	return false
+}
+
 // normalizedString returns a normalized string from r. The TTL
 // is removed and the domain name is lowercased. We go from this:
 // DomainName<TAB>TTL<TAB>CLASS<TAB>TYPE<TAB>RDATA to:
@@ -1,6 +1,9 @@
 package dns
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestDedup(t *testing.T) {
 	// make it []string
@@ -82,3 +85,61 @@ func newRR(t *testing.T, s string) RR {
 	}
 	return r
 }
+
+func TestDetectLoop(t *testing.T) {
+	testcases := []struct {
+		rrs  []string
+		loop bool
+	}{
+		{[]string{"a.example. IN CNAME b.example.", "b.example. IN CNAME c.example.", "c.example. IN A 192.0.2.1"}, false},
+		{[]string{"a.example. IN CNAME b.example.", "b.example. IN CNAME a.example."}, true},
+		{[]string{"a.example. IN CNAME A.example."}, true},
+		{[]string{"www.a.example. IN CNAME www.b.example.", "b.example. IN DNAME a.example."}, true},
+		{[]string{"www.a.example. IN CNAME www.b.example.", "b.example. IN DNAME c.example."}, false},
+		// A name that grows past 255 octets ends the chain with YXDOMAIN.
+		{[]string{"a.example. IN DNAME b.a.example."}, false},
+		{[]string{"www.a.example. IN CNAME www.b.example.", "b.example. IN DNAME " + strings.Repeat(strings.Repeat("x", 60)+".", 4) + "a.example."}, false},
+		{[]string{"x.a.example. IN CNAME x.b.example.", "b.example. IN DNAME c.example.", "x.c.example. IN CNAME y.b.example."}, false},
+	}
+	for i, tc := range testcases {
+		var rrs []RR
+		for _, s := range tc.rrs {
+			rrs = append(rrs, newRR(t, s))
+		}
+		if loop := DetectLoop(rrs); loop != tc.loop {
+			t.Errorf("test %d: expected loop %t, got %t", i, tc.loop, loop)
+		}
+	}
+}
+
+func TestZoneDiff(t *testing.T) {
+	var old, new []RR
+	for _, s := range []string{
+		"example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 3600 600 86400 300",
+		"example.org. 3600 IN NS ns.example.org.",
+		"ns.example.org. 3600 IN A 192.0.2.1",
+		"example.org. 3600 IN TXT \"v=spf1 -all\"",
+	} {
+		old = append(old, newRR(t, s))
+	}
+	for _, s := range []string{
+		"example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 2 3600 600 86400 300",
+		"example.org. 3600 IN NS ns.example.org.",
+		"NS.example.org. 300 IN A 192.0.2.1",
+		"www.example.org. 3600 IN A 192.0.2.2",
+	} {
+		new = append(new, newRR(t, s))
+	}
+
+	added, removed := ZoneDiff(old, new)
+	if len(added) != 2 || added[0] != new[0] || added[1] != new[3] {
+		t.Errorf("expected the new SOA and the www A record to be added, got %v", added)
+	}
+	if len(removed) != 2 || removed[0] != old[0] || removed[1] != old[3] {
+		t.Errorf("expected the old SOA and the TXT record to be removed, got %v", removed)
+	}
+
+	if added, removed := ZoneDiff(old, old); len(added) != 0 || len(removed) != 0 {
+		t.Errorf("expected no difference, got %v added and %v removed", added, removed)
+	}
+}
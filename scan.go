@@ -72,6 +72,10 @@ type ParseError struct {
 	file string
 	err  string
 	lex  lex
+
+	// UnknownType is the offending token when a known RR type was expected, but the
+	// token isn't one, e.g. because of a typo in the mnemonic. It is empty otherwise.
+	UnknownType string
 }
 
 func (e *ParseError) Error() (s string) {
@@ -83,6 +87,16 @@ func (e *ParseError) Error() (s string) {
 	return
 }
 
+// ttlOrTypeError returns the error for a token that should have been a TTL or an
+// RR type, but is neither. When it does not start with a digit, it was probably meant
+// as a type, i.e. the type's mnemonic has a typo.
+func ttlOrTypeError(f string, l lex) *ParseError {
+	if l.token != "" && !isDigit(l.token[0]) {
+		return &ParseError{file: f, err: "unknown RR type", lex: l, UnknownType: l.token}
+	}
+	return &ParseError{file: f, err: "not a TTL", lex: l}
+}
+
 type lex struct {
 	token      string // text of the token
 	tokenUpper string // uppercase text of the token
@@ -191,7 +205,7 @@ func parseZone(r io.Reader, origin, f string, t chan *Token, include int) {
 	}
 	origin = Fqdn(origin)
 	if _, ok := IsDomainName(origin); !ok {
-		t <- &Token{Error: &ParseError{f, "bad initial origin name", lex{}}}
+		t <- &Token{Error: &ParseError{file: f, err: "bad initial origin name"}}
 		return
 	}
 
@@ -202,7 +216,7 @@ func parseZone(r io.Reader, origin, f string, t chan *Token, include int) {
 	for l := range c {
 		// Lexer spotted an error already
 		if l.err == true {
-			t <- &Token{Error: &ParseError{f, l.token, l}}
+			t <- &Token{Error: &ParseError{file: f, err: l.token, lex: l}}
 			return
 
 		}
@@ -227,7 +241,7 @@ func parseZone(r io.Reader, origin, f string, t chan *Token, include int) {
 				}
 				_, ok := IsDomainName(l.token)
 				if !ok {
-					t <- &Token{Error: &ParseError{f, "bad owner name", l}}
+					t <- &Token{Error: &ParseError{file: f, err: "bad owner name", lex: l}}
 					return
 				}
 				prevName = h.Name
@@ -254,7 +268,7 @@ func parseZone(r io.Reader, origin, f string, t chan *Token, include int) {
 			case zString:
 				ttl, ok := stringToTtl(l.token)
 				if !ok {
-					t <- &Token{Error: &ParseError{f, "not a TTL", l}}
+					t <- &Token{Error: ttlOrTypeError(f, l)}
 					return
 				}
 				h.Ttl = ttl
@@ -263,18 +277,18 @@ func parseZone(r io.Reader, origin, f string, t chan *Token, include int) {
 				st = zExpectAnyNoTtlBl
 
 			default:
-				t <- &Token{Error: &ParseError{f, "syntax error at beginning", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "syntax error at beginning", lex: l}}
 				return
 			}
 		case zExpectDirIncludeBl:
 			if l.value != zBlank {
-				t <- &Token{Error: &ParseError{f, "no blank after $INCLUDE-directive", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "no blank after $INCLUDE-directive", lex: l}}
 				return
 			}
 			st = zExpectDirInclude
 		case zExpectDirInclude:
 			if l.value != zString {
-				t <- &Token{Error: &ParseError{f, "expecting $INCLUDE value, not this...", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "expecting $INCLUDE value, not this...", lex: l}}
 				return
 			}
 			neworigin := origin // There may be optionally a new origin set after the filename, if not use current one
@@ -284,7 +298,7 @@ func parseZone(r io.Reader, origin, f string, t chan *Token, include int) {
 				l := <-c
 				if l.value == zString {
 					if _, ok := IsDomainName(l.token); !ok || l.length == 0 || l.err {
-						t <- &Token{Error: &ParseError{f, "bad origin name", l}}
+						t <- &Token{Error: &ParseError{file: f, err: "bad origin name", lex: l}}
 						return
 					}
 					// a new origin is specified.
@@ -301,30 +315,30 @@ func parseZone(r io.Reader, origin, f string, t chan *Token, include int) {
 			case zNewline, zEOF:
 				// Ok
 			default:
-				t <- &Token{Error: &ParseError{f, "garbage after $INCLUDE", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "garbage after $INCLUDE", lex: l}}
 				return
 			}
 			// Start with the new file
 			r1, e1 := os.Open(l.token)
 			if e1 != nil {
-				t <- &Token{Error: &ParseError{f, "failed to open `" + l.token + "'", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "failed to open `" + l.token + "'", lex: l}}
 				return
 			}
 			if include+1 > 7 {
-				t <- &Token{Error: &ParseError{f, "too deeply nested $INCLUDE", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "too deeply nested $INCLUDE", lex: l}}
 				return
 			}
 			parseZone(r1, l.token, neworigin, t, include+1)
 			st = zExpectOwnerDir
 		case zExpectDirTtlBl:
 			if l.value != zBlank {
-				t <- &Token{Error: &ParseError{f, "no blank after $TTL-directive", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "no blank after $TTL-directive", lex: l}}
 				return
 			}
 			st = zExpectDirTtl
 		case zExpectDirTtl:
 			if l.value != zString {
-				t <- &Token{Error: &ParseError{f, "expecting $TTL value, not this...", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "expecting $TTL value, not this...", lex: l}}
 				return
 			}
 			if e, _ := slurpRemainder(c, f); e != nil {
@@ -333,27 +347,27 @@ func parseZone(r io.Reader, origin, f string, t chan *Token, include int) {
 			}
 			ttl, ok := stringToTtl(l.token)
 			if !ok {
-				t <- &Token{Error: &ParseError{f, "expecting $TTL value, not this...", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "expecting $TTL value, not this...", lex: l}}
 				return
 			}
 			defttl = ttl
 			st = zExpectOwnerDir
 		case zExpectDirOriginBl:
 			if l.value != zBlank {
-				t <- &Token{Error: &ParseError{f, "no blank after $ORIGIN-directive", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "no blank after $ORIGIN-directive", lex: l}}
 				return
 			}
 			st = zExpectDirOrigin
 		case zExpectDirOrigin:
 			if l.value != zString {
-				t <- &Token{Error: &ParseError{f, "expecting $ORIGIN value, not this...", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "expecting $ORIGIN value, not this...", lex: l}}
 				return
 			}
 			if e, _ := slurpRemainder(c, f); e != nil {
 				t <- &Token{Error: e}
 			}
 			if _, ok := IsDomainName(l.token); !ok {
-				t <- &Token{Error: &ParseError{f, "bad origin name", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "bad origin name", lex: l}}
 				return
 			}
 			if l.token[l.length-1] != '.' {
@@ -368,23 +382,23 @@ func parseZone(r io.Reader, origin, f string, t chan *Token, include int) {
 			st = zExpectOwnerDir
 		case zExpectDirGenerateBl:
 			if l.value != zBlank {
-				t <- &Token{Error: &ParseError{f, "no blank after $GENERATE-directive", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "no blank after $GENERATE-directive", lex: l}}
 				return
 			}
 			st = zExpectDirGenerate
 		case zExpectDirGenerate:
 			if l.value != zString {
-				t <- &Token{Error: &ParseError{f, "expecting $GENERATE value, not this...", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "expecting $GENERATE value, not this...", lex: l}}
 				return
 			}
 			if errMsg := generate(l, c, t, origin); errMsg != "" {
-				t <- &Token{Error: &ParseError{f, errMsg, l}}
+				t <- &Token{Error: &ParseError{file: f, err: errMsg, lex: l}}
 				return
 			}
 			st = zExpectOwnerDir
 		case zExpectOwnerBl:
 			if l.value != zBlank {
-				t <- &Token{Error: &ParseError{f, "no blank after owner", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "no blank after owner", lex: l}}
 				return
 			}
 			st = zExpectAny
@@ -399,25 +413,25 @@ func parseZone(r io.Reader, origin, f string, t chan *Token, include int) {
 			case zString:
 				ttl, ok := stringToTtl(l.token)
 				if !ok {
-					t <- &Token{Error: &ParseError{f, "not a TTL", l}}
+					t <- &Token{Error: ttlOrTypeError(f, l)}
 					return
 				}
 				h.Ttl = ttl
 				// defttl = ttl // don't set the defttl here
 				st = zExpectAnyNoTtlBl
 			default:
-				t <- &Token{Error: &ParseError{f, "expecting RR type, TTL or class, not this...", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "expecting RR type, TTL or class, not this...", lex: l}}
 				return
 			}
 		case zExpectAnyNoClassBl:
 			if l.value != zBlank {
-				t <- &Token{Error: &ParseError{f, "no blank before class", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "no blank before class", lex: l}}
 				return
 			}
 			st = zExpectAnyNoClass
 		case zExpectAnyNoTtlBl:
 			if l.value != zBlank {
-				t <- &Token{Error: &ParseError{f, "no blank before TTL", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "no blank before TTL", lex: l}}
 				return
 			}
 			st = zExpectAnyNoTtl
@@ -430,7 +444,7 @@ func parseZone(r io.Reader, origin, f string, t chan *Token, include int) {
 				h.Rrtype = l.torc
 				st = zExpectRdata
 			default:
-				t <- &Token{Error: &ParseError{f, "expecting RR type or class, not this...", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "expecting RR type or class, not this...", lex: l}}
 				return
 			}
 		case zExpectAnyNoClass:
@@ -438,7 +452,7 @@ func parseZone(r io.Reader, origin, f string, t chan *Token, include int) {
 			case zString:
 				ttl, ok := stringToTtl(l.token)
 				if !ok {
-					t <- &Token{Error: &ParseError{f, "not a TTL", l}}
+					t <- &Token{Error: ttlOrTypeError(f, l)}
 					return
 				}
 				h.Ttl = ttl
@@ -448,18 +462,18 @@ func parseZone(r io.Reader, origin, f string, t chan *Token, include int) {
 				h.Rrtype = l.torc
 				st = zExpectRdata
 			default:
-				t <- &Token{Error: &ParseError{f, "expecting RR type or TTL, not this...", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "expecting RR type or TTL, not this...", lex: l}}
 				return
 			}
 		case zExpectRrtypeBl:
 			if l.value != zBlank {
-				t <- &Token{Error: &ParseError{f, "no blank before RR type", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "no blank before RR type", lex: l}}
 				return
 			}
 			st = zExpectRrtype
 		case zExpectRrtype:
 			if l.value != zRrtpe {
-				t <- &Token{Error: &ParseError{f, "unknown RR type", l}}
+				t <- &Token{Error: &ParseError{file: f, err: "unknown RR type", lex: l, UnknownType: l.token}}
 				return
 			}
 			h.Rrtype = l.torc
@@ -832,6 +846,20 @@ func typeToInt(token string) (uint16, bool) {
 	return uint16(typ), true
 }
 
+// StringToTTL parses a TTL as used in zone files, it is either a bare integer or
+// made up of numbers with the suffixes w, d, h, m and s, for instance 1h30m.
+// The TTL is returned in seconds.
+func StringToTTL(s string) (uint32, error) {
+	if s == "" {
+		return 0, &Error{err: "empty TTL"}
+	}
+	ttl, ok := stringToTtl(s)
+	if !ok {
+		return 0, &Error{err: "bad TTL: " + s}
+	}
+	return ttl, nil
+}
+
 // Parse things like 2w, 2m, etc, Return the time in seconds.
 func stringToTtl(token string) (uint32, bool) {
 	s := uint32(0)
@@ -944,13 +972,13 @@ func slurpRemainder(c chan lex, f string) (*ParseError, string) {
 		l = <-c
 		com = l.comment
 		if l.value != zNewline && l.value != zEOF {
-			return &ParseError{f, "garbage after rdata", l}, ""
+			return &ParseError{file: f, err: "garbage after rdata", lex: l}, ""
 		}
 	case zNewline:
 		com = l.comment
 	case zEOF:
 	default:
-		return &ParseError{f, "garbage after rdata", l}, ""
+		return &ParseError{file: f, err: "garbage after rdata", lex: l}, ""
 	}
 	return nil, com
 }
@@ -959,16 +987,16 @@ func slurpRemainder(c chan lex, f string) (*ParseError, string) {
 // Used for NID and L64 record.
 func stringToNodeID(l lex) (uint64, *ParseError) {
 	if len(l.token) < 19 {
-		return 0, &ParseError{l.token, "bad NID/L64 NodeID/Locator64", l}
+		return 0, &ParseError{file: l.token, err: "bad NID/L64 NodeID/Locator64", lex: l}
 	}
 	// There must be three colons at fixes postitions, if not its a parse error
 	if l.token[4] != ':' && l.token[9] != ':' && l.token[14] != ':' {
-		return 0, &ParseError{l.token, "bad NID/L64 NodeID/Locator64", l}
+		return 0, &ParseError{file: l.token, err: "bad NID/L64 NodeID/Locator64", lex: l}
 	}
 	s := l.token[0:4] + l.token[5:9] + l.token[10:14] + l.token[15:19]
 	u, err := strconv.ParseUint(s, 16, 64)
 	if err != nil {
-		return 0, &ParseError{l.token, "bad NID/L64 NodeID/Locator64", l}
+		return 0, &ParseError{file: l.token, err: "bad NID/L64 NodeID/Locator64", lex: l}
 	}
 	return u, nil
 }
@@ -50,14 +50,14 @@ func endingToString(c chan lex, errstr, f string) (string, *ParseError, string)
 	l := <-c // zString
 	for l.value != zNewline && l.value != zEOF {
 		if l.err {
-			return s, &ParseError{f, errstr, l}, ""
+			return s, &ParseError{file: f, err: errstr, lex: l}, ""
 		}
 		switch l.value {
 		case zString:
 			s += l.token
 		case zBlank: // Ok
 		default:
-			return "", &ParseError{f, errstr, l}, ""
+			return "", &ParseError{file: f, err: errstr, lex: l}, ""
 		}
 		l = <-c
 	}
@@ -72,7 +72,7 @@ func endingToTxtSlice(c chan lex, errstr, f string) ([]string, *ParseError, stri
 	l := <-c
 	var s []string
 	if l.err {
-		return s, &ParseError{f, errstr, l}, ""
+		return s, &ParseError{file: f, err: errstr, lex: l}, ""
 	}
 	switch l.value == zQuote {
 	case true: // A number of quoted string
@@ -80,7 +80,7 @@ func endingToTxtSlice(c chan lex, errstr, f string) ([]string, *ParseError, stri
 		empty := true
 		for l.value != zNewline && l.value != zEOF {
 			if l.err {
-				return nil, &ParseError{f, errstr, l}, ""
+				return nil, &ParseError{file: f, err: errstr, lex: l}, ""
 			}
 			switch l.value {
 			case zString:
@@ -107,7 +107,7 @@ func endingToTxtSlice(c chan lex, errstr, f string) ([]string, *ParseError, stri
 			case zBlank:
 				if quote {
 					// zBlank can only be seen in between txt parts.
-					return nil, &ParseError{f, errstr, l}, ""
+					return nil, &ParseError{file: f, err: errstr, lex: l}, ""
 				}
 			case zQuote:
 				if empty && quote {
@@ -116,18 +116,18 @@ func endingToTxtSlice(c chan lex, errstr, f string) ([]string, *ParseError, stri
 				quote = !quote
 				empty = true
 			default:
-				return nil, &ParseError{f, errstr, l}, ""
+				return nil, &ParseError{file: f, err: errstr, lex: l}, ""
 			}
 			l = <-c
 		}
 		if quote {
-			return nil, &ParseError{f, errstr, l}, ""
+			return nil, &ParseError{file: f, err: errstr, lex: l}, ""
 		}
 	case false: // Unquoted text record
 		s = make([]string, 1)
 		for l.value != zNewline && l.value != zEOF {
 			if l.err {
-				return s, &ParseError{f, errstr, l}, ""
+				return s, &ParseError{file: f, err: errstr, lex: l}, ""
 			}
 			s[0] += l.token
 			l = <-c
@@ -146,7 +146,7 @@ func setA(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	rr.A = net.ParseIP(l.token)
 	if rr.A == nil || l.err {
-		return nil, &ParseError{f, "bad A A", l}, ""
+		return nil, &ParseError{file: f, err: "bad A A", lex: l}, ""
 	}
 	return rr, nil, ""
 }
@@ -161,7 +161,7 @@ func setAAAA(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	rr.AAAA = net.ParseIP(l.token)
 	if rr.AAAA == nil || l.err {
-		return nil, &ParseError{f, "bad AAAA AAAA", l}, ""
+		return nil, &ParseError{file: f, err: "bad AAAA AAAA", lex: l}, ""
 	}
 	return rr, nil, ""
 }
@@ -181,7 +181,7 @@ func setNS(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad NS Ns", l}, ""
+		return nil, &ParseError{file: f, err: "bad NS Ns", lex: l}, ""
 	}
 	if rr.Ns[l.length-1] != '.' {
 		rr.Ns = appendOrigin(rr.Ns, o)
@@ -204,7 +204,7 @@ func setPTR(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad PTR Ptr", l}, ""
+		return nil, &ParseError{file: f, err: "bad PTR Ptr", lex: l}, ""
 	}
 	if rr.Ptr[l.length-1] != '.' {
 		rr.Ptr = appendOrigin(rr.Ptr, o)
@@ -227,7 +227,7 @@ func setNSAPPTR(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string)
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad NSAP-PTR Ptr", l}, ""
+		return nil, &ParseError{file: f, err: "bad NSAP-PTR Ptr", lex: l}, ""
 	}
 	if rr.Ptr[l.length-1] != '.' {
 		rr.Ptr = appendOrigin(rr.Ptr, o)
@@ -249,7 +249,7 @@ func setRP(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	} else {
 		_, ok := IsDomainName(l.token)
 		if !ok || l.length == 0 || l.err {
-			return nil, &ParseError{f, "bad RP Mbox", l}, ""
+			return nil, &ParseError{file: f, err: "bad RP Mbox", lex: l}, ""
 		}
 		if rr.Mbox[l.length-1] != '.' {
 			rr.Mbox = appendOrigin(rr.Mbox, o)
@@ -264,7 +264,7 @@ func setRP(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad RP Txt", l}, ""
+		return nil, &ParseError{file: f, err: "bad RP Txt", lex: l}, ""
 	}
 	if rr.Txt[l.length-1] != '.' {
 		rr.Txt = appendOrigin(rr.Txt, o)
@@ -287,7 +287,7 @@ func setMR(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad MR Mr", l}, ""
+		return nil, &ParseError{file: f, err: "bad MR Mr", lex: l}, ""
 	}
 	if rr.Mr[l.length-1] != '.' {
 		rr.Mr = appendOrigin(rr.Mr, o)
@@ -310,7 +310,7 @@ func setMB(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad MB Mb", l}, ""
+		return nil, &ParseError{file: f, err: "bad MB Mb", lex: l}, ""
 	}
 	if rr.Mb[l.length-1] != '.' {
 		rr.Mb = appendOrigin(rr.Mb, o)
@@ -333,7 +333,7 @@ func setMG(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad MG Mg", l}, ""
+		return nil, &ParseError{file: f, err: "bad MG Mg", lex: l}, ""
 	}
 	if rr.Mg[l.length-1] != '.' {
 		rr.Mg = appendOrigin(rr.Mg, o)
@@ -381,7 +381,7 @@ func setMINFO(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	} else {
 		_, ok := IsDomainName(l.token)
 		if !ok || l.length == 0 || l.err {
-			return nil, &ParseError{f, "bad MINFO Rmail", l}, ""
+			return nil, &ParseError{file: f, err: "bad MINFO Rmail", lex: l}, ""
 		}
 		if rr.Rmail[l.length-1] != '.' {
 			rr.Rmail = appendOrigin(rr.Rmail, o)
@@ -396,7 +396,7 @@ func setMINFO(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad MINFO Email", l}, ""
+		return nil, &ParseError{file: f, err: "bad MINFO Email", lex: l}, ""
 	}
 	if rr.Email[l.length-1] != '.' {
 		rr.Email = appendOrigin(rr.Email, o)
@@ -419,7 +419,7 @@ func setMF(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad MF Mf", l}, ""
+		return nil, &ParseError{file: f, err: "bad MF Mf", lex: l}, ""
 	}
 	if rr.Mf[l.length-1] != '.' {
 		rr.Mf = appendOrigin(rr.Mf, o)
@@ -442,7 +442,7 @@ func setMD(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad MD Md", l}, ""
+		return nil, &ParseError{file: f, err: "bad MD Md", lex: l}, ""
 	}
 	if rr.Md[l.length-1] != '.' {
 		rr.Md = appendOrigin(rr.Md, o)
@@ -460,7 +460,7 @@ func setMX(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad MX Pref", l}, ""
+		return nil, &ParseError{file: f, err: "bad MX Pref", lex: l}, ""
 	}
 	rr.Preference = uint16(i)
 	<-c     // zBlank
@@ -472,7 +472,7 @@ func setMX(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad MX Mx", l}, ""
+		return nil, &ParseError{file: f, err: "bad MX Mx", lex: l}, ""
 	}
 	if rr.Mx[l.length-1] != '.' {
 		rr.Mx = appendOrigin(rr.Mx, o)
@@ -489,7 +489,7 @@ func setRT(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil {
-		return nil, &ParseError{f, "bad RT Preference", l}, ""
+		return nil, &ParseError{file: f, err: "bad RT Preference", lex: l}, ""
 	}
 	rr.Preference = uint16(i)
 	<-c     // zBlank
@@ -501,7 +501,7 @@ func setRT(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad RT Host", l}, ""
+		return nil, &ParseError{file: f, err: "bad RT Host", lex: l}, ""
 	}
 	if rr.Host[l.length-1] != '.' {
 		rr.Host = appendOrigin(rr.Host, o)
@@ -519,7 +519,7 @@ func setAFSDB(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad AFSDB Subtype", l}, ""
+		return nil, &ParseError{file: f, err: "bad AFSDB Subtype", lex: l}, ""
 	}
 	rr.Subtype = uint16(i)
 	<-c     // zBlank
@@ -531,7 +531,7 @@ func setAFSDB(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad AFSDB Hostname", l}, ""
+		return nil, &ParseError{file: f, err: "bad AFSDB Hostname", lex: l}, ""
 	}
 	if rr.Hostname[l.length-1] != '.' {
 		rr.Hostname = appendOrigin(rr.Hostname, o)
@@ -548,7 +548,7 @@ func setX25(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 		return rr, nil, ""
 	}
 	if l.err {
-		return nil, &ParseError{f, "bad X25 PSDNAddress", l}, ""
+		return nil, &ParseError{file: f, err: "bad X25 PSDNAddress", lex: l}, ""
 	}
 	rr.PSDNAddress = l.token
 	return rr, nil, ""
@@ -564,7 +564,7 @@ func setKX(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad KX Pref", l}, ""
+		return nil, &ParseError{file: f, err: "bad KX Pref", lex: l}, ""
 	}
 	rr.Preference = uint16(i)
 	<-c     // zBlank
@@ -576,7 +576,7 @@ func setKX(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad KX Exchanger", l}, ""
+		return nil, &ParseError{file: f, err: "bad KX Exchanger", lex: l}, ""
 	}
 	if rr.Exchanger[l.length-1] != '.' {
 		rr.Exchanger = appendOrigin(rr.Exchanger, o)
@@ -599,7 +599,7 @@ func setCNAME(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad CNAME Target", l}, ""
+		return nil, &ParseError{file: f, err: "bad CNAME Target", lex: l}, ""
 	}
 	if rr.Target[l.length-1] != '.' {
 		rr.Target = appendOrigin(rr.Target, o)
@@ -622,7 +622,7 @@ func setDNAME(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad CNAME Target", l}, ""
+		return nil, &ParseError{file: f, err: "bad CNAME Target", lex: l}, ""
 	}
 	if rr.Target[l.length-1] != '.' {
 		rr.Target = appendOrigin(rr.Target, o)
@@ -645,7 +645,7 @@ func setSOA(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	} else {
 		_, ok := IsDomainName(l.token)
 		if !ok || l.length == 0 || l.err {
-			return nil, &ParseError{f, "bad SOA Ns", l}, ""
+			return nil, &ParseError{file: f, err: "bad SOA Ns", lex: l}, ""
 		}
 		if rr.Ns[l.length-1] != '.' {
 			rr.Ns = appendOrigin(rr.Ns, o)
@@ -659,7 +659,7 @@ func setSOA(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	} else {
 		_, ok := IsDomainName(l.token)
 		if !ok || l.length == 0 || l.err {
-			return nil, &ParseError{f, "bad SOA Mbox", l}, ""
+			return nil, &ParseError{file: f, err: "bad SOA Mbox", lex: l}, ""
 		}
 		if rr.Mbox[l.length-1] != '.' {
 			rr.Mbox = appendOrigin(rr.Mbox, o)
@@ -674,15 +674,15 @@ func setSOA(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	for i := 0; i < 5; i++ {
 		l = <-c
 		if l.err {
-			return nil, &ParseError{f, "bad SOA zone parameter", l}, ""
+			return nil, &ParseError{file: f, err: "bad SOA zone parameter", lex: l}, ""
 		}
 		if j, e := strconv.Atoi(l.token); e != nil {
 			if i == 0 {
 				// Serial should be a number
-				return nil, &ParseError{f, "bad SOA zone parameter", l}, ""
+				return nil, &ParseError{file: f, err: "bad SOA zone parameter", lex: l}, ""
 			}
 			if v, ok = stringToTtl(l.token); !ok {
-				return nil, &ParseError{f, "bad SOA zone parameter", l}, ""
+				return nil, &ParseError{file: f, err: "bad SOA zone parameter", lex: l}, ""
 
 			}
 		} else {
@@ -718,21 +718,21 @@ func setSRV(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad SRV Priority", l}, ""
+		return nil, &ParseError{file: f, err: "bad SRV Priority", lex: l}, ""
 	}
 	rr.Priority = uint16(i)
 	<-c     // zBlank
 	l = <-c // zString
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad SRV Weight", l}, ""
+		return nil, &ParseError{file: f, err: "bad SRV Weight", lex: l}, ""
 	}
 	rr.Weight = uint16(i)
 	<-c     // zBlank
 	l = <-c // zString
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad SRV Port", l}, ""
+		return nil, &ParseError{file: f, err: "bad SRV Port", lex: l}, ""
 	}
 	rr.Port = uint16(i)
 	<-c     // zBlank
@@ -744,7 +744,7 @@ func setSRV(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad SRV Target", l}, ""
+		return nil, &ParseError{file: f, err: "bad SRV Target", lex: l}, ""
 	}
 	if rr.Target[l.length-1] != '.' {
 		rr.Target = appendOrigin(rr.Target, o)
@@ -762,71 +762,71 @@ func setNAPTR(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad NAPTR Order", l}, ""
+		return nil, &ParseError{file: f, err: "bad NAPTR Order", lex: l}, ""
 	}
 	rr.Order = uint16(i)
 	<-c     // zBlank
 	l = <-c // zString
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad NAPTR Preference", l}, ""
+		return nil, &ParseError{file: f, err: "bad NAPTR Preference", lex: l}, ""
 	}
 	rr.Preference = uint16(i)
 	// Flags
 	<-c     // zBlank
 	l = <-c // _QUOTE
 	if l.value != zQuote {
-		return nil, &ParseError{f, "bad NAPTR Flags", l}, ""
+		return nil, &ParseError{file: f, err: "bad NAPTR Flags", lex: l}, ""
 	}
 	l = <-c // Either String or Quote
 	if l.value == zString {
 		rr.Flags = l.token
 		l = <-c // _QUOTE
 		if l.value != zQuote {
-			return nil, &ParseError{f, "bad NAPTR Flags", l}, ""
+			return nil, &ParseError{file: f, err: "bad NAPTR Flags", lex: l}, ""
 		}
 	} else if l.value == zQuote {
 		rr.Flags = ""
 	} else {
-		return nil, &ParseError{f, "bad NAPTR Flags", l}, ""
+		return nil, &ParseError{file: f, err: "bad NAPTR Flags", lex: l}, ""
 	}
 
 	// Service
 	<-c     // zBlank
 	l = <-c // _QUOTE
 	if l.value != zQuote {
-		return nil, &ParseError{f, "bad NAPTR Service", l}, ""
+		return nil, &ParseError{file: f, err: "bad NAPTR Service", lex: l}, ""
 	}
 	l = <-c // Either String or Quote
 	if l.value == zString {
 		rr.Service = l.token
 		l = <-c // _QUOTE
 		if l.value != zQuote {
-			return nil, &ParseError{f, "bad NAPTR Service", l}, ""
+			return nil, &ParseError{file: f, err: "bad NAPTR Service", lex: l}, ""
 		}
 	} else if l.value == zQuote {
 		rr.Service = ""
 	} else {
-		return nil, &ParseError{f, "bad NAPTR Service", l}, ""
+		return nil, &ParseError{file: f, err: "bad NAPTR Service", lex: l}, ""
 	}
 
 	// Regexp
 	<-c     // zBlank
 	l = <-c // _QUOTE
 	if l.value != zQuote {
-		return nil, &ParseError{f, "bad NAPTR Regexp", l}, ""
+		return nil, &ParseError{file: f, err: "bad NAPTR Regexp", lex: l}, ""
 	}
 	l = <-c // Either String or Quote
 	if l.value == zString {
 		rr.Regexp = l.token
 		l = <-c // _QUOTE
 		if l.value != zQuote {
-			return nil, &ParseError{f, "bad NAPTR Regexp", l}, ""
+			return nil, &ParseError{file: f, err: "bad NAPTR Regexp", lex: l}, ""
 		}
 	} else if l.value == zQuote {
 		rr.Regexp = ""
 	} else {
-		return nil, &ParseError{f, "bad NAPTR Regexp", l}, ""
+		return nil, &ParseError{file: f, err: "bad NAPTR Regexp", lex: l}, ""
 	}
 	// After quote no space??
 	<-c     // zBlank
@@ -838,7 +838,7 @@ func setNAPTR(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad NAPTR Replacement", l}, ""
+		return nil, &ParseError{file: f, err: "bad NAPTR Replacement", lex: l}, ""
 	}
 	if rr.Replacement[l.length-1] != '.' {
 		rr.Replacement = appendOrigin(rr.Replacement, o)
@@ -860,7 +860,7 @@ func setTALINK(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	} else {
 		_, ok := IsDomainName(l.token)
 		if !ok || l.length == 0 || l.err {
-			return nil, &ParseError{f, "bad TALINK PreviousName", l}, ""
+			return nil, &ParseError{file: f, err: "bad TALINK PreviousName", lex: l}, ""
 		}
 		if rr.PreviousName[l.length-1] != '.' {
 			rr.PreviousName = appendOrigin(rr.PreviousName, o)
@@ -875,7 +875,7 @@ func setTALINK(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad TALINK NextName", l}, ""
+		return nil, &ParseError{file: f, err: "bad TALINK NextName", lex: l}, ""
 	}
 	if rr.NextName[l.length-1] != '.' {
 		rr.NextName = appendOrigin(rr.NextName, o)
@@ -898,7 +898,7 @@ func setLOC(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad LOC Latitude", l}, ""
+		return nil, &ParseError{file: f, err: "bad LOC Latitude", lex: l}, ""
 	}
 	rr.Latitude = 1000 * 60 * 60 * uint32(i)
 
@@ -910,14 +910,14 @@ func setLOC(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad LOC Latitude minutes", l}, ""
+		return nil, &ParseError{file: f, err: "bad LOC Latitude minutes", lex: l}, ""
 	}
 	rr.Latitude += 1000 * 60 * uint32(i)
 
 	<-c // zBlank
 	l = <-c
 	if i, e := strconv.ParseFloat(l.token, 32); e != nil || l.err {
-		return nil, &ParseError{f, "bad LOC Latitude seconds", l}, ""
+		return nil, &ParseError{file: f, err: "bad LOC Latitude seconds", lex: l}, ""
 	} else {
 		rr.Latitude += uint32(1000 * i)
 	}
@@ -928,14 +928,14 @@ func setLOC(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 		goto East
 	}
 	// If still alive, flag an error
-	return nil, &ParseError{f, "bad LOC Latitude North/South", l}, ""
+	return nil, &ParseError{file: f, err: "bad LOC Latitude North/South", lex: l}, ""
 
 East:
 	// East
 	<-c // zBlank
 	l = <-c
 	if i, e := strconv.Atoi(l.token); e != nil || l.err {
-		return nil, &ParseError{f, "bad LOC Longitude", l}, ""
+		return nil, &ParseError{file: f, err: "bad LOC Longitude", lex: l}, ""
 	} else {
 		rr.Longitude = 1000 * 60 * 60 * uint32(i)
 	}
@@ -946,14 +946,14 @@ East:
 		goto Altitude
 	}
 	if i, e := strconv.Atoi(l.token); e != nil || l.err {
-		return nil, &ParseError{f, "bad LOC Longitude minutes", l}, ""
+		return nil, &ParseError{file: f, err: "bad LOC Longitude minutes", lex: l}, ""
 	} else {
 		rr.Longitude += 1000 * 60 * uint32(i)
 	}
 	<-c // zBlank
 	l = <-c
 	if i, e := strconv.ParseFloat(l.token, 32); e != nil || l.err {
-		return nil, &ParseError{f, "bad LOC Longitude seconds", l}, ""
+		return nil, &ParseError{file: f, err: "bad LOC Longitude seconds", lex: l}, ""
 	} else {
 		rr.Longitude += uint32(1000 * i)
 	}
@@ -964,19 +964,19 @@ East:
 		goto Altitude
 	}
 	// If still alive, flag an error
-	return nil, &ParseError{f, "bad LOC Longitude East/West", l}, ""
+	return nil, &ParseError{file: f, err: "bad LOC Longitude East/West", lex: l}, ""
 
 Altitude:
 	<-c // zBlank
 	l = <-c
 	if l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad LOC Altitude", l}, ""
+		return nil, &ParseError{file: f, err: "bad LOC Altitude", lex: l}, ""
 	}
 	if l.token[len(l.token)-1] == 'M' || l.token[len(l.token)-1] == 'm' {
 		l.token = l.token[0 : len(l.token)-1]
 	}
 	if i, e := strconv.ParseFloat(l.token, 32); e != nil {
-		return nil, &ParseError{f, "bad LOC Altitude", l}, ""
+		return nil, &ParseError{file: f, err: "bad LOC Altitude", lex: l}, ""
 	} else {
 		rr.Altitude = uint32(i*100.0 + 10000000.0 + 0.5)
 	}
@@ -991,19 +991,19 @@ Altitude:
 			case 0: // Size
 				e, m, ok := stringToCm(l.token)
 				if !ok {
-					return nil, &ParseError{f, "bad LOC Size", l}, ""
+					return nil, &ParseError{file: f, err: "bad LOC Size", lex: l}, ""
 				}
 				rr.Size = (e & 0x0f) | (m << 4 & 0xf0)
 			case 1: // HorizPre
 				e, m, ok := stringToCm(l.token)
 				if !ok {
-					return nil, &ParseError{f, "bad LOC HorizPre", l}, ""
+					return nil, &ParseError{file: f, err: "bad LOC HorizPre", lex: l}, ""
 				}
 				rr.HorizPre = (e & 0x0f) | (m << 4 & 0xf0)
 			case 2: // VertPre
 				e, m, ok := stringToCm(l.token)
 				if !ok {
-					return nil, &ParseError{f, "bad LOC VertPre", l}, ""
+					return nil, &ParseError{file: f, err: "bad LOC VertPre", lex: l}, ""
 				}
 				rr.VertPre = (e & 0x0f) | (m << 4 & 0xf0)
 			}
@@ -1011,7 +1011,7 @@ Altitude:
 		case zBlank:
 			// Ok
 		default:
-			return nil, &ParseError{f, "bad LOC Size, HorizPre or VertPre", l}, ""
+			return nil, &ParseError{file: f, err: "bad LOC Size, HorizPre or VertPre", lex: l}, ""
 		}
 		l = <-c
 	}
@@ -1029,13 +1029,13 @@ func setHIP(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad HIP PublicKeyAlgorithm", l}, ""
+		return nil, &ParseError{file: f, err: "bad HIP PublicKeyAlgorithm", lex: l}, ""
 	}
 	rr.PublicKeyAlgorithm = uint8(i)
 	<-c     // zBlank
 	l = <-c // zString
 	if l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad HIP Hit", l}, ""
+		return nil, &ParseError{file: f, err: "bad HIP Hit", lex: l}, ""
 	}
 	rr.Hit = l.token // This can not contain spaces, see RFC 5205 Section 6.
 	rr.HitLength = uint8(len(rr.Hit)) / 2
@@ -1043,7 +1043,7 @@ func setHIP(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	<-c     // zBlank
 	l = <-c // zString
 	if l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad HIP PublicKey", l}, ""
+		return nil, &ParseError{file: f, err: "bad HIP PublicKey", lex: l}, ""
 	}
 	rr.PublicKey = l.token // This cannot contain spaces
 	rr.PublicKeyLength = uint16(base64.StdEncoding.DecodedLen(len(rr.PublicKey)))
@@ -1061,7 +1061,7 @@ func setHIP(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 			}
 			_, ok := IsDomainName(l.token)
 			if !ok || l.length == 0 || l.err {
-				return nil, &ParseError{f, "bad HIP RendezvousServers", l}, ""
+				return nil, &ParseError{file: f, err: "bad HIP RendezvousServers", lex: l}, ""
 			}
 			if l.token[l.length-1] != '.' {
 				l.token = appendOrigin(l.token, o)
@@ -1070,7 +1070,7 @@ func setHIP(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 		case zBlank:
 			// Ok
 		default:
-			return nil, &ParseError{f, "bad HIP RendezvousServers", l}, ""
+			return nil, &ParseError{file: f, err: "bad HIP RendezvousServers", lex: l}, ""
 		}
 		l = <-c
 	}
@@ -1089,7 +1089,7 @@ func setCERT(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	if v, ok := StringToCertType[l.token]; ok {
 		rr.Type = v
 	} else if i, e := strconv.Atoi(l.token); e != nil {
-		return nil, &ParseError{f, "bad CERT Type", l}, ""
+		return nil, &ParseError{file: f, err: "bad CERT Type", lex: l}, ""
 	} else {
 		rr.Type = uint16(i)
 	}
@@ -1097,7 +1097,7 @@ func setCERT(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	l = <-c // zString
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad CERT KeyTag", l}, ""
+		return nil, &ParseError{file: f, err: "bad CERT KeyTag", lex: l}, ""
 	}
 	rr.KeyTag = uint16(i)
 	<-c     // zBlank
@@ -1105,7 +1105,7 @@ func setCERT(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	if v, ok := StringToAlgorithm[l.token]; ok {
 		rr.Algorithm = v
 	} else if i, e := strconv.Atoi(l.token); e != nil {
-		return nil, &ParseError{f, "bad CERT Algorithm", l}, ""
+		return nil, &ParseError{file: f, err: "bad CERT Algorithm", lex: l}, ""
 	} else {
 		rr.Algorithm = uint8(i)
 	}
@@ -1148,11 +1148,11 @@ func setRRSIG(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 		if strings.HasPrefix(l.tokenUpper, "TYPE") {
 			t, ok = typeToInt(l.tokenUpper)
 			if !ok {
-				return nil, &ParseError{f, "bad RRSIG Typecovered", l}, ""
+				return nil, &ParseError{file: f, err: "bad RRSIG Typecovered", lex: l}, ""
 			}
 			rr.TypeCovered = t
 		} else {
-			return nil, &ParseError{f, "bad RRSIG Typecovered", l}, ""
+			return nil, &ParseError{file: f, err: "bad RRSIG Typecovered", lex: l}, ""
 		}
 	} else {
 		rr.TypeCovered = t
@@ -1161,21 +1161,21 @@ func setRRSIG(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	l = <-c
 	i, err := strconv.Atoi(l.token)
 	if err != nil || l.err {
-		return nil, &ParseError{f, "bad RRSIG Algorithm", l}, ""
+		return nil, &ParseError{file: f, err: "bad RRSIG Algorithm", lex: l}, ""
 	}
 	rr.Algorithm = uint8(i)
 	<-c // zBlank
 	l = <-c
 	i, err = strconv.Atoi(l.token)
 	if err != nil || l.err {
-		return nil, &ParseError{f, "bad RRSIG Labels", l}, ""
+		return nil, &ParseError{file: f, err: "bad RRSIG Labels", lex: l}, ""
 	}
 	rr.Labels = uint8(i)
 	<-c // zBlank
 	l = <-c
 	i, err = strconv.Atoi(l.token)
 	if err != nil || l.err {
-		return nil, &ParseError{f, "bad RRSIG OrigTtl", l}, ""
+		return nil, &ParseError{file: f, err: "bad RRSIG OrigTtl", lex: l}, ""
 	}
 	rr.OrigTtl = uint32(i)
 	<-c // zBlank
@@ -1186,7 +1186,7 @@ func setRRSIG(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 			// TODO(miek): error out on > MAX_UINT32, same below
 			rr.Expiration = uint32(i)
 		} else {
-			return nil, &ParseError{f, "bad RRSIG Expiration", l}, ""
+			return nil, &ParseError{file: f, err: "bad RRSIG Expiration", lex: l}, ""
 		}
 	} else {
 		rr.Expiration = i
@@ -1197,7 +1197,7 @@ func setRRSIG(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 		if i, err := strconv.ParseInt(l.token, 10, 64); err == nil {
 			rr.Inception = uint32(i)
 		} else {
-			return nil, &ParseError{f, "bad RRSIG Inception", l}, ""
+			return nil, &ParseError{file: f, err: "bad RRSIG Inception", lex: l}, ""
 		}
 	} else {
 		rr.Inception = i
@@ -1206,7 +1206,7 @@ func setRRSIG(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	l = <-c
 	i, err = strconv.Atoi(l.token)
 	if err != nil || l.err {
-		return nil, &ParseError{f, "bad RRSIG KeyTag", l}, ""
+		return nil, &ParseError{file: f, err: "bad RRSIG KeyTag", lex: l}, ""
 	}
 	rr.KeyTag = uint16(i)
 	<-c // zBlank
@@ -1217,7 +1217,7 @@ func setRRSIG(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	} else {
 		_, ok := IsDomainName(l.token)
 		if !ok || l.length == 0 || l.err {
-			return nil, &ParseError{f, "bad RRSIG SignerName", l}, ""
+			return nil, &ParseError{file: f, err: "bad RRSIG SignerName", lex: l}, ""
 		}
 		if rr.SignerName[l.length-1] != '.' {
 			rr.SignerName = appendOrigin(rr.SignerName, o)
@@ -1245,7 +1245,7 @@ func setNSEC(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	} else {
 		_, ok := IsDomainName(l.token)
 		if !ok || l.length == 0 || l.err {
-			return nil, &ParseError{f, "bad NSEC NextDomain", l}, ""
+			return nil, &ParseError{file: f, err: "bad NSEC NextDomain", lex: l}, ""
 		}
 		if rr.NextDomain[l.length-1] != '.' {
 			rr.NextDomain = appendOrigin(rr.NextDomain, o)
@@ -1265,12 +1265,12 @@ func setNSEC(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 		case zString:
 			if k, ok = StringToType[l.tokenUpper]; !ok {
 				if k, ok = typeToInt(l.tokenUpper); !ok {
-					return nil, &ParseError{f, "bad NSEC TypeBitMap", l}, ""
+					return nil, &ParseError{file: f, err: "bad NSEC TypeBitMap", lex: l}, ""
 				}
 			}
 			rr.TypeBitMap = append(rr.TypeBitMap, k)
 		default:
-			return nil, &ParseError{f, "bad NSEC TypeBitMap", l}, ""
+			return nil, &ParseError{file: f, err: "bad NSEC TypeBitMap", lex: l}, ""
 		}
 		l = <-c
 	}
@@ -1287,35 +1287,35 @@ func setNSEC3(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad NSEC3 Hash", l}, ""
+		return nil, &ParseError{file: f, err: "bad NSEC3 Hash", lex: l}, ""
 	}
 	rr.Hash = uint8(i)
 	<-c // zBlank
 	l = <-c
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad NSEC3 Flags", l}, ""
+		return nil, &ParseError{file: f, err: "bad NSEC3 Flags", lex: l}, ""
 	}
 	rr.Flags = uint8(i)
 	<-c // zBlank
 	l = <-c
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad NSEC3 Iterations", l}, ""
+		return nil, &ParseError{file: f, err: "bad NSEC3 Iterations", lex: l}, ""
 	}
 	rr.Iterations = uint16(i)
 	<-c
 	l = <-c
-	if len(l.token) == 0 || l.err {
-		return nil, &ParseError{f, "bad NSEC3 Salt", l}, ""
+	if len(l.token) == 0 || len(l.token) > 510 || l.err {
+		return nil, &ParseError{file: f, err: "bad NSEC3 Salt", lex: l}, ""
 	}
-	rr.SaltLength = uint8(len(l.token)) / 2
+	rr.SaltLength = uint8(len(l.token) / 2)
 	rr.Salt = l.token
 
 	<-c
 	l = <-c
 	if len(l.token) == 0 || l.err {
-		return nil, &ParseError{f, "bad NSEC3 NextDomain", l}, ""
+		return nil, &ParseError{file: f, err: "bad NSEC3 NextDomain", lex: l}, ""
 	}
 	rr.HashLength = 20 // Fix for NSEC3 (sha1 160 bits)
 	rr.NextDomain = l.token
@@ -1333,12 +1333,12 @@ func setNSEC3(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 		case zString:
 			if k, ok = StringToType[l.tokenUpper]; !ok {
 				if k, ok = typeToInt(l.tokenUpper); !ok {
-					return nil, &ParseError{f, "bad NSEC3 TypeBitMap", l}, ""
+					return nil, &ParseError{file: f, err: "bad NSEC3 TypeBitMap", lex: l}, ""
 				}
 			}
 			rr.TypeBitMap = append(rr.TypeBitMap, k)
 		default:
-			return nil, &ParseError{f, "bad NSEC3 TypeBitMap", l}, ""
+			return nil, &ParseError{file: f, err: "bad NSEC3 TypeBitMap", lex: l}, ""
 		}
 		l = <-c
 	}
@@ -1355,27 +1355,32 @@ func setNSEC3PARAM(h RR_Header, c chan lex, o, f string) (RR, *ParseError, strin
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad NSEC3PARAM Hash", l}, ""
+		return nil, &ParseError{file: f, err: "bad NSEC3PARAM Hash", lex: l}, ""
 	}
 	rr.Hash = uint8(i)
 	<-c // zBlank
 	l = <-c
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad NSEC3PARAM Flags", l}, ""
+		return nil, &ParseError{file: f, err: "bad NSEC3PARAM Flags", lex: l}, ""
 	}
 	rr.Flags = uint8(i)
 	<-c // zBlank
 	l = <-c
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad NSEC3PARAM Iterations", l}, ""
+		return nil, &ParseError{file: f, err: "bad NSEC3PARAM Iterations", lex: l}, ""
 	}
 	rr.Iterations = uint16(i)
 	<-c
 	l = <-c
-	rr.SaltLength = uint8(len(l.token))
-	rr.Salt = l.token
+	if len(l.token) > 510 {
+		return nil, &ParseError{file: f, err: "bad NSEC3PARAM Salt", lex: l}, ""
+	}
+	if l.token != "-" {
+		rr.SaltLength = uint8(len(l.token) / 2)
+		rr.Salt = l.token
+	}
 	return rr, nil, ""
 }
 
@@ -1388,7 +1393,7 @@ func setEUI48(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 		return rr, nil, ""
 	}
 	if l.length != 17 || l.err {
-		return nil, &ParseError{f, "bad EUI48 Address", l}, ""
+		return nil, &ParseError{file: f, err: "bad EUI48 Address", lex: l}, ""
 	}
 	addr := make([]byte, 12)
 	dash := 0
@@ -1397,7 +1402,7 @@ func setEUI48(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 		addr[i+1] = l.token[i+1+dash]
 		dash++
 		if l.token[i+1+dash] != '-' {
-			return nil, &ParseError{f, "bad EUI48 Address", l}, ""
+			return nil, &ParseError{file: f, err: "bad EUI48 Address", lex: l}, ""
 		}
 	}
 	addr[10] = l.token[15]
@@ -1405,7 +1410,7 @@ func setEUI48(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 
 	i, e := strconv.ParseUint(string(addr), 16, 48)
 	if e != nil {
-		return nil, &ParseError{f, "bad EUI48 Address", l}, ""
+		return nil, &ParseError{file: f, err: "bad EUI48 Address", lex: l}, ""
 	}
 	rr.Address = i
 	return rr, nil, ""
@@ -1420,7 +1425,7 @@ func setEUI64(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 		return rr, nil, ""
 	}
 	if l.length != 23 || l.err {
-		return nil, &ParseError{f, "bad EUI64 Address", l}, ""
+		return nil, &ParseError{file: f, err: "bad EUI64 Address", lex: l}, ""
 	}
 	addr := make([]byte, 16)
 	dash := 0
@@ -1429,7 +1434,7 @@ func setEUI64(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 		addr[i+1] = l.token[i+1+dash]
 		dash++
 		if l.token[i+1+dash] != '-' {
-			return nil, &ParseError{f, "bad EUI64 Address", l}, ""
+			return nil, &ParseError{file: f, err: "bad EUI64 Address", lex: l}, ""
 		}
 	}
 	addr[14] = l.token[21]
@@ -1437,7 +1442,7 @@ func setEUI64(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 
 	i, e := strconv.ParseUint(string(addr), 16, 64)
 	if e != nil {
-		return nil, &ParseError{f, "bad EUI68 Address", l}, ""
+		return nil, &ParseError{file: f, err: "bad EUI68 Address", lex: l}, ""
 	}
 	rr.Address = uint64(i)
 	return rr, nil, ""
@@ -1453,14 +1458,14 @@ func setSSHFP(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad SSHFP Algorithm", l}, ""
+		return nil, &ParseError{file: f, err: "bad SSHFP Algorithm", lex: l}, ""
 	}
 	rr.Algorithm = uint8(i)
 	<-c // zBlank
 	l = <-c
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad SSHFP Type", l}, ""
+		return nil, &ParseError{file: f, err: "bad SSHFP Type", lex: l}, ""
 	}
 	rr.Type = uint8(i)
 	<-c // zBlank
@@ -1482,21 +1487,21 @@ func setDNSKEYs(h RR_Header, c chan lex, o, f, typ string) (RR, *ParseError, str
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad " + typ + " Flags", l}, ""
+		return nil, &ParseError{file: f, err: "bad " + typ + " Flags", lex: l}, ""
 	}
 	rr.Flags = uint16(i)
 	<-c     // zBlank
 	l = <-c // zString
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad " + typ + " Protocol", l}, ""
+		return nil, &ParseError{file: f, err: "bad " + typ + " Protocol", lex: l}, ""
 	}
 	rr.Protocol = uint8(i)
 	<-c     // zBlank
 	l = <-c // zString
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad " + typ + " Algorithm", l}, ""
+		return nil, &ParseError{file: f, err: "bad " + typ + " Algorithm", lex: l}, ""
 	}
 	rr.Algorithm = uint8(i)
 	s, e1, c1 := endingToString(c, "bad "+typ+" PublicKey", f)
@@ -1538,21 +1543,21 @@ func setRKEY(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad RKEY Flags", l}, ""
+		return nil, &ParseError{file: f, err: "bad RKEY Flags", lex: l}, ""
 	}
 	rr.Flags = uint16(i)
 	<-c     // zBlank
 	l = <-c // zString
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad RKEY Protocol", l}, ""
+		return nil, &ParseError{file: f, err: "bad RKEY Protocol", lex: l}, ""
 	}
 	rr.Protocol = uint8(i)
 	<-c     // zBlank
 	l = <-c // zString
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad RKEY Algorithm", l}, ""
+		return nil, &ParseError{file: f, err: "bad RKEY Algorithm", lex: l}, ""
 	}
 	rr.Algorithm = uint8(i)
 	s, e1, c1 := endingToString(c, "bad RKEY PublicKey", f)
@@ -1594,21 +1599,21 @@ func setGPOS(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, e := strconv.ParseFloat(l.token, 64)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad GPOS Longitude", l}, ""
+		return nil, &ParseError{file: f, err: "bad GPOS Longitude", lex: l}, ""
 	}
 	rr.Longitude = l.token
 	<-c // zBlank
 	l = <-c
 	_, e = strconv.ParseFloat(l.token, 64)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad GPOS Latitude", l}, ""
+		return nil, &ParseError{file: f, err: "bad GPOS Latitude", lex: l}, ""
 	}
 	rr.Latitude = l.token
 	<-c // zBlank
 	l = <-c
 	_, e = strconv.ParseFloat(l.token, 64)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad GPOS Altitude", l}, ""
+		return nil, &ParseError{file: f, err: "bad GPOS Altitude", lex: l}, ""
 	}
 	rr.Altitude = l.token
 	return rr, nil, ""
@@ -1623,7 +1628,7 @@ func setDSs(h RR_Header, c chan lex, o, f, typ string) (RR, *ParseError, string)
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad " + typ + " KeyTag", l}, ""
+		return nil, &ParseError{file: f, err: "bad " + typ + " KeyTag", lex: l}, ""
 	}
 	rr.KeyTag = uint16(i)
 	<-c // zBlank
@@ -1631,7 +1636,7 @@ func setDSs(h RR_Header, c chan lex, o, f, typ string) (RR, *ParseError, string)
 	if i, e := strconv.Atoi(l.token); e != nil {
 		i, ok := StringToAlgorithm[l.tokenUpper]
 		if !ok || l.err {
-			return nil, &ParseError{f, "bad " + typ + " Algorithm", l}, ""
+			return nil, &ParseError{file: f, err: "bad " + typ + " Algorithm", lex: l}, ""
 		}
 		rr.Algorithm = i
 	} else {
@@ -1641,7 +1646,7 @@ func setDSs(h RR_Header, c chan lex, o, f, typ string) (RR, *ParseError, string)
 	l = <-c
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad " + typ + " DigestType", l}, ""
+		return nil, &ParseError{file: f, err: "bad " + typ + " DigestType", lex: l}, ""
 	}
 	rr.DigestType = uint8(i)
 	s, e1, c1 := endingToString(c, "bad "+typ+" Digest", f)
@@ -1682,7 +1687,7 @@ func setTA(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad TA KeyTag", l}, ""
+		return nil, &ParseError{file: f, err: "bad TA KeyTag", lex: l}, ""
 	}
 	rr.KeyTag = uint16(i)
 	<-c // zBlank
@@ -1690,7 +1695,7 @@ func setTA(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	if i, e := strconv.Atoi(l.token); e != nil {
 		i, ok := StringToAlgorithm[l.tokenUpper]
 		if !ok || l.err {
-			return nil, &ParseError{f, "bad TA Algorithm", l}, ""
+			return nil, &ParseError{file: f, err: "bad TA Algorithm", lex: l}, ""
 		}
 		rr.Algorithm = i
 	} else {
@@ -1700,7 +1705,7 @@ func setTA(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	l = <-c
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad TA DigestType", l}, ""
+		return nil, &ParseError{file: f, err: "bad TA DigestType", lex: l}, ""
 	}
 	rr.DigestType = uint8(i)
 	s, e, c1 := endingToString(c, "bad TA Digest", f)
@@ -1720,21 +1725,21 @@ func setTLSA(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad TLSA Usage", l}, ""
+		return nil, &ParseError{file: f, err: "bad TLSA Usage", lex: l}, ""
 	}
 	rr.Usage = uint8(i)
 	<-c // zBlank
 	l = <-c
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad TLSA Selector", l}, ""
+		return nil, &ParseError{file: f, err: "bad TLSA Selector", lex: l}, ""
 	}
 	rr.Selector = uint8(i)
 	<-c // zBlank
 	l = <-c
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad TLSA MatchingType", l}, ""
+		return nil, &ParseError{file: f, err: "bad TLSA MatchingType", lex: l}, ""
 	}
 	rr.MatchingType = uint8(i)
 	// So this needs be e2 (i.e. different than e), because...??t
@@ -1751,13 +1756,13 @@ func setRFC3597(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string)
 	rr.Hdr = h
 	l := <-c
 	if l.token != "\\#" {
-		return nil, &ParseError{f, "bad RFC3597 Rdata", l}, ""
+		return nil, &ParseError{file: f, err: "bad RFC3597 Rdata", lex: l}, ""
 	}
 	<-c // zBlank
 	l = <-c
 	rdlength, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad RFC3597 Rdata ", l}, ""
+		return nil, &ParseError{file: f, err: "bad RFC3597 Rdata ", lex: l}, ""
 	}
 
 	s, e1, c1 := endingToString(c, "bad RFC3597 Rdata", f)
@@ -1765,7 +1770,7 @@ func setRFC3597(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string)
 		return nil, e1, c1
 	}
 	if rdlength*2 != len(s) {
-		return nil, &ParseError{f, "bad RFC3597 Rdata", l}, ""
+		return nil, &ParseError{file: f, err: "bad RFC3597 Rdata", lex: l}, ""
 	}
 	rr.Rdata = s
 	return rr, nil, c1
@@ -1783,6 +1788,18 @@ func setSPF(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	return rr, nil, c1
 }
 
+func setAVC(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
+	rr := new(AVC)
+	rr.Hdr = h
+
+	s, e, c1 := endingToTxtSlice(c, "bad AVC Txt", f)
+	if e != nil {
+		return nil, e, ""
+	}
+	rr.Txt = s
+	return rr, nil, c1
+}
+
 func setTXT(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	rr := new(TXT)
 	rr.Hdr = h
@@ -1820,14 +1837,14 @@ func setURI(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad URI Priority", l}, ""
+		return nil, &ParseError{file: f, err: "bad URI Priority", lex: l}, ""
 	}
 	rr.Priority = uint16(i)
 	<-c // zBlank
 	l = <-c
 	i, e = strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad URI Weight", l}, ""
+		return nil, &ParseError{file: f, err: "bad URI Weight", lex: l}, ""
 	}
 	rr.Weight = uint16(i)
 
@@ -1837,7 +1854,7 @@ func setURI(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 		return nil, err, ""
 	}
 	if len(s) > 1 {
-		return nil, &ParseError{f, "bad URI Target", l}, ""
+		return nil, &ParseError{file: f, err: "bad URI Target", lex: l}, ""
 	}
 	rr.Target = s[0]
 	return rr, nil, c1
@@ -1866,7 +1883,7 @@ func setNID(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad NID Preference", l}, ""
+		return nil, &ParseError{file: f, err: "bad NID Preference", lex: l}, ""
 	}
 	rr.Preference = uint16(i)
 	<-c     // zBlank
@@ -1889,14 +1906,14 @@ func setL32(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad L32 Preference", l}, ""
+		return nil, &ParseError{file: f, err: "bad L32 Preference", lex: l}, ""
 	}
 	rr.Preference = uint16(i)
 	<-c     // zBlank
 	l = <-c // zString
 	rr.Locator32 = net.ParseIP(l.token)
 	if rr.Locator32 == nil || l.err {
-		return nil, &ParseError{f, "bad L32 Locator", l}, ""
+		return nil, &ParseError{file: f, err: "bad L32 Locator", lex: l}, ""
 	}
 	return rr, nil, ""
 }
@@ -1911,7 +1928,7 @@ func setLP(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad LP Preference", l}, ""
+		return nil, &ParseError{file: f, err: "bad LP Preference", lex: l}, ""
 	}
 	rr.Preference = uint16(i)
 	<-c     // zBlank
@@ -1926,7 +1943,7 @@ func setLP(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad LP Fqdn", l}, ""
+		return nil, &ParseError{file: f, err: "bad LP Fqdn", lex: l}, ""
 	}
 	if rr.Fqdn[l.length-1] != '.' {
 		rr.Fqdn = appendOrigin(rr.Fqdn, o)
@@ -1944,7 +1961,7 @@ func setL64(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad L64 Preference", l}, ""
+		return nil, &ParseError{file: f, err: "bad L64 Preference", lex: l}, ""
 	}
 	rr.Preference = uint16(i)
 	<-c     // zBlank
@@ -1966,7 +1983,7 @@ func setUID(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad UID Uid", l}, ""
+		return nil, &ParseError{file: f, err: "bad UID Uid", lex: l}, ""
 	}
 	rr.Uid = uint32(i)
 	return rr, nil, ""
@@ -1981,7 +1998,7 @@ func setGID(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad GID Gid", l}, ""
+		return nil, &ParseError{file: f, err: "bad GID Gid", lex: l}, ""
 	}
 	rr.Gid = uint32(i)
 	return rr, nil, ""
@@ -2008,7 +2025,7 @@ func setPX(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, e := strconv.Atoi(l.token)
 	if e != nil || l.err {
-		return nil, &ParseError{f, "bad PX Preference", l}, ""
+		return nil, &ParseError{file: f, err: "bad PX Preference", lex: l}, ""
 	}
 	rr.Preference = uint16(i)
 	<-c     // zBlank
@@ -2023,7 +2040,7 @@ func setPX(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok := IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad PX Map822", l}, ""
+		return nil, &ParseError{file: f, err: "bad PX Map822", lex: l}, ""
 	}
 	if rr.Map822[l.length-1] != '.' {
 		rr.Map822 = appendOrigin(rr.Map822, o)
@@ -2037,7 +2054,7 @@ func setPX(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	_, ok = IsDomainName(l.token)
 	if !ok || l.length == 0 || l.err {
-		return nil, &ParseError{f, "bad PX Mapx400", l}, ""
+		return nil, &ParseError{file: f, err: "bad PX Mapx400", lex: l}, ""
 	}
 	if rr.Mapx400[l.length-1] != '.' {
 		rr.Mapx400 = appendOrigin(rr.Mapx400, o)
@@ -2054,14 +2071,14 @@ func setCAA(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	}
 	i, err := strconv.Atoi(l.token)
 	if err != nil || l.err {
-		return nil, &ParseError{f, "bad CAA Flag", l}, ""
+		return nil, &ParseError{file: f, err: "bad CAA Flag", lex: l}, ""
 	}
 	rr.Flag = uint8(i)
 
 	<-c     // zBlank
 	l = <-c // zString
 	if l.value != zString {
-		return nil, &ParseError{f, "bad CAA Tag", l}, ""
+		return nil, &ParseError{file: f, err: "bad CAA Tag", lex: l}, ""
 	}
 	rr.Tag = l.token
 
@@ -2071,16 +2088,74 @@ func setCAA(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 		return nil, e, ""
 	}
 	if len(s) > 1 {
-		return nil, &ParseError{f, "bad CAA Value", l}, ""
+		return nil, &ParseError{file: f, err: "bad CAA Value", lex: l}, ""
 	}
 	rr.Value = s[0]
 	return rr, nil, c1
 }
 
+func setAPL(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
+	rr := new(APL)
+	rr.Hdr = h
+
+	var prefixes []APLPrefix
+	l := <-c
+	for l.value != zNewline && l.value != zEOF {
+		switch l.value {
+		case zBlank:
+			// Ok
+		case zString:
+			p, ok := stringToAPLPrefix(l.token)
+			if !ok || l.err {
+				return nil, &ParseError{file: f, err: "bad APL prefix", lex: l}, ""
+			}
+			prefixes = append(prefixes, p)
+		default:
+			return nil, &ParseError{file: f, err: "bad APL prefix", lex: l}, ""
+		}
+		l = <-c
+	}
+	rr.Prefixes = prefixes
+	return rr, nil, l.comment
+}
+
+// stringToAPLPrefix parses a prefix like "!1:192.0.2.0/24".
+func stringToAPLPrefix(s string) (APLPrefix, bool) {
+	var p APLPrefix
+	if len(s) > 0 && s[0] == '!' {
+		p.Negation = true
+		s = s[1:]
+	}
+	colon := strings.IndexByte(s, ':')
+	if colon < 0 {
+		return p, false
+	}
+	ip, network, err := net.ParseCIDR(s[colon+1:])
+	if err != nil {
+		return p, false
+	}
+	switch s[:colon] {
+	case "1":
+		if ip.To4() == nil {
+			return p, false
+		}
+	case "2":
+		if ip.To4() != nil {
+			return p, false
+		}
+	default:
+		return p, false
+	}
+	p.Network = *network
+	return p, true
+}
+
 var typeToparserFunc = map[uint16]parserFunc{
 	TypeAAAA:       {setAAAA, false},
 	TypeAFSDB:      {setAFSDB, false},
 	TypeA:          {setA, false},
+	TypeAPL:        {setAPL, true},
+	TypeAVC:        {setAVC, true},
 	TypeCAA:        {setCAA, true},
 	TypeCDS:        {setCDS, true},
 	TypeCDNSKEY:    {setCDNSKEY, true},
@@ -7,6 +7,7 @@ import (
 	"crypto/tls"
 	"encoding/binary"
 	"io"
+	"io/ioutil"
 	"net"
 	"sync"
 	"time"
@@ -608,36 +609,14 @@ Exit:
 
 func (srv *Server) readTCP(conn net.Conn, timeout time.Duration) ([]byte, error) {
 	conn.SetReadDeadline(time.Now().Add(timeout))
-	l := make([]byte, 2)
-	n, err := conn.Read(l)
-	if err != nil || n != 2 {
-		if err != nil {
-			return nil, err
-		}
-		return nil, ErrShortRead
-	}
-	length := binary.BigEndian.Uint16(l)
-	if length == 0 {
-		return nil, ErrShortRead
-	}
-	m := make([]byte, int(length))
-	n, err = conn.Read(m[:int(length)])
-	if err != nil || n == 0 {
-		if err != nil {
-			return nil, err
-		}
-		return nil, ErrShortRead
+	length, err := tcpMsgLen(conn)
+	if err != nil {
+		return nil, err
 	}
-	i := n
-	for i < int(length) {
-		j, err := conn.Read(m[i:int(length)])
-		if err != nil {
-			return nil, err
-		}
-		i += j
+	m := make([]byte, length)
+	if _, err := tcpRead(conn, m); err != nil {
+		return nil, err
 	}
-	n = i
-	m = m[:n]
 	return m, nil
 }
 
@@ -655,6 +634,53 @@ func (srv *Server) readUDP(conn *net.UDPConn, timeout time.Duration) ([]byte, *S
 	return m, s, nil
 }
 
+// ReadUDP reads a single message from conn into buf and unpacks it. Besides the
+// message the source address of the packet is returned. If buf is nil, a buffer of
+// MaxMsgSize bytes is allocated. When the message can not be unpacked, the source
+// address is still returned together with the error.
+func ReadUDP(conn net.PacketConn, buf []byte) (*Msg, net.Addr, error) {
+	if buf == nil {
+		buf = make([]byte, MaxMsgSize)
+	}
+	n, addr, err := conn.ReadFrom(buf)
+	if err != nil {
+		return nil, addr, err
+	}
+	if n < headerSize {
+		return nil, addr, ErrShortRead
+	}
+	m := new(Msg)
+	if err := m.Unpack(buf[:n]); err != nil {
+		return nil, addr, err
+	}
+	return m, addr, nil
+}
+
+// ReadMsgFromReader reads a single message of at most max bytes from r and unpacks
+// it. This can be used for messages that are not read from a DNS connection, i.e. the
+// body of a DNS over HTTPS request. If max is zero or negative MaxMsgSize is used.
+// ErrBuf is returned when r holds more than max bytes.
+func ReadMsgFromReader(r io.Reader, max int) (*Msg, error) {
+	if max <= 0 {
+		max = MaxMsgSize
+	}
+	buf, err := ioutil.ReadAll(io.LimitReader(r, int64(max)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) > max {
+		return nil, ErrBuf
+	}
+	if len(buf) < headerSize {
+		return nil, ErrShortRead
+	}
+	m := new(Msg)
+	if err := m.Unpack(buf); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // WriteMsg implements the ResponseWriter.WriteMsg method.
 func (w *response) WriteMsg(m *Msg) (err error) {
 	var data []byte
@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"bytes"
 	"crypto/tls"
 	"fmt"
 	"io"
@@ -677,3 +678,129 @@ zDCJkckCgYEAndqM5KXGk5xYo+MAA1paZcbTUXwaWwjLU+XSRSSoyBEi5xMtfvUb
 kFsxKCqxAnBVGEWAvVZAiiTOxleQFjz5RnL0BQp9Lg2cQe+dvuUmIAA=
 -----END RSA PRIVATE KEY-----`)
 )
+
+func TestReadUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer pc.Close()
+	c, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("unable to dial: %v", err)
+	}
+	defer c.Close()
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeSOA)
+	buf, _ := m.Pack()
+	if _, err := c.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	r, addr, err := ReadUDP(pc, nil)
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	if r.Id != m.Id || r.Question[0].Name != "miek.nl." {
+		t.Errorf("read a different message: %s", r)
+	}
+	if addr.String() != c.LocalAddr().String() {
+		t.Errorf("expected source address %s, got %s", c.LocalAddr(), addr)
+	}
+
+	if _, err := c.Write([]byte{0, 1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	if _, addr, err := ReadUDP(pc, make([]byte, MinMsgSize)); err != ErrShortRead || addr == nil {
+		t.Errorf("expected ErrShortRead and a source address, got %v and %v", err, addr)
+	}
+}
+
+func TestReadMsgFromReader(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeSOA)
+	buf, _ := m.Pack()
+
+	r, err := ReadMsgFromReader(bytes.NewReader(buf), len(buf))
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	if r.Id != m.Id || r.Question[0].Name != "miek.nl." {
+		t.Errorf("read a different message: %s", r)
+	}
+	if _, err := ReadMsgFromReader(bytes.NewReader(buf), len(buf)-1); err != ErrBuf {
+		t.Errorf("expected ErrBuf for a body larger than max, got %v", err)
+	}
+	if _, err := ReadMsgFromReader(bytes.NewReader(buf[:5]), 0); err != ErrShortRead {
+		t.Errorf("expected ErrShortRead for a short body, got %v", err)
+	}
+}
+
+func TestTransferInLimit(t *testing.T) {
+	soa := &SOA{Hdr: RR_Header{Name: "example.org.", Rrtype: TypeSOA, Class: ClassINET, Ttl: 3600},
+		Ns: "ns.example.org.", Mbox: "hostmaster.example.org.", Serial: 1, Refresh: 3600, Retry: 600, Expire: 86400, Minttl: 300}
+	HandleFunc("example.org.", func(w ResponseWriter, req *Msg) {
+		ch := make(chan *Envelope)
+		go func() {
+			defer close(ch)
+			ch <- &Envelope{RR: []RR{soa}}
+			for i := 0; i < 100; i++ {
+				var rrs []RR
+				for j := 0; j < 10; j++ {
+					rrs = append(rrs, &A{Hdr: RR_Header{Name: fmt.Sprintf("host%d.example.org.", i*10+j), Rrtype: TypeA, Class: ClassINET, Ttl: 3600}, A: net.IPv4(127, 0, byte(i), byte(j))})
+				}
+				ch <- &Envelope{RR: rrs}
+			}
+			ch <- &Envelope{RR: []RR{soa}}
+		}()
+		// The client aborts the transfer, writes may fail.
+		new(Transfer).Out(w, req, ch)
+		for range ch {
+		}
+	})
+	defer HandleRemove("example.org.")
+
+	s, addrstr, err := RunLocalTCPServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	defer s.Shutdown()
+
+	for _, tc := range []struct {
+		name    string
+		tr      *Transfer
+		limited bool
+	}{
+		{"no limit", new(Transfer), false},
+		{"RR limit", &Transfer{MaxRRs: 500}, true},
+		{"byte limit", &Transfer{MaxBytes: 4096}, true},
+		{"high limits", &Transfer{MaxRRs: 1002, MaxBytes: 1 << 20}, false},
+	} {
+		m := new(Msg)
+		m.SetAxfr("example.org.")
+		env, err := tc.tr.In(m, addrstr)
+		if err != nil {
+			t.Fatalf("%s: failed to start transfer: %v", tc.name, err)
+		}
+		var rrs int
+		var last error
+		for e := range env {
+			rrs += len(e.RR)
+			last = e.Error
+		}
+		if !tc.limited {
+			if last != nil || rrs != 1002 {
+				t.Errorf("%s: expected 1002 RRs without error, got %d and %v", tc.name, rrs, last)
+			}
+			continue
+		}
+		if last != ErrLimit {
+			t.Errorf("%s: expected ErrLimit, got %v", tc.name, last)
+		}
+		if tc.tr.MaxRRs > 0 && rrs > tc.tr.MaxRRs {
+			t.Errorf("%s: expected at most %d RRs, got %d", tc.name, tc.tr.MaxRRs, rrs)
+		}
+	}
+}
@@ -87,3 +87,25 @@ func TestSIG0(t *testing.T) {
 		}
 	}
 }
+
+func TestSIG0KeyAlgMismatch(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeSOA)
+
+	keyrr := &KEY{DNSKEY{Hdr: RR_Header{Name: "example.org.", Rrtype: TypeKEY, Class: ClassINET}, Algorithm: ECDSAP256SHA256}}
+	pk, err := keyrr.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := uint32(time.Now().Unix())
+	for _, alg := range []uint8{RSASHA1, RSASHA256, ECDSAP384SHA384} {
+		sigrr := &SIG{RRSIG{Algorithm: alg, Expiration: now + 300, Inception: now - 300, KeyTag: keyrr.KeyTag(), SignerName: keyrr.Hdr.Name}}
+		if _, err := sigrr.Sign(pk.(crypto.Signer), m); err != ErrKeyAlg {
+			t.Errorf("expected ErrKeyAlg signing with an ECDSA P-256 key for %s, got %v", AlgorithmToString[alg], err)
+		}
+	}
+	sigrr := &SIG{RRSIG{Algorithm: ECDSAP256SHA256, Expiration: now + 300, Inception: now - 300, KeyTag: keyrr.KeyTag(), SignerName: keyrr.Hdr.Name}}
+	if _, err := sigrr.Sign(pk.(crypto.Signer), m); err != nil {
+		t.Errorf("failed to sign with matching key and algorithm: %v", err)
+	}
+}
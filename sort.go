@@ -0,0 +1,54 @@
+package dns
+
+import "sort"
+
+// SortSRV sorts the SRV records in rrs on priority, lowest first and, within a
+// priority, on weight, highest first. This does not implement the weighted random
+// selection from RFC 2782; clients that want it have to apply it to the records of
+// each priority themselves. Records that are not SRV records are moved to the end,
+// keeping their order.
+// Note that SRV targets are never compressed, as RFC 2782 forbids it.
+func SortSRV(rrs []RR) {
+	sort.Stable(srvSlice(rrs))
+}
+
+// SortMX sorts the MX records in rrs on preference, lowest first. Records that
+// are not MX records are moved to the end, keeping their order.
+func SortMX(rrs []RR) {
+	sort.Stable(mxSlice(rrs))
+}
+
+type srvSlice []RR
+
+func (p srvSlice) Len() int      { return len(p) }
+func (p srvSlice) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p srvSlice) Less(i, j int) bool {
+	a, ok := p[i].(*SRV)
+	if !ok {
+		return false
+	}
+	b, ok := p[j].(*SRV)
+	if !ok {
+		return true
+	}
+	if a.Priority != b.Priority {
+		return a.Priority < b.Priority
+	}
+	return a.Weight > b.Weight
+}
+
+type mxSlice []RR
+
+func (p mxSlice) Len() int      { return len(p) }
+func (p mxSlice) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p mxSlice) Less(i, j int) bool {
+	a, ok := p[i].(*MX)
+	if !ok {
+		return false
+	}
+	b, ok := p[j].(*MX)
+	if !ok {
+		return true
+	}
+	return a.Preference < b.Preference
+}
@@ -0,0 +1,40 @@
+package dns
+
+import "testing"
+
+func TestSortSRV(t *testing.T) {
+	rrs := []RR{
+		newRR(t, "_sip._tcp.example.org. IN SRV 20 0 5060 backup.example.org."),
+		newRR(t, "_sip._tcp.example.org. IN SRV 10 20 5060 small.example.org."),
+		newRR(t, "_sip._tcp.example.org. IN TXT \"not an SRV\""),
+		newRR(t, "_sip._tcp.example.org. IN SRV 10 60 5060 big.example.org."),
+		newRR(t, "_sip._tcp.example.org. IN SRV 10 0 5060 zero.example.org."),
+		newRR(t, "_sip._tcp.example.org. IN SRV 5 0 5060 first.example.org."),
+	}
+	SortSRV(rrs)
+	expected := []string{"first.example.org.", "big.example.org.", "small.example.org.", "zero.example.org.", "backup.example.org."}
+	for i, target := range expected {
+		if srv, ok := rrs[i].(*SRV); !ok || srv.Target != target {
+			t.Errorf("record %d: expected SRV with target %s, got %s", i, target, rrs[i])
+		}
+	}
+	if _, ok := rrs[len(rrs)-1].(*TXT); !ok {
+		t.Errorf("expected the TXT record last, got %s", rrs[len(rrs)-1])
+	}
+}
+
+func TestSortMX(t *testing.T) {
+	rrs := []RR{
+		newRR(t, "example.org. IN MX 30 c.example.org."),
+		newRR(t, "example.org. IN MX 10 a.example.org."),
+		newRR(t, "example.org. IN MX 20 b.example.org."),
+		newRR(t, "example.org. IN MX 10 a2.example.org."),
+	}
+	SortMX(rrs)
+	expected := []string{"a.example.org.", "a2.example.org.", "b.example.org.", "c.example.org."}
+	for i, mx := range expected {
+		if rrs[i].(*MX).Mx != mx {
+			t.Errorf("record %d: expected MX %s, got %s", i, mx, rrs[i])
+		}
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"hash"
-	"io"
 	"strconv"
 	"strings"
 	"time"
@@ -91,41 +90,31 @@ type timerWireFmt struct {
 // The TSIG MAC is saved in that Tsig RR.
 // When TsigGenerate is called for the first time requestMAC is set to the empty string and
 // timersOnly is false.
-// If something goes wrong an error is returned, otherwise it is nil.
+// If something goes wrong an error is returned, otherwise it is nil. On error the
+// TSIG RR is left in the message.
 func TsigGenerate(m *Msg, secret, requestMAC string, timersOnly bool) ([]byte, string, error) {
 	if m.IsTsig() == nil {
 		panic("dns: TSIG not last RR in additional")
 	}
-	// If we barf here, the caller is to blame
-	rawsecret, err := fromBase64([]byte(secret))
-	if err != nil {
-		return nil, "", err
-	}
 
+	extra := m.Extra
 	rr := m.Extra[len(m.Extra)-1].(*TSIG)
 	m.Extra = m.Extra[0 : len(m.Extra)-1] // kill the TSIG from the msg
 	mbuf, err := m.Pack()
 	if err != nil {
+		m.Extra = extra
 		return nil, "", err
 	}
 	buf := tsigBuffer(mbuf, rr, requestMAC, timersOnly)
 
-	t := new(TSIG)
-	var h hash.Hash
-	switch strings.ToLower(rr.Algorithm) {
-	case HmacMD5:
-		h = hmac.New(md5.New, []byte(rawsecret))
-	case HmacSHA1:
-		h = hmac.New(sha1.New, []byte(rawsecret))
-	case HmacSHA256:
-		h = hmac.New(sha256.New, []byte(rawsecret))
-	case HmacSHA512:
-		h = hmac.New(sha512.New, []byte(rawsecret))
-	default:
-		return nil, "", ErrKeyAlg
+	// If we barf here, the caller is to blame
+	mac, err := tsigHMAC(rr.Algorithm, []byte(secret), buf)
+	if err != nil {
+		m.Extra = extra
+		return nil, "", err
 	}
-	io.WriteString(h, string(buf))
-	t.MAC = hex.EncodeToString(h.Sum(nil))
+	t := new(TSIG)
+	t.MAC = hex.EncodeToString(mac)
 	t.MACSize = uint16(len(t.MAC) / 2) // Size is half!
 
 	t.Hdr = RR_Header{Name: rr.Hdr.Name, Rrtype: TypeTSIG, Class: ClassANY, Ttl: 0}
@@ -151,10 +140,6 @@ func TsigGenerate(m *Msg, secret, requestMAC string, timersOnly bool) ([]byte, s
 // If the signature does not validate err contains the
 // error, otherwise it is nil.
 func TsigVerify(msg []byte, secret, requestMAC string, timersOnly bool) error {
-	rawsecret, err := fromBase64([]byte(secret))
-	if err != nil {
-		return err
-	}
 	// Strip the TSIG from the incoming msg
 	stripped, tsig, err := stripTsig(msg)
 	if err != nil {
@@ -179,8 +164,25 @@ func TsigVerify(msg []byte, secret, requestMAC string, timersOnly bool) error {
 		return ErrTime
 	}
 
+	mac, err := tsigHMAC(tsig.Algorithm, []byte(secret), buf)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(mac, msgMAC) {
+		return ErrSig
+	}
+	return nil
+}
+
+// tsigHMAC calculates the MAC over data with the base64 encoded secret, using
+// the HMAC that belongs to the TSIG algorithm alg.
+func tsigHMAC(alg string, secret, data []byte) ([]byte, error) {
+	rawsecret, err := fromBase64(secret)
+	if err != nil {
+		return nil, err
+	}
 	var h hash.Hash
-	switch strings.ToLower(tsig.Algorithm) {
+	switch strings.ToLower(alg) {
 	case HmacMD5:
 		h = hmac.New(md5.New, rawsecret)
 	case HmacSHA1:
@@ -190,13 +192,10 @@ func TsigVerify(msg []byte, secret, requestMAC string, timersOnly bool) error {
 	case HmacSHA512:
 		h = hmac.New(sha512.New, rawsecret)
 	default:
-		return ErrKeyAlg
+		return nil, ErrKeyAlg
 	}
-	h.Write(buf)
-	if !hmac.Equal(h.Sum(nil), msgMAC) {
-		return ErrSig
-	}
-	return nil
+	h.Write(data)
+	return h.Sum(nil), nil
 }
 
 // Create a wiredata buffer for the MAC calculation.
@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"encoding/hex"
 	"testing"
 	"time"
 )
@@ -35,3 +36,67 @@ func TestTsigCase(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestTsigHMAC(t *testing.T) {
+	// Test case 2 from RFC 2202 and RFC 4231, key is "Jefe".
+	secret := []byte("SmVmZQ==")
+	data := []byte("what do ya want for nothing?")
+	tests := map[string]string{
+		HmacMD5:    "750c783e6ab0b503eaa86e310a5db738",
+		HmacSHA1:   "effcdf6ae5eb2fa2d27416d5f184df9c259a7c79",
+		HmacSHA256: "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		HmacSHA512: "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea250554" +
+			"9758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737",
+	}
+	for alg, expected := range tests {
+		mac, err := tsigHMAC(alg, secret, data)
+		if err != nil {
+			t.Errorf("failed to calculate HMAC for %s: %v", alg, err)
+			continue
+		}
+		if x := hex.EncodeToString(mac); x != expected {
+			t.Errorf("wrong HMAC for %s, got %s, expected %s", alg, x, expected)
+		}
+	}
+	if _, err := tsigHMAC("hmac-foo.", secret, data); err != ErrKeyAlg {
+		t.Errorf("expected ErrKeyAlg for unknown algorithm, got %v", err)
+	}
+}
+
+func TestTsigGenerateError(t *testing.T) {
+	tests := []struct {
+		algo, secret string
+	}{
+		{"hmac-foo.", "pRZgBrBvI4NAHZYhxmhs/Q=="},
+		{HmacMD5, "not base64!"},
+	}
+	for _, tc := range tests {
+		m := newTsig(tc.algo)
+		if _, _, err := TsigGenerate(m, tc.secret, "", false); err == nil {
+			t.Errorf("%s with secret %q: expected an error", tc.algo, tc.secret)
+		}
+		if m.IsTsig() == nil {
+			t.Errorf("%s with secret %q: expected the TSIG RR to be left in the message", tc.algo, tc.secret)
+		}
+	}
+}
+
+func TestTsigPackSizeAutofill(t *testing.T) {
+	tsig := &TSIG{Hdr: RR_Header{Name: "example.", Rrtype: TypeTSIG, Class: ClassANY}, Algorithm: HmacMD5,
+		Fudge: 300, MAC: "0123456789abcdef0123456789abcdef", OtherData: "0000"}
+	buf := make([]byte, tsig.len())
+	off, err := PackRR(tsig, buf, 0, nil, false)
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+	if tsig.MACSize != 16 || tsig.OtherLen != 2 {
+		t.Errorf("expected MACSize 16 and OtherLen 2, got %d and %d", tsig.MACSize, tsig.OtherLen)
+	}
+	rr, _, err := UnpackRR(buf[:off], 0)
+	if err != nil {
+		t.Fatalf("failed to unpack: %v", err)
+	}
+	if got := rr.(*TSIG); got.MACSize != 16 || got.MAC != tsig.MAC || got.OtherData != tsig.OtherData {
+		t.Errorf("round trip failed, got %q, expected %q", got.String(), tsig.String())
+	}
+}
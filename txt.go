@@ -0,0 +1,80 @@
+package dns
+
+// maxTxtChunks is the number of full 255 byte character-strings that fit in the
+// rdata of a single TXT record.
+const maxTxtChunks = MaxMsgSize / 256
+
+// ChunkRRset stores data in TXT records for owner. The data is split in character-strings
+// of at most 255 bytes and as few records as possible are used; only when data does not fit
+// in the rdata of one record more records are returned. Note that the order of the records
+// in an RRset is not preserved by the DNS, so large blobs may not survive a trip through a
+// name server. Use UnchunkRRset to get the data back.
+func ChunkRRset(owner string, ttl uint32, data []byte) []RR {
+	var rrs []RR
+	chunks := splitTxt(data)
+	for len(chunks) > 0 {
+		n := len(chunks)
+		if n > maxTxtChunks {
+			n = maxTxtChunks
+		}
+		rrs = append(rrs, &TXT{Hdr: RR_Header{Name: owner, Rrtype: TypeTXT, Class: ClassINET, Ttl: ttl}, Txt: chunks[:n]})
+		chunks = chunks[n:]
+	}
+	return rrs
+}
+
+// UnchunkRRset returns the data stored in the TXT records rrs, it is the inverse of
+// ChunkRRset. An error is returned when rrs contains other types.
+func UnchunkRRset(rrs []RR) ([]byte, error) {
+	var data []byte
+	for _, r := range rrs {
+		txt, ok := r.(*TXT)
+		if !ok {
+			return nil, &Error{err: "not a TXT record: " + r.String()}
+		}
+		data = joinTxt(data, txt.Txt)
+	}
+	return data, nil
+}
+
+// Text returns the character-strings of rr concatenated, without any escaping. This is
+// how SPF and DKIM records are read.
+func (rr *TXT) Text() string { return string(joinTxt(nil, rr.Txt)) }
+
+// SetText sets the character-strings of rr to s, split in pieces of at most 255 bytes.
+func (rr *TXT) SetText(s string) { rr.Txt = splitTxt([]byte(s)) }
+
+// splitTxt splits data in character-strings of at most 255 bytes, escaped as they are
+// stored in a TXT record. Empty data gives a single empty character-string.
+func splitTxt(data []byte) []string {
+	var chunks []string
+	for len(data) > 0 || chunks == nil {
+		n := len(data)
+		if n > 255 {
+			n = 255
+		}
+		s := make([]byte, 0, n)
+		for _, b := range data[:n] {
+			s = appendTXTStringByte(s, b)
+		}
+		chunks = append(chunks, string(s))
+		data = data[n:]
+	}
+	return chunks
+}
+
+// joinTxt appends the unescaped character-strings txt to data.
+func joinTxt(data []byte, txt []string) []byte {
+	for _, s := range txt {
+		bs := []byte(s)
+		for i := 0; i < len(bs); {
+			b, n := nextByte(bs, i)
+			if n == 0 {
+				break
+			}
+			data = append(data, b)
+			i += n
+		}
+	}
+	return data
+}
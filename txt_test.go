@@ -0,0 +1,84 @@
+package dns
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestChunkRRset(t *testing.T) {
+	data := make([]byte, 1024)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	rrs := ChunkRRset("blob.example.org.", 300, data)
+	if len(rrs) != 1 {
+		t.Fatalf("expected 1 TXT record, got %d", len(rrs))
+	}
+	txt := rrs[0].(*TXT)
+	if len(txt.Txt) != 5 {
+		t.Errorf("expected 5 character-strings, got %d", len(txt.Txt))
+	}
+
+	// Take the record through the wire and back.
+	m := new(Msg)
+	m.Answer = rrs
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatalf("failed to pack: %v", err)
+	}
+	m = new(Msg)
+	if err := m.Unpack(buf); err != nil {
+		t.Fatalf("failed to unpack: %v", err)
+	}
+	data1, err := UnchunkRRset(m.Answer)
+	if err != nil {
+		t.Fatalf("failed to reassemble data: %v", err)
+	}
+	if !bytes.Equal(data, data1) {
+		t.Errorf("reassembled data differs from original")
+	}
+
+	if _, err := UnchunkRRset([]RR{newRR(t, "example.org. 300 IN A 127.0.0.1")}); err == nil {
+		t.Error("expected error when reassembling non TXT records")
+	}
+}
+
+func TestChunkRRsetLarge(t *testing.T) {
+	data := bytes.Repeat([]byte{'"'}, 255*maxTxtChunks+1)
+	rrs := ChunkRRset("blob.example.org.", 300, data)
+	if len(rrs) != 2 {
+		t.Fatalf("expected 2 TXT records, got %d", len(rrs))
+	}
+	data1, err := UnchunkRRset(rrs)
+	if err != nil {
+		t.Fatalf("failed to reassemble data: %v", err)
+	}
+	if !bytes.Equal(data, data1) {
+		t.Errorf("reassembled data differs from original")
+	}
+}
+
+func TestTXTText(t *testing.T) {
+	key := "v=DKIM1; k=rsa; p=" + strings.Repeat("MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA", 10) + "IDAQAB"
+	txt := &TXT{Hdr: RR_Header{Name: "sel._domainkey.example.org.", Rrtype: TypeTXT, Class: ClassINET, Ttl: 300}}
+	txt.SetText(key)
+	if len(txt.Txt) != 2 || len(txt.Txt[0]) != 255 {
+		t.Fatalf("expected 2 character-strings, the first of 255 bytes, got %q", txt.Txt)
+	}
+
+	rr := newRR(t, txt.String())
+	if s := rr.(*TXT).Text(); s != key {
+		t.Errorf("expected %q, got %q", key, s)
+	}
+
+	rr = newRR(t, `example.org. 300 IN TXT "v=spf1 " "include:\"x\".example.org -all"`)
+	if s := rr.(*TXT).Text(); s != `v=spf1 include:"x".example.org -all` {
+		t.Errorf("expected the unescaped concatenation, got %q", s)
+	}
+
+	txt.SetText("")
+	if len(txt.Txt) != 1 || txt.Txt[0] != "" {
+		t.Errorf("expected a single empty character-string, got %q", txt.Txt)
+	}
+}
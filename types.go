@@ -61,6 +61,7 @@ const (
 	TypeCERT       uint16 = 37
 	TypeDNAME      uint16 = 39
 	TypeOPT        uint16 = 41 // EDNS
+	TypeAPL        uint16 = 42
 	TypeDS         uint16 = 43
 	TypeSSHFP      uint16 = 44
 	TypeRRSIG      uint16 = 46
@@ -90,6 +91,7 @@ const (
 	TypeEUI64      uint16 = 109
 	TypeURI        uint16 = 256
 	TypeCAA        uint16 = 257
+	TypeAVC        uint16 = 258
 
 	TypeTKEY uint16 = 249
 	TypeTSIG uint16 = 250
@@ -215,7 +217,7 @@ type Question struct {
 }
 
 func (q *Question) len() int {
-	return len(q.Name) + 1 + 2 + 2
+	return domainNameLen(q.Name) + 2 + 2
 }
 
 func (q *Question) String() (s string) {
@@ -316,7 +318,7 @@ func (rr *MX) String() string {
 type AFSDB struct {
 	Hdr      RR_Header
 	Subtype  uint16
-	Hostname string `dns:"cdomain-name"`
+	Hostname string `dns:"domain-name"`
 }
 
 func (rr *AFSDB) String() string {
@@ -335,7 +337,7 @@ func (rr *X25) String() string {
 type RT struct {
 	Hdr        RR_Header
 	Preference uint16
-	Host       string `dns:"cdomain-name"`
+	Host       string `dns:"domain-name"`
 }
 
 func (rr *RT) String() string {
@@ -390,6 +392,65 @@ func (rr *SOA) String() string {
 		" " + strconv.FormatInt(int64(rr.Minttl), 10)
 }
 
+// Email returns the mailbox in Mbox as an email address: the first label is the
+// local part, the rest of the name the domain. A dot in the local part is escaped
+// in Mbox, as in "john\.doe.example.org.", and is a plain dot in the address. Email
+// returns the empty string when Mbox does not have at least two labels.
+func (rr *SOA) Email() string {
+	labels, err := UnescapeName(Fqdn(rr.Mbox))
+	if err != nil || len(labels) < 2 {
+		return ""
+	}
+	domain := EscapeName(labels[1:])
+	return labels[0] + "@" + domain[:len(domain)-1]
+}
+
+// SetEmail sets Mbox to the mailbox for the email address email, escaping dots in
+// the local part. It is the reverse of Email.
+func (rr *SOA) SetEmail(email string) error {
+	i := strings.LastIndex(email, "@")
+	if i <= 0 || i > 63 || i == len(email)-1 {
+		return &Error{err: "bad email address: " + email}
+	}
+	domain, err := UnescapeName(Fqdn(email[i+1:]))
+	if err != nil {
+		return err
+	}
+	rr.Mbox = EscapeName(append([]string{email[:i]}, domain...))
+	return nil
+}
+
+// SerialMode selects how BumpSerial increases the serial of an SOA record.
+type SerialMode int
+
+const (
+	SerialIncrement SerialMode = iota // Add one to the serial.
+	SerialUnixTime                    // Set the serial to the current Unix time.
+	SerialDate                        // Set the serial to the current date as YYYYMMDDnn, nn counts changes on a day.
+)
+
+// BumpSerial increases the serial of rr after a change of the zone, as mode says.
+// The new serial is always greater than the old one in serial arithmetic (RFC 1982):
+// when mode would give a serial that isn't, such as for the hundredth change on a
+// day with SerialDate, one is added to the old serial instead.
+func (rr *SOA) BumpSerial(mode SerialMode) { rr.bumpSerial(mode, time.Now()) }
+
+func (rr *SOA) bumpSerial(mode SerialMode, now time.Time) {
+	serial := rr.Serial + 1
+	switch mode {
+	case SerialUnixTime:
+		serial = uint32(now.Unix())
+	case SerialDate:
+		y, m, d := now.UTC().Date()
+		serial = uint32(y*1000000 + int(m)*10000 + d*100)
+	}
+	// The difference must be positive and less than 2^31, RFC 1982 section 3.1.
+	if int32(serial-rr.Serial) <= 0 {
+		serial = rr.Serial + 1
+	}
+	rr.Serial = serial
+}
+
 type TXT struct {
 	Hdr RR_Header
 	Txt []string `dns:"txt"`
@@ -544,6 +605,14 @@ type SPF struct {
 
 func (rr *SPF) String() string { return rr.Hdr.String() + sprintTxt(rr.Txt) }
 
+// AVC RR. Application Visibility and Control, its rdata has the same format as TXT.
+type AVC struct {
+	Hdr RR_Header
+	Txt []string `dns:"txt"`
+}
+
+func (rr *AVC) String() string { return rr.Hdr.String() + sprintTxt(rr.Txt) }
+
 type SRV struct {
 	Hdr      RR_Header
 	Priority uint16
@@ -958,7 +1027,7 @@ type NSEC3PARAM struct {
 	Flags      uint8
 	Iterations uint16
 	SaltLength uint8
-	Salt       string `dns:"hex"`
+	Salt       string `dns:"size-hex:SaltLength"`
 }
 
 func (rr *NSEC3PARAM) String() string {
@@ -998,7 +1067,10 @@ func (rr *RFC3597) String() string {
 	// Let's call it a hack
 	s := rfc3597Header(rr.Hdr)
 
-	s += "\\# " + strconv.Itoa(len(rr.Rdata)/2) + " " + rr.Rdata
+	s += "\\# " + strconv.Itoa(len(rr.Rdata)/2)
+	if rr.Rdata != "" {
+		s += " " + rr.Rdata
+	}
 	return s
 }
 
@@ -1150,6 +1222,78 @@ func (rr *CAA) String() string {
 	return rr.Hdr.String() + strconv.Itoa(int(rr.Flag)) + " " + rr.Tag + " " + sprintTxtOctet(rr.Value)
 }
 
+// APL RR. See RFC 3123.
+type APL struct {
+	Hdr      RR_Header
+	Prefixes []APLPrefix `dns:"apl"`
+}
+
+// APLPrefix is an address prefix in an APL record.
+type APLPrefix struct {
+	Negation bool
+	Network  net.IPNet
+}
+
+func (rr *APL) String() string {
+	s := make([]string, len(rr.Prefixes))
+	for i, p := range rr.Prefixes {
+		s[i] = p.String()
+	}
+	return rr.Hdr.String() + strings.Join(s, " ")
+}
+
+// String returns the prefix in presentation format, i.e. "!1:192.0.2.0/24".
+func (p *APLPrefix) String() string {
+	s := ""
+	if p.Negation {
+		s = "!"
+	}
+	ones, _ := p.Network.Mask.Size()
+	if len(p.Network.Mask) == net.IPv4len {
+		s += "1:" + p.Network.IP.String()
+	} else {
+		s += "2:" + p.Network.IP.String()
+	}
+	return s + "/" + strconv.Itoa(ones)
+}
+
+// afd returns the address family and the address part as sent on the wire, trailing
+// zero octets are not sent.
+func (p *APLPrefix) afd() (uint16, []byte, error) {
+	var family uint16
+	ip := p.Network.IP
+	switch len(p.Network.Mask) {
+	case net.IPv4len:
+		family, ip = 1, ip.To4()
+	case net.IPv6len:
+		family, ip = 2, ip.To16()
+	default:
+		return 0, nil, &Error{err: "bad APL prefix mask"}
+	}
+	if ip == nil {
+		return 0, nil, &Error{err: "bad APL prefix address"}
+	}
+	ones, _ := p.Network.Mask.Size()
+	addr := ip.Mask(p.Network.Mask)[:(ones+7)/8]
+	for len(addr) > 0 && addr[len(addr)-1] == 0 {
+		addr = addr[:len(addr)-1]
+	}
+	return family, addr, nil
+}
+
+// copy returns a deep copy of the prefix.
+func (p *APLPrefix) copy() APLPrefix {
+	return APLPrefix{
+		Negation: p.Negation,
+		Network:  net.IPNet{IP: copyIP(p.Network.IP), Mask: net.IPMask(copyIP(net.IP(p.Network.Mask)))},
+	}
+}
+
+func (p *APLPrefix) len() int {
+	_, addr, _ := p.afd()
+	return 4 + len(addr)
+}
+
 type UID struct {
 	Hdr RR_Header
 	Uid uint32
@@ -2,6 +2,7 @@ package dns
 
 import (
 	"testing"
+	"time"
 )
 
 func TestCmToM(t *testing.T) {
@@ -40,3 +41,60 @@ func TestCmToM(t *testing.T) {
 		t.Error("9, 9")
 	}
 }
+
+func TestSOAEmail(t *testing.T) {
+	testcases := map[string]string{
+		"hostmaster.example.org.": "hostmaster@example.org",
+		`john\.doe.example.org.`:  "john.doe@example.org",
+	}
+	for mbox, email := range testcases {
+		soa := &SOA{Mbox: mbox}
+		if e := soa.Email(); e != email {
+			t.Errorf("expected email %s for %s, got %s", email, mbox, e)
+		}
+		soa.Mbox = ""
+		if err := soa.SetEmail(email); err != nil {
+			t.Errorf("failed to set email %s: %v", email, err)
+			continue
+		}
+		if soa.Mbox != mbox {
+			t.Errorf("expected mbox %s for %s, got %s", mbox, email, soa.Mbox)
+		}
+	}
+
+	if e := (&SOA{Mbox: "."}).Email(); e != "" {
+		t.Errorf("expected no email for the root name, got %s", e)
+	}
+	for _, email := range []string{"hostmaster", "@example.org", "hostmaster@"} {
+		if err := new(SOA).SetEmail(email); err == nil {
+			t.Errorf("expected error for %q", email)
+		}
+	}
+}
+
+func TestSOABumpSerial(t *testing.T) {
+	now := time.Date(2017, time.March, 4, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		mode     SerialMode
+		serial   uint32
+		expected uint32
+	}{
+		{SerialIncrement, 1, 2},
+		{SerialIncrement, 4294967295, 0}, // wraps around
+		{SerialUnixTime, 1, uint32(now.Unix())},
+		{SerialUnixTime, uint32(now.Unix()), uint32(now.Unix()) + 1},                 // second change in the same second
+		{SerialUnixTime, uint32(now.Unix()) + 1<<31, uint32(now.Unix()) + 1<<31 + 1}, // a step of 2^31 is undefined
+		{SerialDate, 2017030307, 2017030400},
+		{SerialDate, 2017030400, 2017030401},
+		{SerialDate, 2017030498, 2017030499},
+		{SerialDate, 2017030499, 2017030500}, // the hundredth change on a day
+		{SerialDate, 2017031000, 2017031001},
+		{SerialDate, 1488628800, 2017030400},
+	} {
+		soa := &SOA{Serial: tc.serial}
+		soa.bumpSerial(tc.mode, now)
+		if soa.Serial != tc.expected {
+			t.Errorf("mode %d: expected %d after %d, got %d", tc.mode, tc.expected, tc.serial, soa.Serial)
+		}
+	}
+}
@@ -17,7 +17,10 @@ type Transfer struct {
 	ReadTimeout    time.Duration     // net.Conn.SetReadTimeout value for connections, defaults to 2 seconds
 	WriteTimeout   time.Duration     // net.Conn.SetWriteTimeout value for connections, defaults to 2 seconds
 	TsigSecret     map[string]string // Secret(s) for Tsig map[<zonename>]<base64 secret>, zonename must be fully qualified
+	MaxRRs         int               // maximum number of RRs an incoming transfer may have, 0 means no limit
+	MaxBytes       int               // maximum size of the messages of an incoming transfer together, 0 means no limit
 	tsigTimersOnly bool
+	rrs, bytes     int // received so far by In
 }
 
 // Think we need to away to stop the transfer
@@ -38,6 +41,7 @@ func (t *Transfer) In(q *Msg, a string) (env chan *Envelope, err error) {
 	if t.DialTimeout != 0 {
 		timeout = t.DialTimeout
 	}
+	t.rrs, t.bytes = 0, 0
 	if t.Conn == nil {
 		t.Conn, err = DialTimeout("tcp", a, timeout)
 		if err != nil {
@@ -80,6 +84,10 @@ func (t *Transfer) inAxfr(id uint16, c chan *Envelope) {
 			c <- &Envelope{in.Answer, ErrId}
 			return
 		}
+		if err := t.limit(in); err != nil {
+			c <- &Envelope{nil, err}
+			return
+		}
 		if first {
 			if !isSOAFirst(in) {
 				c <- &Envelope{in.Answer, ErrSoa}
@@ -125,6 +133,10 @@ func (t *Transfer) inIxfr(id uint16, c chan *Envelope) {
 			c <- &Envelope{in.Answer, ErrId}
 			return
 		}
+		if err := t.limit(in); err != nil {
+			c <- &Envelope{nil, err}
+			return
+		}
 		if first {
 			// A single SOA RR signals "no changes"
 			if len(in.Answer) == 1 && isSOAFirst(in) {
@@ -157,6 +169,17 @@ func (t *Transfer) inIxfr(id uint16, c chan *Envelope) {
 	}
 }
 
+// limit adds the RRs and size of in to what has been received and returns ErrLimit
+// when that is more than MaxRRs or MaxBytes allow.
+func (t *Transfer) limit(in *Msg) error {
+	t.rrs += len(in.Answer)
+	t.bytes += in.Size
+	if (t.MaxRRs > 0 && t.rrs > t.MaxRRs) || (t.MaxBytes > 0 && t.bytes > t.MaxBytes) {
+		return ErrLimit
+	}
+	return nil
+}
+
 // Out performs an outgoing transfer with the client connecting in w.
 // Basic use pattern:
 //
@@ -43,7 +43,7 @@ func (rr *AFSDB) pack(msg []byte, off int, compression map[string]int, compress
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Hostname, msg, off, compression, compress)
+	off, err = PackDomainName(rr.Hostname, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -61,6 +61,34 @@ func (rr *ANY) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
+func (rr *APL) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+	off, err := rr.Hdr.pack(msg, off, compression, compress)
+	if err != nil {
+		return off, err
+	}
+	headerEnd := off
+	off, err = packDataApl(rr.Prefixes, msg, off)
+	if err != nil {
+		return off, err
+	}
+	rr.Header().Rdlength = uint16(off - headerEnd)
+	return off, nil
+}
+
+func (rr *AVC) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+	off, err := rr.Hdr.pack(msg, off, compression, compress)
+	if err != nil {
+		return off, err
+	}
+	headerEnd := off
+	off, err = packStringTxt(rr.Txt, msg, off)
+	if err != nil {
+		return off, err
+	}
+	rr.Header().Rdlength = uint16(off - headerEnd)
+	return off, nil
+}
+
 func (rr *CAA) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
@@ -221,7 +249,7 @@ func (rr *DNAME) pack(msg []byte, off int, compression map[string]int, compress
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Target, msg, off, compression, compress)
+	off, err = PackDomainName(rr.Target, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -383,6 +411,7 @@ func (rr *HIP) pack(msg []byte, off int, compression map[string]int, compress bo
 		return off, err
 	}
 	headerEnd := off
+	offHitLength := off
 	off, err = packUint8(rr.HitLength, msg, off)
 	if err != nil {
 		return off, err
@@ -391,19 +420,36 @@ func (rr *HIP) pack(msg []byte, off int, compression map[string]int, compress bo
 	if err != nil {
 		return off, err
 	}
+	offPublicKeyLength := off
 	off, err = packUint16(rr.PublicKeyLength, msg, off)
 	if err != nil {
 		return off, err
 	}
+	sizeStart := off
 	off, err = packStringHex(rr.Hit, msg, off)
 	if err != nil {
 		return off, err
 	}
+	rr.HitLength = uint8(off - sizeStart)
+	if int(rr.HitLength) != off-sizeStart {
+		return len(msg), ErrFmt
+	}
+	if _, err = packUint8(rr.HitLength, msg, offHitLength); err != nil {
+		return off, err
+	}
+	sizeStart = off
 	off, err = packStringBase64(rr.PublicKey, msg, off)
 	if err != nil {
 		return off, err
 	}
-	off, err = packDataDomainNames(rr.RendezvousServers, msg, off, compression, compress)
+	rr.PublicKeyLength = uint16(off - sizeStart)
+	if int(rr.PublicKeyLength) != off-sizeStart {
+		return len(msg), ErrFmt
+	}
+	if _, err = packUint16(rr.PublicKeyLength, msg, offPublicKeyLength); err != nil {
+		return off, err
+	}
+	off, err = packDataDomainNames(rr.RendezvousServers, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -447,7 +493,7 @@ func (rr *KX) pack(msg []byte, off int, compression map[string]int, compress boo
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Exchanger, msg, off, compression, compress)
+	off, err = PackDomainName(rr.Exchanger, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -539,7 +585,7 @@ func (rr *LP) pack(msg []byte, off int, compression map[string]int, compress boo
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Fqdn, msg, off, compression, compress)
+	off, err = PackDomainName(rr.Fqdn, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -679,7 +725,7 @@ func (rr *NAPTR) pack(msg []byte, off int, compression map[string]int, compress
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Replacement, msg, off, compression, compress)
+	off, err = PackDomainName(rr.Replacement, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -753,7 +799,7 @@ func (rr *NSAPPTR) pack(msg []byte, off int, compression map[string]int, compres
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Ptr, msg, off, compression, compress)
+	off, err = PackDomainName(rr.Ptr, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -767,7 +813,7 @@ func (rr *NSEC) pack(msg []byte, off int, compression map[string]int, compress b
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.NextDomain, msg, off, compression, compress)
+	off, err = PackDomainName(rr.NextDomain, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -797,22 +843,40 @@ func (rr *NSEC3) pack(msg []byte, off int, compression map[string]int, compress
 	if err != nil {
 		return off, err
 	}
+	offSaltLength := off
 	off, err = packUint8(rr.SaltLength, msg, off)
 	if err != nil {
 		return off, err
 	}
+	sizeStart := off
 	off, err = packStringHex(rr.Salt, msg, off)
 	if err != nil {
 		return off, err
 	}
+	rr.SaltLength = uint8(off - sizeStart)
+	if int(rr.SaltLength) != off-sizeStart {
+		return len(msg), ErrFmt
+	}
+	if _, err = packUint8(rr.SaltLength, msg, offSaltLength); err != nil {
+		return off, err
+	}
+	offHashLength := off
 	off, err = packUint8(rr.HashLength, msg, off)
 	if err != nil {
 		return off, err
 	}
+	sizeStart = off
 	off, err = packStringBase32(rr.NextDomain, msg, off)
 	if err != nil {
 		return off, err
 	}
+	rr.HashLength = uint8(off - sizeStart)
+	if int(rr.HashLength) != off-sizeStart {
+		return len(msg), ErrFmt
+	}
+	if _, err = packUint8(rr.HashLength, msg, offHashLength); err != nil {
+		return off, err
+	}
 	off, err = packDataNsec(rr.TypeBitMap, msg, off)
 	if err != nil {
 		return off, err
@@ -839,14 +903,23 @@ func (rr *NSEC3PARAM) pack(msg []byte, off int, compression map[string]int, comp
 	if err != nil {
 		return off, err
 	}
+	offSaltLength := off
 	off, err = packUint8(rr.SaltLength, msg, off)
 	if err != nil {
 		return off, err
 	}
+	sizeStart := off
 	off, err = packStringHex(rr.Salt, msg, off)
 	if err != nil {
 		return off, err
 	}
+	rr.SaltLength = uint8(off - sizeStart)
+	if int(rr.SaltLength) != off-sizeStart {
+		return len(msg), ErrFmt
+	}
+	if _, err = packUint8(rr.SaltLength, msg, offSaltLength); err != nil {
+		return off, err
+	}
 	rr.Header().Rdlength = uint16(off - headerEnd)
 	return off, nil
 }
@@ -903,11 +976,11 @@ func (rr *PX) pack(msg []byte, off int, compression map[string]int, compress boo
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Map822, msg, off, compression, compress)
+	off, err = PackDomainName(rr.Map822, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Mapx400, msg, off, compression, compress)
+	off, err = PackDomainName(rr.Mapx400, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -961,11 +1034,11 @@ func (rr *RP) pack(msg []byte, off int, compression map[string]int, compress boo
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Mbox, msg, off, compression, compress)
+	off, err = PackDomainName(rr.Mbox, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Txt, msg, off, compression, compress)
+	off, err = PackDomainName(rr.Txt, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1007,7 +1080,7 @@ func (rr *RRSIG) pack(msg []byte, off int, compression map[string]int, compress
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.SignerName, msg, off, compression, compress)
+	off, err = PackDomainName(rr.SignerName, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1029,7 +1102,7 @@ func (rr *RT) pack(msg []byte, off int, compression map[string]int, compress boo
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Host, msg, off, compression, compress)
+	off, err = PackDomainName(rr.Host, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1071,7 +1144,7 @@ func (rr *SIG) pack(msg []byte, off int, compression map[string]int, compress bo
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.SignerName, msg, off, compression, compress)
+	off, err = PackDomainName(rr.SignerName, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1153,7 +1226,7 @@ func (rr *SRV) pack(msg []byte, off int, compression map[string]int, compress bo
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Target, msg, off, compression, compress)
+	off, err = PackDomainName(rr.Target, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1215,11 +1288,11 @@ func (rr *TALINK) pack(msg []byte, off int, compression map[string]int, compress
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.PreviousName, msg, off, compression, compress)
+	off, err = PackDomainName(rr.PreviousName, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.NextName, msg, off, compression, compress)
+	off, err = PackDomainName(rr.NextName, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1233,7 +1306,7 @@ func (rr *TKEY) pack(msg []byte, off int, compression map[string]int, compress b
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Algorithm, msg, off, compression, compress)
+	off, err = PackDomainName(rr.Algorithm, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1305,7 +1378,7 @@ func (rr *TSIG) pack(msg []byte, off int, compression map[string]int, compress b
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Algorithm, msg, off, compression, compress)
+	off, err = PackDomainName(rr.Algorithm, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1317,14 +1390,23 @@ func (rr *TSIG) pack(msg []byte, off int, compression map[string]int, compress b
 	if err != nil {
 		return off, err
 	}
+	offMACSize := off
 	off, err = packUint16(rr.MACSize, msg, off)
 	if err != nil {
 		return off, err
 	}
+	sizeStart := off
 	off, err = packStringHex(rr.MAC, msg, off)
 	if err != nil {
 		return off, err
 	}
+	rr.MACSize = uint16(off - sizeStart)
+	if int(rr.MACSize) != off-sizeStart {
+		return len(msg), ErrFmt
+	}
+	if _, err = packUint16(rr.MACSize, msg, offMACSize); err != nil {
+		return off, err
+	}
 	off, err = packUint16(rr.OrigId, msg, off)
 	if err != nil {
 		return off, err
@@ -1333,14 +1415,23 @@ func (rr *TSIG) pack(msg []byte, off int, compression map[string]int, compress b
 	if err != nil {
 		return off, err
 	}
+	offOtherLen := off
 	off, err = packUint16(rr.OtherLen, msg, off)
 	if err != nil {
 		return off, err
 	}
+	sizeStart = off
 	off, err = packStringHex(rr.OtherData, msg, off)
 	if err != nil {
 		return off, err
 	}
+	rr.OtherLen = uint16(off - sizeStart)
+	if int(rr.OtherLen) != off-sizeStart {
+		return len(msg), ErrFmt
+	}
+	if _, err = packUint16(rr.OtherLen, msg, offOtherLen); err != nil {
+		return off, err
+	}
 	rr.Header().Rdlength = uint16(off - headerEnd)
 	return off, nil
 }
@@ -1496,6 +1587,40 @@ func unpackANY(h RR_Header, msg []byte, off int) (RR, int, error) {
 	return rr, off, err
 }
 
+func unpackAPL(h RR_Header, msg []byte, off int) (RR, int, error) {
+	rr := new(APL)
+	rr.Hdr = h
+	if noRdata(h) {
+		return rr, off, nil
+	}
+	var err error
+	rdStart := off
+	_ = rdStart
+
+	rr.Prefixes, off, err = unpackDataApl(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	return rr, off, err
+}
+
+func unpackAVC(h RR_Header, msg []byte, off int) (RR, int, error) {
+	rr := new(AVC)
+	rr.Hdr = h
+	if noRdata(h) {
+		return rr, off, nil
+	}
+	var err error
+	rdStart := off
+	_ = rdStart
+
+	rr.Txt, off, err = unpackStringTxt(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	return rr, off, err
+}
+
 func unpackCAA(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rr := new(CAA)
 	rr.Hdr = h
@@ -2030,7 +2155,7 @@ func unpackKX(h RR_Header, msg []byte, off int) (RR, int, error) {
 	if off == len(msg) {
 		return rr, off, nil
 	}
-	rr.Exchanger, off, err = UnpackDomainName(msg, off)
+	rr.Exchanger, off, err = unpackDomainNameUncompressed(msg, off)
 	if err != nil {
 		return rr, off, err
 	}
@@ -2161,7 +2286,7 @@ func unpackLP(h RR_Header, msg []byte, off int) (RR, int, error) {
 	if off == len(msg) {
 		return rr, off, nil
 	}
-	rr.Fqdn, off, err = UnpackDomainName(msg, off)
+	rr.Fqdn, off, err = unpackDomainNameUncompressed(msg, off)
 	if err != nil {
 		return rr, off, err
 	}
@@ -2438,7 +2563,7 @@ func unpackNSAPPTR(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rdStart := off
 	_ = rdStart
 
-	rr.Ptr, off, err = UnpackDomainName(msg, off)
+	rr.Ptr, off, err = unpackDomainNameUncompressed(msg, off)
 	if err != nil {
 		return rr, off, err
 	}
@@ -2455,7 +2580,7 @@ func unpackNSEC(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rdStart := off
 	_ = rdStart
 
-	rr.NextDomain, off, err = UnpackDomainName(msg, off)
+	rr.NextDomain, off, err = unpackDomainNameUncompressed(msg, off)
 	if err != nil {
 		return rr, off, err
 	}
@@ -2567,7 +2692,7 @@ func unpackNSEC3PARAM(h RR_Header, msg []byte, off int) (RR, int, error) {
 	if off == len(msg) {
 		return rr, off, nil
 	}
-	rr.Salt, off, err = unpackStringHex(msg, off, rdStart+int(rr.Hdr.Rdlength))
+	rr.Salt, off, err = unpackStringHex(msg, off, off+int(rr.SaltLength))
 	if err != nil {
 		return rr, off, err
 	}
@@ -2794,7 +2919,7 @@ func unpackRRSIG(h RR_Header, msg []byte, off int) (RR, int, error) {
 	if off == len(msg) {
 		return rr, off, nil
 	}
-	rr.SignerName, off, err = UnpackDomainName(msg, off)
+	rr.SignerName, off, err = unpackDomainNameUncompressed(msg, off)
 	if err != nil {
 		return rr, off, err
 	}
@@ -3098,14 +3223,14 @@ func unpackTALINK(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rdStart := off
 	_ = rdStart
 
-	rr.PreviousName, off, err = UnpackDomainName(msg, off)
+	rr.PreviousName, off, err = unpackDomainNameUncompressed(msg, off)
 	if err != nil {
 		return rr, off, err
 	}
 	if off == len(msg) {
 		return rr, off, nil
 	}
-	rr.NextName, off, err = UnpackDomainName(msg, off)
+	rr.NextName, off, err = unpackDomainNameUncompressed(msg, off)
 	if err != nil {
 		return rr, off, err
 	}
@@ -3122,7 +3247,7 @@ func unpackTKEY(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rdStart := off
 	_ = rdStart
 
-	rr.Algorithm, off, err = UnpackDomainName(msg, off)
+	rr.Algorithm, off, err = unpackDomainNameUncompressed(msg, off)
 	if err != nil {
 		return rr, off, err
 	}
@@ -3233,7 +3358,7 @@ func unpackTSIG(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rdStart := off
 	_ = rdStart
 
-	rr.Algorithm, off, err = UnpackDomainName(msg, off)
+	rr.Algorithm, off, err = unpackDomainNameUncompressed(msg, off)
 	if err != nil {
 		return rr, off, err
 	}
@@ -3397,6 +3522,8 @@ var typeToUnpack = map[uint16]func(RR_Header, []byte, int) (RR, int, error){
 	TypeAAAA:       unpackAAAA,
 	TypeAFSDB:      unpackAFSDB,
 	TypeANY:        unpackANY,
+	TypeAPL:        unpackAPL,
+	TypeAVC:        unpackAVC,
 	TypeCAA:        unpackCAA,
 	TypeCDNSKEY:    unpackCDNSKEY,
 	TypeCDS:        unpackCDS,
@@ -14,6 +14,8 @@ var TypeToRR = map[uint16]func() RR{
 	TypeAAAA:       func() RR { return new(AAAA) },
 	TypeAFSDB:      func() RR { return new(AFSDB) },
 	TypeANY:        func() RR { return new(ANY) },
+	TypeAPL:        func() RR { return new(APL) },
+	TypeAVC:        func() RR { return new(AVC) },
 	TypeCAA:        func() RR { return new(CAA) },
 	TypeCDNSKEY:    func() RR { return new(CDNSKEY) },
 	TypeCDS:        func() RR { return new(CDS) },
@@ -84,7 +86,9 @@ var TypeToString = map[uint16]string{
 	TypeAAAA:       "AAAA",
 	TypeAFSDB:      "AFSDB",
 	TypeANY:        "ANY",
+	TypeAPL:        "APL",
 	TypeATMA:       "ATMA",
+	TypeAVC:        "AVC",
 	TypeAXFR:       "AXFR",
 	TypeCAA:        "CAA",
 	TypeCDNSKEY:    "CDNSKEY",
@@ -164,6 +168,8 @@ func (rr *A) Header() *RR_Header          { return &rr.Hdr }
 func (rr *AAAA) Header() *RR_Header       { return &rr.Hdr }
 func (rr *AFSDB) Header() *RR_Header      { return &rr.Hdr }
 func (rr *ANY) Header() *RR_Header        { return &rr.Hdr }
+func (rr *APL) Header() *RR_Header        { return &rr.Hdr }
+func (rr *AVC) Header() *RR_Header        { return &rr.Hdr }
 func (rr *CAA) Header() *RR_Header        { return &rr.Hdr }
 func (rr *CDNSKEY) Header() *RR_Header    { return &rr.Hdr }
 func (rr *CDS) Header() *RR_Header        { return &rr.Hdr }
@@ -242,13 +248,27 @@ func (rr *AAAA) len() int {
 func (rr *AFSDB) len() int {
 	l := rr.Hdr.len()
 	l += 2 // Subtype
-	l += len(rr.Hostname) + 1
+	l += domainNameLen(rr.Hostname)
 	return l
 }
 func (rr *ANY) len() int {
 	l := rr.Hdr.len()
 	return l
 }
+func (rr *APL) len() int {
+	l := rr.Hdr.len()
+	for _, x := range rr.Prefixes {
+		l += x.len()
+	}
+	return l
+}
+func (rr *AVC) len() int {
+	l := rr.Hdr.len()
+	for _, x := range rr.Txt {
+		l += len(x) + 1
+	}
+	return l
+}
 func (rr *CAA) len() int {
 	l := rr.Hdr.len()
 	l += 1 // Flag
@@ -266,7 +286,7 @@ func (rr *CERT) len() int {
 }
 func (rr *CNAME) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Target) + 1
+	l += domainNameLen(rr.Target)
 	return l
 }
 func (rr *DHCID) len() int {
@@ -276,7 +296,7 @@ func (rr *DHCID) len() int {
 }
 func (rr *DNAME) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Target) + 1
+	l += domainNameLen(rr.Target)
 	return l
 }
 func (rr *DNSKEY) len() int {
@@ -292,12 +312,12 @@ func (rr *DS) len() int {
 	l += 2 // KeyTag
 	l += 1 // Algorithm
 	l += 1 // DigestType
-	l += len(rr.Digest)/2 + 1
+	l += len(rr.Digest) / 2
 	return l
 }
 func (rr *EID) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Endpoint)/2 + 1
+	l += len(rr.Endpoint) / 2
 	return l
 }
 func (rr *EUI48) len() int {
@@ -333,17 +353,17 @@ func (rr *HIP) len() int {
 	l += 1 // HitLength
 	l += 1 // PublicKeyAlgorithm
 	l += 2 // PublicKeyLength
-	l += len(rr.Hit)/2 + 1
+	l += len(rr.Hit) / 2
 	l += base64.StdEncoding.DecodedLen(len(rr.PublicKey))
 	for _, x := range rr.RendezvousServers {
-		l += len(x) + 1
+		l += domainNameLen(x)
 	}
 	return l
 }
 func (rr *KX) len() int {
 	l := rr.Hdr.len()
 	l += 2 // Preference
-	l += len(rr.Exchanger) + 1
+	l += domainNameLen(rr.Exchanger)
 	return l
 }
 func (rr *L32) len() int {
@@ -372,44 +392,44 @@ func (rr *LOC) len() int {
 func (rr *LP) len() int {
 	l := rr.Hdr.len()
 	l += 2 // Preference
-	l += len(rr.Fqdn) + 1
+	l += domainNameLen(rr.Fqdn)
 	return l
 }
 func (rr *MB) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Mb) + 1
+	l += domainNameLen(rr.Mb)
 	return l
 }
 func (rr *MD) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Md) + 1
+	l += domainNameLen(rr.Md)
 	return l
 }
 func (rr *MF) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Mf) + 1
+	l += domainNameLen(rr.Mf)
 	return l
 }
 func (rr *MG) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Mg) + 1
+	l += domainNameLen(rr.Mg)
 	return l
 }
 func (rr *MINFO) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Rmail) + 1
-	l += len(rr.Email) + 1
+	l += domainNameLen(rr.Rmail)
+	l += domainNameLen(rr.Email)
 	return l
 }
 func (rr *MR) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Mr) + 1
+	l += domainNameLen(rr.Mr)
 	return l
 }
 func (rr *MX) len() int {
 	l := rr.Hdr.len()
 	l += 2 // Preference
-	l += len(rr.Mx) + 1
+	l += domainNameLen(rr.Mx)
 	return l
 }
 func (rr *NAPTR) len() int {
@@ -419,7 +439,7 @@ func (rr *NAPTR) len() int {
 	l += len(rr.Flags) + 1
 	l += len(rr.Service) + 1
 	l += len(rr.Regexp) + 1
-	l += len(rr.Replacement) + 1
+	l += domainNameLen(rr.Replacement)
 	return l
 }
 func (rr *NID) len() int {
@@ -430,7 +450,7 @@ func (rr *NID) len() int {
 }
 func (rr *NIMLOC) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Locator)/2 + 1
+	l += len(rr.Locator) / 2
 	return l
 }
 func (rr *NINFO) len() int {
@@ -442,12 +462,12 @@ func (rr *NINFO) len() int {
 }
 func (rr *NS) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Ns) + 1
+	l += domainNameLen(rr.Ns)
 	return l
 }
 func (rr *NSAPPTR) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Ptr) + 1
+	l += domainNameLen(rr.Ptr)
 	return l
 }
 func (rr *NSEC3PARAM) len() int {
@@ -456,7 +476,7 @@ func (rr *NSEC3PARAM) len() int {
 	l += 1 // Flags
 	l += 2 // Iterations
 	l += 1 // SaltLength
-	l += len(rr.Salt)/2 + 1
+	l += len(rr.Salt) / 2
 	return l
 }
 func (rr *OPENPGPKEY) len() int {
@@ -466,19 +486,19 @@ func (rr *OPENPGPKEY) len() int {
 }
 func (rr *PTR) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Ptr) + 1
+	l += domainNameLen(rr.Ptr)
 	return l
 }
 func (rr *PX) len() int {
 	l := rr.Hdr.len()
 	l += 2 // Preference
-	l += len(rr.Map822) + 1
-	l += len(rr.Mapx400) + 1
+	l += domainNameLen(rr.Map822)
+	l += domainNameLen(rr.Mapx400)
 	return l
 }
 func (rr *RFC3597) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Rdata)/2 + 1
+	l += len(rr.Rdata) / 2
 	return l
 }
 func (rr *RKEY) len() int {
@@ -491,8 +511,8 @@ func (rr *RKEY) len() int {
 }
 func (rr *RP) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Mbox) + 1
-	l += len(rr.Txt) + 1
+	l += domainNameLen(rr.Mbox)
+	l += domainNameLen(rr.Txt)
 	return l
 }
 func (rr *RRSIG) len() int {
@@ -504,20 +524,20 @@ func (rr *RRSIG) len() int {
 	l += 4 // Expiration
 	l += 4 // Inception
 	l += 2 // KeyTag
-	l += len(rr.SignerName) + 1
+	l += domainNameLen(rr.SignerName)
 	l += base64.StdEncoding.DecodedLen(len(rr.Signature))
 	return l
 }
 func (rr *RT) len() int {
 	l := rr.Hdr.len()
 	l += 2 // Preference
-	l += len(rr.Host) + 1
+	l += domainNameLen(rr.Host)
 	return l
 }
 func (rr *SOA) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Ns) + 1
-	l += len(rr.Mbox) + 1
+	l += domainNameLen(rr.Ns)
+	l += domainNameLen(rr.Mbox)
 	l += 4 // Serial
 	l += 4 // Refresh
 	l += 4 // Retry
@@ -537,14 +557,14 @@ func (rr *SRV) len() int {
 	l += 2 // Priority
 	l += 2 // Weight
 	l += 2 // Port
-	l += len(rr.Target) + 1
+	l += domainNameLen(rr.Target)
 	return l
 }
 func (rr *SSHFP) len() int {
 	l := rr.Hdr.len()
 	l += 1 // Algorithm
 	l += 1 // Type
-	l += len(rr.FingerPrint)/2 + 1
+	l += len(rr.FingerPrint) / 2
 	return l
 }
 func (rr *TA) len() int {
@@ -552,18 +572,18 @@ func (rr *TA) len() int {
 	l += 2 // KeyTag
 	l += 1 // Algorithm
 	l += 1 // DigestType
-	l += len(rr.Digest)/2 + 1
+	l += len(rr.Digest) / 2
 	return l
 }
 func (rr *TALINK) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.PreviousName) + 1
-	l += len(rr.NextName) + 1
+	l += domainNameLen(rr.PreviousName)
+	l += domainNameLen(rr.NextName)
 	return l
 }
 func (rr *TKEY) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Algorithm) + 1
+	l += domainNameLen(rr.Algorithm)
 	l += 4 // Inception
 	l += 4 // Expiration
 	l += 2 // Mode
@@ -579,20 +599,20 @@ func (rr *TLSA) len() int {
 	l += 1 // Usage
 	l += 1 // Selector
 	l += 1 // MatchingType
-	l += len(rr.Certificate)/2 + 1
+	l += len(rr.Certificate) / 2
 	return l
 }
 func (rr *TSIG) len() int {
 	l := rr.Hdr.len()
-	l += len(rr.Algorithm) + 1
+	l += domainNameLen(rr.Algorithm)
 	l += 6 // TimeSigned
 	l += 2 // Fudge
 	l += 2 // MACSize
-	l += len(rr.MAC)/2 + 1
+	l += len(rr.MAC) / 2
 	l += 2 // OrigId
 	l += 2 // Error
 	l += 2 // OtherLen
-	l += len(rr.OtherData)/2 + 1
+	l += len(rr.OtherData) / 2
 	return l
 }
 func (rr *TXT) len() int {
@@ -638,6 +658,19 @@ func (rr *AFSDB) copy() RR {
 func (rr *ANY) copy() RR {
 	return &ANY{*rr.Hdr.copyHeader()}
 }
+func (rr *APL) copy() RR {
+	Prefixes := make([]APLPrefix, len(rr.Prefixes))
+	copy(Prefixes, rr.Prefixes)
+	for i := range rr.Prefixes {
+		Prefixes[i] = rr.Prefixes[i].copy()
+	}
+	return &APL{*rr.Hdr.copyHeader(), Prefixes}
+}
+func (rr *AVC) copy() RR {
+	Txt := make([]string, len(rr.Txt))
+	copy(Txt, rr.Txt)
+	return &AVC{*rr.Hdr.copyHeader(), Txt}
+}
 func (rr *CAA) copy() RR {
 	return &CAA{*rr.Hdr.copyHeader(), rr.Flag, rr.Tag, rr.Value}
 }